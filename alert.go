@@ -1,6 +1,8 @@
 package kkok
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,9 +14,22 @@ const (
 	maxTitleLength = 250
 )
 
+// Alert status values.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
 // Alert represents an alert.
 type Alert struct {
 
+	// ID is a unique identifier assigned when the alert is posted.
+	ID string `json:",omitempty"`
+
+	// Fingerprint identifies alerts regarded as the same.
+	// This is computed when the alert is posted.
+	Fingerprint string `json:",omitempty"`
+
 	// From is an identifying string who sent this alert.
 	// Example: "NTP monitor"
 	From string
@@ -43,6 +58,17 @@ type Alert struct {
 
 	// Sub may list alerts grouped into this.
 	Sub []*Alert `json:",omitempty"`
+
+	// Escalation is the ID of the escalation policy to be applied.
+	Escalation string `json:",omitempty"`
+
+	// Status is either StatusFiring or StatusResolved.
+	// Empty status is regarded as StatusFiring.
+	Status string `json:",omitempty"`
+
+	// Incident is a snapshot of the incident of this alert.
+	// This is set by kkok before filters process the alert.
+	Incident *Incident `json:",omitempty"`
 }
 
 // Validate validates constructed Alert struct.
@@ -68,9 +94,20 @@ func (a *Alert) Validate() error {
 		return errors.New("multi-line Title")
 	}
 
+	switch a.Status {
+	case "", StatusFiring, StatusResolved:
+	default:
+		return errors.New("invalid Status: " + a.Status)
+	}
+
 	return nil
 }
 
+// Resolved returns true if the alert notifies a resolution.
+func (a *Alert) Resolved() bool {
+	return a.Status == StatusResolved
+}
+
 // SetInfo sets a value in Info with key.
 func (a *Alert) SetInfo(key string, value interface{}) {
 	if a.Info == nil {
@@ -113,16 +150,38 @@ func (a *Alert) Clone() *Alert {
 		}
 	}
 
+	var cincident *Incident
+	if a.Incident != nil {
+		c := *a.Incident
+		cincident = &c
+	}
+
 	return &Alert{
-		From:    a.From,
-		Date:    a.Date,
-		Host:    a.Host,
-		Title:   a.Title,
-		Message: a.Message,
-		Routes:  croutes,
-		Info:    cinfo,
-		Sub:     csub,
+		ID:          a.ID,
+		Fingerprint: a.Fingerprint,
+		From:        a.From,
+		Date:        a.Date,
+		Host:        a.Host,
+		Title:       a.Title,
+		Message:     a.Message,
+		Routes:      croutes,
+		Info:        cinfo,
+		Sub:         csub,
+		Escalation:  a.Escalation,
+		Status:      a.Status,
+		Incident:    cincident,
+	}
+}
+
+// newAlertID returns a new random alert ID.
+func newAlertID() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand never fails on supported platforms.
+		panic(err)
 	}
+	return hex.EncodeToString(b)
 }
 
 // String returns a string representation of the alert.
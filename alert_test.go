@@ -101,7 +101,33 @@ func testAlertClone(t *testing.T) {
 	}
 }
 
+func testAlertStatus(t *testing.T) {
+	t.Parallel()
+
+	a := &Alert{From: "from", Title: "title"}
+	if a.Validate() != nil {
+		t.Error(`empty status is rejected`)
+	}
+	if a.Resolved() {
+		t.Error(`a.Resolved()`)
+	}
+
+	a.Status = StatusResolved
+	if a.Validate() != nil {
+		t.Error(`resolved status is rejected`)
+	}
+	if !a.Resolved() {
+		t.Error(`!a.Resolved()`)
+	}
+
+	a.Status = "unknown"
+	if a.Validate() == nil {
+		t.Error(`invalid status is accepted`)
+	}
+}
+
 func TestAlert(t *testing.T) {
 	t.Run("Otto", testAlertOtto)
 	t.Run("Clone", testAlertClone)
+	t.Run("Status", testAlertStatus)
 }
@@ -0,0 +1,111 @@
+package kkok
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	alertmanagerSource = "alertmanager"
+)
+
+// amAlert is an alert in Alertmanager webhook messages.
+type amAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// amMessage is a webhook message sent by Prometheus Alertmanager.
+// https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type amMessage struct {
+	Version  string    `json:"version"`
+	Receiver string    `json:"receiver"`
+	Status   string    `json:"status"`
+	Alerts   []amAlert `json:"alerts"`
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	ret := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
+
+// toAlert converts an Alertmanager alert into Alert.
+//
+// From is "job" label or "alertmanager".
+// Host is "instance" label without port number.
+// Title is "summary" annotation or "alertname" label.
+// Message is "description" annotation.
+func (am *amAlert) toAlert(receiver string, now time.Time) *Alert {
+	a := &Alert{
+		From:    am.Labels["job"],
+		Title:   am.Annotations["summary"],
+		Message: am.Annotations["description"],
+		Info: map[string]interface{}{
+			"labels":      stringMap(am.Labels),
+			"annotations": stringMap(am.Annotations),
+			"receiver":    receiver,
+		},
+	}
+	if len(a.From) == 0 {
+		a.From = alertmanagerSource
+	}
+	if len(a.Title) == 0 {
+		a.Title = am.Labels["alertname"]
+	}
+	if len(am.GeneratorURL) > 0 {
+		a.Info["generator_url"] = am.GeneratorURL
+	}
+
+	if inst := am.Labels["instance"]; len(inst) > 0 {
+		h, _, err := net.SplitHostPort(inst)
+		if err != nil {
+			h = inst
+		}
+		a.Host = h
+	}
+
+	a.Date = am.StartsAt
+	if am.Status == StatusResolved {
+		a.Status = StatusResolved
+		if !am.EndsAt.IsZero() {
+			a.Date = am.EndsAt
+		}
+	} else {
+		a.Status = StatusFiring
+	}
+	if a.Date.IsZero() {
+		a.Date = now
+	}
+	a.Date = a.Date.UTC()
+
+	return a
+}
+
+func (a *apiHandler) postAlertmanager(w http.ResponseWriter, r *http.Request) {
+	if getMethod(r) != "POST" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg := new(amMessage)
+	if !recvJSON(w, r, msg) {
+		return
+	}
+
+	// alerts are validated and posted independently like POST /alerts.
+	now := time.Now().UTC()
+	results := make([]postResult, 0, len(msg.Alerts))
+	for i := range msg.Alerts {
+		alert := msg.Alerts[i].toAlert(msg.Receiver, now)
+		results = append(results, a.postOneAlert(r, alert, alertmanagerSource))
+	}
+	sendJSON(w, r, results)
+}
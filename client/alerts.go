@@ -16,6 +16,8 @@ func (c alertsCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands
 	newc.Register(AlertsListCommand(), "")
 	newc.Register(AlertsPostCommand(), "")
 	newc.Register(AlertsPostJSONCommand(), "")
+	newc.Register(AlertsPostBatchCommand(), "")
+	newc.Register(AlertsAckCommand(), "")
 	return newc.Execute(ctx)
 }
 
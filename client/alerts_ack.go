@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+
+	"github.com/google/subcommands"
+)
+
+type alertsAckCommand struct{}
+
+func (c alertsAckCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c alertsAckCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	args := f.Args()
+	if len(args) != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id := args[0]
+
+	_, err := Call(ctx, "POST", path.Join("/alerts", id, "ack"), nil)
+	if err == nil {
+		fmt.Println("success")
+	}
+	return handleError(err)
+}
+
+// AlertsAckCommand implements "alerts ack" subcommand.
+func AlertsAckCommand() subcommands.Command {
+	return subcmd{
+		alertsAckCommand{},
+		"ack",
+		"acknowledge an alert",
+		`ack ID:
+    Acknowledge an alert to stop its escalation.
+    ID is the alert ID.
+`}
+}
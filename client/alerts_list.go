@@ -48,6 +48,7 @@ func (c *alertsListCommand) printAlert(i int, a *kkok.Alert) {
 	}
 
 	fmt.Printf(`#%d
+ID: %s
 Date: %s
 From: %s
 Host: %s
@@ -56,7 +57,7 @@ Message:
 %s
 Info: %+v
 
-`, i, dt, a.From, a.Host, a.Title, a.Message, a.Info)
+`, i, a.ID, dt, a.From, a.Host, a.Title, a.Message, a.Info)
 }
 
 // AlertsListCommand implements "alerts list" subcommand.
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -55,11 +56,25 @@ func (c *alertsPostCommand) Execute(ctx context.Context, f *flag.FlagSet) subcom
 		return handleError(err)
 	}
 
-	_, err = Call(ctx, "POST", "/alerts", a)
-	if err == nil {
-		fmt.Println("success")
+	return postAlert(ctx, a)
+}
+
+// postAlert posts a and prints the ID assigned by the server.
+func postAlert(ctx context.Context, a *kkok.Alert) subcommands.ExitStatus {
+	data, err := Call(ctx, "POST", "/alerts", a)
+	if err != nil {
+		return handleError(err)
+	}
+
+	var res struct {
+		ID string `json:"id"`
+	}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return handleError(err)
 	}
-	return handleError(err)
+	fmt.Println(res.ID)
+	return handleError(nil)
 }
 
 // AlertsPostCommand implements "alerts post" subcommand.
@@ -70,6 +85,6 @@ func AlertsPostCommand() subcommands.Command {
 		"post a new alert",
 		`post [options] TITLE [MESSAGE]:
     Post a new alert.  If MESSAGE is not specified, it will be read
-    from stdin.
+    from stdin.  The ID of the posted alert is printed.
 `}
 }
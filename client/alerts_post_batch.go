@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/google/subcommands"
+	"github.com/pkg/errors"
+)
+
+type alertsPostBatchCommand struct{}
+
+func (c alertsPostBatchCommand) SetFlags(f *flag.FlagSet) {}
+
+// readAlerts reads a JSON array of alerts or a stream of
+// JSON objects of alerts.
+func readAlerts(r io.Reader) ([]*kkok.Alert, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		br.UnreadByte()
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	var alerts []*kkok.Alert
+	if b, _ := br.Peek(1); b[0] == '[' {
+		err := dec.Decode(&alerts)
+		if err != nil {
+			return nil, err
+		}
+		return alerts, nil
+	}
+
+	for {
+		a := new(kkok.Alert)
+		err := dec.Decode(a)
+		if err == io.EOF {
+			return alerts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+}
+
+func (c alertsPostBatchCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	args := f.Args()
+	infile := os.Stdin
+	switch len(args) {
+	case 0:
+	case 1:
+		g, err := os.Open(args[0])
+		if err != nil {
+			return handleError(err)
+		}
+		defer g.Close()
+		infile = g
+	default:
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	alerts, err := readAlerts(infile)
+	if err != nil {
+		return handleError(err)
+	}
+	if len(alerts) == 0 {
+		return handleError(errors.New("no alerts"))
+	}
+
+	data, err := Call(ctx, "POST", "/alerts", alerts)
+	if err != nil {
+		return handleError(err)
+	}
+
+	var results []struct {
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	err = json.Unmarshal(data, &results)
+	if err != nil {
+		return handleError(err)
+	}
+
+	failed := 0
+	for i, res := range results {
+		if len(res.Error) > 0 {
+			fmt.Fprintf(os.Stderr, "alert %d: %s\n", i, res.Error)
+			failed++
+			continue
+		}
+		fmt.Println(res.ID)
+	}
+	if failed > 0 {
+		return handleError(fmt.Errorf("%d of %d alerts were rejected", failed, len(results)))
+	}
+	return handleError(nil)
+}
+
+// AlertsPostBatchCommand implements "alerts postBatch" subcommand.
+func AlertsPostBatchCommand() subcommands.Command {
+	return subcmd{
+		alertsPostBatchCommand{},
+		"postBatch",
+		"post multiple alerts at once",
+		`postBatch [FILENAME]:
+    Post new alerts by a single request.  If FILENAME is given, alerts
+    are read from the file.  If FILENAME is not given, they are read
+    from stdin.
+
+    The input should be a JSON array of alert objects, or a sequence
+    of alert objects such as newline-delimited JSON.  See "postJSON"
+    for the fields of alert objects.
+
+    The IDs of posted alerts are printed in the input order.
+    Rejected alerts are reported to stderr with their indices.
+`}
+}
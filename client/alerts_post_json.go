@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
-	"fmt"
 	"os"
 
 	"github.com/cybozu-go/kkok"
@@ -43,11 +42,7 @@ func (c alertsPostJSONCommand) Execute(ctx context.Context, f *flag.FlagSet) sub
 		return handleError(err)
 	}
 
-	_, err = Call(ctx, "POST", "/alerts", a)
-	if err == nil {
-		fmt.Println("success")
-	}
-	return handleError(err)
+	return postAlert(ctx, a)
 }
 
 // AlertsPostJSONCommand implements "alerts postJSON" subcommand.
@@ -70,5 +65,6 @@ Date    | No       | string | RFC3339 format date string.
 Host    | No       | string | Where this alert was generated.
 Message | No       | string | Multi-line description of the alert.
 Info    | No       | object | Additional fields.
+Status  | No       | string | "firing" or "resolved".
 `}
 }
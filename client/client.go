@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -25,6 +26,7 @@ var (
 
 // Call makes a REST API call to kkok server.
 // method is HTTP method.  api is API request path such as /version.
+// api may have a query string such as /history?from=foo.
 // If j is []byte, it will be used as the request body.
 // If j is not []byte nor nil, it will be encoded into JSON and
 // be used as request body.
@@ -34,8 +36,13 @@ var (
 // For other status codes, this will return non-nil errors.
 func Call(ctx context.Context, method, api string, j interface{}) ([]byte, error) {
 	u := *kkokURL
+	p := api
+	if idx := strings.IndexByte(api, '?'); idx != -1 {
+		p = api[0:idx]
+		u.RawQuery = api[idx+1:]
+	}
 	// to allow reverse proxies adding a path prefix.
-	u.Path = path.Join(u.Path, api)
+	u.Path = path.Join(u.Path, p)
 	header := make(http.Header)
 	if method == "PUT" || method == "POST" {
 		header.Set("Content-Type", "application/json")
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"flag"
+
+	"github.com/google/subcommands"
+)
+
+type deadLettersCommand struct{}
+
+func (c deadLettersCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c deadLettersCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	newc := NewCommander(f, "deadletters")
+	newc.Register(DeadLettersListCommand(), "")
+	newc.Register(DeadLettersRetryCommand(), "")
+	newc.Register(DeadLettersPurgeCommand(), "")
+	return newc.Execute(ctx)
+}
+
+// DeadLettersCommand implements "deadletters" subcommand.
+func DeadLettersCommand() subcommands.Command {
+	return subcmd{
+		deadLettersCommand{},
+		"deadletters",
+		"call /deadletters/... API",
+		"deadletters ACTION ...",
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/google/subcommands"
+)
+
+type deadLettersListCommand struct{}
+
+func (c deadLettersListCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c deadLettersListCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	data, err := Call(ctx, "GET", "/deadletters", nil)
+	if err != nil {
+		return handleError(err)
+	}
+	var letters []*kkok.DeadLetter
+	err = json.Unmarshal(data, &letters)
+	if err != nil {
+		return handleError(err)
+	}
+
+	if len(letters) == 0 {
+		fmt.Fprintln(os.Stderr, "no dead letters")
+		return handleError(nil)
+	}
+
+	for _, d := range letters {
+		dt := d.Date.UTC().Format("2006-01-02T15:04:05.000")
+		fmt.Printf("%d: %s %s/%s %s: %s\n",
+			d.ID, dt, d.Route, d.Transport, d.Alert.Title, d.Error)
+	}
+	return handleError(nil)
+}
+
+// DeadLettersListCommand implements "deadletters list" subcommand.
+func DeadLettersListCommand() subcommands.Command {
+	return subcmd{
+		deadLettersListCommand{},
+		"list",
+		"show list of undelivered alerts",
+		`list:
+    Show list of alerts that could not be delivered.
+`}
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/subcommands"
+)
+
+type deadLettersPurgeCommand struct{}
+
+func (c deadLettersPurgeCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c deadLettersPurgeCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	_, err := Call(ctx, "DELETE", "/deadletters", nil)
+	if err == nil {
+		fmt.Println("success")
+	}
+	return handleError(err)
+}
+
+// DeadLettersPurgeCommand implements "deadletters purge" subcommand.
+func DeadLettersPurgeCommand() subcommands.Command {
+	return subcmd{
+		deadLettersPurgeCommand{},
+		"purge",
+		"remove all undelivered alerts",
+		`purge:
+    Remove all alerts that could not be delivered.
+`}
+}
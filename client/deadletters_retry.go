@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+
+	"github.com/google/subcommands"
+)
+
+type deadLettersRetryCommand struct{}
+
+func (c deadLettersRetryCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c deadLettersRetryCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	args := f.Args()
+	if len(args) != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id := args[0]
+
+	_, err := Call(ctx, "POST", path.Join("/deadletters", id, "retry"), nil)
+	if err == nil {
+		fmt.Println("success")
+	}
+	return handleError(err)
+}
+
+// DeadLettersRetryCommand implements "deadletters retry" subcommand.
+func DeadLettersRetryCommand() subcommands.Command {
+	return subcmd{
+		deadLettersRetryCommand{},
+		"retry",
+		"retry to deliver an undelivered alert",
+		`retry ID:
+    Retry to deliver an alert that could not be delivered.
+    ID is the dead letter ID.
+`}
+}
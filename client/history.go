@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"flag"
+
+	"github.com/google/subcommands"
+)
+
+type historyCommand struct{}
+
+func (c historyCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c historyCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	newc := NewCommander(f, "history")
+	newc.Register(HistorySearchCommand(), "")
+	return newc.Execute(ctx)
+}
+
+// HistoryCommand implements "history" subcommand.
+func HistoryCommand() subcommands.Command {
+	return subcmd{
+		historyCommand{},
+		"history",
+		"call /history API",
+		"history ACTION ...",
+	}
+}
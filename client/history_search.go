@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/google/subcommands"
+)
+
+type historySearchCommand struct {
+	from  string
+	host  string
+	route string
+	since string
+	until string
+}
+
+func (c *historySearchCommand) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.from, "from", "", "search alerts from this")
+	f.StringVar(&c.host, "host", "", "search alerts of this host")
+	f.StringVar(&c.route, "route", "", "search alerts routed to this")
+	f.StringVar(&c.since, "since", "", "RFC3339 date to search alerts since")
+	f.StringVar(&c.until, "until", "", "RFC3339 date to search alerts until")
+}
+
+func (c *historySearchCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	v := make(url.Values)
+	for key, value := range map[string]string{
+		"from":  c.from,
+		"host":  c.host,
+		"route": c.route,
+		"since": c.since,
+		"until": c.until,
+	} {
+		if len(value) > 0 {
+			v.Set(key, value)
+		}
+	}
+
+	data, err := Call(ctx, "GET", "/history?"+v.Encode(), nil)
+	if err != nil {
+		return handleError(err)
+	}
+	var entries []*kkok.HistoryEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return handleError(err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "no alerts")
+		return handleError(nil)
+	}
+
+	for _, e := range entries {
+		c.printEntry(e)
+	}
+	return handleError(nil)
+}
+
+func (c *historySearchCommand) printEntry(e *kkok.HistoryEntry) {
+	const layout = "2006-01-02T15:04:05.000"
+
+	a := e.Alert
+	fmt.Printf(`#%d
+Date: %s
+From: %s
+Host: %s
+Title: %s
+Routes: %s
+Filters: %s
+`, e.ID, e.Date.UTC().Format(layout), a.From, a.Host, a.Title,
+		strings.Join(a.Routes, ","), strings.Join(e.Filters, ","))
+
+	for _, d := range e.Deliveries {
+		result := "ok"
+		if len(d.Error) > 0 {
+			result = d.Error
+		}
+		fmt.Printf("Delivery: %s %s/%s %s\n",
+			d.Date.UTC().Format(layout), d.Route, d.Transport, result)
+	}
+	fmt.Println()
+}
+
+// HistorySearchCommand implements "history search" subcommand.
+func HistorySearchCommand() subcommands.Command {
+	return subcmd{
+		&historySearchCommand{},
+		"search",
+		"search processed alerts",
+		`search [-from FROM] [-host HOST] [-route ROUTE] [-since DATE] [-until DATE]:
+    Search processed alerts with their delivery outcomes.
+`}
+}
@@ -16,6 +16,7 @@ func (c routesCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands
 	newc.Register(RoutesListCommand(), "")
 	newc.Register(RoutesShowCommand(), "")
 	newc.Register(RoutesPutCommand(), "")
+	newc.Register(RoutesDeleteCommand(), "")
 	return newc.Execute(ctx)
 }
 
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/subcommands"
+)
+
+type routesDeleteCommand struct{}
+
+func (c routesDeleteCommand) SetFlags(f *flag.FlagSet) {}
+
+func (c routesDeleteCommand) Execute(ctx context.Context, f *flag.FlagSet) subcommands.ExitStatus {
+	args := f.Args()
+	if len(args) != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id := args[0]
+
+	_, err := Call(ctx, "DELETE", "/routes/"+id, nil)
+	if err == nil {
+		fmt.Println("success")
+	}
+	return handleError(err)
+}
+
+// RoutesDeleteCommand implements "routes delete" subcommand.
+func RoutesDeleteCommand() subcommands.Command {
+	return subcmd{
+		routesDeleteCommand{},
+		"delete",
+		"delete a route",
+		`delete ID:
+    Delete a route.  ID is the route ID.
+`}
+}
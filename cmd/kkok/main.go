@@ -38,6 +38,10 @@ func main() {
 	if err != nil {
 		log.ErrorExit(err)
 	}
+	err = cfg.Validate()
+	if err != nil {
+		log.ErrorExit(err)
+	}
 
 	err = cfg.Log.Apply()
 	if err != nil {
@@ -45,6 +49,11 @@ func main() {
 	}
 
 	k := kkok.NewKkok()
+	k.SetDeliveryTimeout(cfg.DeliveryDuration())
+	k.SetRetryPolicy(cfg.RetryPolicy())
+	k.SetMaxDeadLetters(cfg.MaxDeadLetters)
+	k.SetHistoryLimits(cfg.MaxHistory, cfg.HistoryMaxDuration())
+	k.SetIncidentTimeout(cfg.IncidentDuration())
 
 	// register routes
 	for id, pl := range cfg.Routes {
@@ -62,6 +71,14 @@ func main() {
 		}
 	}
 
+	// register escalation policies
+	for id, steps := range cfg.Escalations {
+		err = k.AddEscalationPolicy(id, steps)
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	}
+
 	// register filters
 	idMap := make(map[string]struct{})
 	for _, p := range cfg.Filters {
@@ -81,10 +98,34 @@ func main() {
 		}
 	}
 
+	// restore dynamic filters and routes
+	if len(cfg.StateFile) > 0 {
+		err = k.SetStateFile(cfg.StateFile)
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	}
+
 	// start dispatcher
-	d := kkok.NewDispatcher(cfg.InitialDuration(), cfg.MaxDuration(), k)
+	var d *kkok.Dispatcher
+	if len(cfg.Journal) > 0 {
+		pool, err := kkok.NewJournalPool(cfg.Journal)
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		d = kkok.NewDispatcherWithPool(cfg.InitialDuration(), cfg.MaxDuration(), k, pool)
+	} else {
+		d = kkok.NewDispatcher(cfg.InitialDuration(), cfg.MaxDuration(), k)
+	}
+	d.SetShutdownTimeout(cfg.ShutdownDuration())
+	fp, err := kkok.NewFingerprinter(cfg.Fingerprint)
+	if err != nil {
+		log.ErrorExit(err)
+	}
+	d.SetFingerprinter(fp)
 	if !*flgTest {
 		well.Go(d.Run)
+		well.Go(k.RunScheduler)
 	}
 
 	for _, p := range cfg.Sources {
@@ -92,11 +133,20 @@ func main() {
 		if err != nil {
 			log.ErrorExit(err)
 		}
+		if wh, ok := src.(kkok.Webhook); ok {
+			err = k.AddWebhook(wh)
+			if err != nil {
+				log.ErrorExit(err)
+			}
+		}
 		if *flgTest {
 			continue
 		}
+		typ := p.Type
 		well.Go(func(ctx context.Context) error {
-			return src.Run(ctx, d.Post)
+			return src.Run(ctx, func(a *kkok.Alert) {
+				d.PostFrom(typ, a)
+			})
 		})
 	}
 
@@ -30,6 +30,8 @@ func main() {
 	sub.Register(client.AlertsCommand(), "")
 	sub.Register(client.FiltersCommand(), "")
 	sub.Register(client.RoutesCommand(), "")
+	sub.Register(client.DeadLettersCommand(), "")
+	sub.Register(client.HistoryCommand(), "")
 	flag.Parse()
 	err := well.LogConfig{}.Apply()
 	if err != nil {
@@ -4,12 +4,19 @@ import (
 	"time"
 
 	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
 )
 
 const (
-	defaultInitialInterval = 30
-	defaultMaxInterval     = 30
-	defaultAddr            = ":19898"
+	defaultInitialInterval  = 30
+	defaultMaxInterval      = 30
+	defaultAddr             = ":19898"
+	defaultRetryMaxAttempts = 3
+	defaultRetryBackoff     = 10
+	defaultRetryMaxBackoff  = 300
+	defaultRetryDeadline    = 3600
+	defaultDeliveryTimeout  = 60
+	defaultShutdownTimeout  = 30
 )
 
 // Config is a struct to load TOML configuration file for kkok.
@@ -40,6 +47,99 @@ type Config struct {
 	// Default is empty.
 	APIToken string `toml:"api_token"`
 
+	// Fingerprint is a JavaScript expression to compute the source
+	// string of alert fingerprints.  If empty, From, Host, and Title
+	// of alerts are used.
+	//
+	// Default is empty.
+	Fingerprint string `toml:"fingerprint"`
+
+	// Journal is the filename to record pooled alerts.
+	// If not empty, alerts that have not been processed are
+	// restored from the file after restarts.
+	//
+	// Default is empty.
+	Journal string `toml:"journal"`
+
+	// StateFile is the filename to save filters and routes added
+	// by REST API.  If not empty, they are restored from the file
+	// after restarts.
+	//
+	// Default is empty.
+	StateFile string `toml:"state_file"`
+
+	// DeliveryTimeout is the maximum seconds to deliver alerts
+	// through a transport.  0 means no timeout.
+	//
+	// Transports that wait for rate limits such as slack and twilio
+	// are given extra time in proportion to the number of messages.
+	//
+	// Transports that do not implement ContextTransport keep running
+	// after the timeout, and may deliver alerts that are also retried
+	// or sent through fallbacks.  All builtin transports implement it.
+	//
+	// Default is 60 (seconds).
+	DeliveryTimeout int `toml:"delivery_timeout"`
+
+	// ShutdownTimeout is the maximum seconds to process pooled
+	// alerts after kkok is requested to stop.
+	//
+	// Default is 30 (seconds).
+	ShutdownTimeout int `toml:"shutdown_timeout"`
+
+	// RetryMaxAttempts is the maximum number of attempts to deliver
+	// alerts through a transport including the first one.
+	// 1 disables retries.
+	//
+	// Default is 3.
+	RetryMaxAttempts int `toml:"retry_max_attempts"`
+
+	// RetryBackoff is the interval seconds before the first retry.
+	// The interval doubles for each retry until it reaches
+	// RetryMaxBackoff.
+	//
+	// Default is 10 (seconds).
+	RetryBackoff int `toml:"retry_backoff"`
+
+	// RetryMaxBackoff is the maximum interval seconds between retries.
+	//
+	// Default is 300 (seconds).
+	RetryMaxBackoff int `toml:"retry_max_backoff"`
+
+	// RetryDeadline is the seconds after the first failure to give up
+	// retries.  0 means no deadline.
+	//
+	// Default is 3600 (seconds).
+	RetryDeadline int `toml:"retry_deadline"`
+
+	// MaxDeadLetters is the maximum number of alerts kept as dead
+	// letters when they could not be delivered.
+	//
+	// Default is 1000.
+	MaxDeadLetters int `toml:"max_dead_letters"`
+
+	// MaxHistory is the maximum number of processed alerts kept
+	// in the history.  0 disables the history.
+	//
+	// Default is 10000.
+	MaxHistory int `toml:"max_history"`
+
+	// HistoryMaxAge is the maximum seconds to keep processed alerts
+	// in the history.  0 means no limit.
+	//
+	// Default is 604800 (7 days).
+	HistoryMaxAge int `toml:"history_max_age"`
+
+	// IncidentTimeout is the seconds to forget an incident if no
+	// firing alerts of it are handled.  0 means never.
+	//
+	// Default is 86400 (1 day).
+	IncidentTimeout int `toml:"incident_timeout"`
+
+	// Escalations is a map between escalation policy ID and
+	// a list of escalation steps.
+	Escalations map[string][]EscalationStep `toml:"escalation"`
+
 	// Log from cybozu-go/well.
 	Log well.LogConfig `toml:"log"`
 
@@ -53,6 +153,17 @@ type Config struct {
 	Filters []PluginParams `toml:"filter"`
 }
 
+// Validate returns an error if c has invalid values.
+func (c *Config) Validate() error {
+	if c.MaxDeadLetters < 0 {
+		return errors.New("max_dead_letters must not be negative")
+	}
+	if c.MaxHistory < 0 {
+		return errors.New("max_history must not be negative")
+	}
+	return nil
+}
+
 // InitialDuration returns the initial dispatch interval.
 func (c *Config) InitialDuration() time.Duration {
 	return time.Second * time.Duration(c.InitialInterval)
@@ -63,11 +174,54 @@ func (c *Config) MaxDuration() time.Duration {
 	return time.Second * time.Duration(c.MaxInterval)
 }
 
+// DeliveryDuration returns the maximum duration to deliver alerts
+// through a transport.
+func (c *Config) DeliveryDuration() time.Duration {
+	return time.Second * time.Duration(c.DeliveryTimeout)
+}
+
+// ShutdownDuration returns the maximum duration to process pooled
+// alerts after kkok is requested to stop.
+func (c *Config) ShutdownDuration() time.Duration {
+	return time.Second * time.Duration(c.ShutdownTimeout)
+}
+
+// RetryPolicy returns the policy to retry failed deliveries.
+func (c *Config) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: c.RetryMaxAttempts,
+		Backoff:     time.Second * time.Duration(c.RetryBackoff),
+		MaxBackoff:  time.Second * time.Duration(c.RetryMaxBackoff),
+		Deadline:    time.Second * time.Duration(c.RetryDeadline),
+	}
+}
+
+// HistoryMaxDuration returns the maximum duration to keep
+// processed alerts in the history.
+func (c *Config) HistoryMaxDuration() time.Duration {
+	return time.Second * time.Duration(c.HistoryMaxAge)
+}
+
+// IncidentDuration returns the duration to forget incidents.
+func (c *Config) IncidentDuration() time.Duration {
+	return time.Second * time.Duration(c.IncidentTimeout)
+}
+
 // NewConfig returns *Config with default settings.
 func NewConfig() *Config {
 	return &Config{
-		InitialInterval: defaultInitialInterval,
-		MaxInterval:     defaultMaxInterval,
-		Addr:            defaultAddr,
+		InitialInterval:  defaultInitialInterval,
+		MaxInterval:      defaultMaxInterval,
+		Addr:             defaultAddr,
+		DeliveryTimeout:  defaultDeliveryTimeout,
+		ShutdownTimeout:  defaultShutdownTimeout,
+		RetryMaxAttempts: defaultRetryMaxAttempts,
+		RetryBackoff:     defaultRetryBackoff,
+		RetryMaxBackoff:  defaultRetryMaxBackoff,
+		RetryDeadline:    defaultRetryDeadline,
+		MaxDeadLetters:   defaultMaxDeadLetters,
+		MaxHistory:       defaultMaxHistory,
+		HistoryMaxAge:    defaultHistoryMaxAge,
+		IncidentTimeout:  defaultIncidentTimeout,
 	}
 }
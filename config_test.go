@@ -21,6 +21,20 @@ func testConfigDefault(t *testing.T) {
 	if c.Addr != defaultAddr {
 		t.Error(`c.Addr != defaultAddr`)
 	}
+
+	p := c.RetryPolicy()
+	if p.MaxAttempts != defaultRetryMaxAttempts {
+		t.Error(`p.MaxAttempts != defaultRetryMaxAttempts`)
+	}
+	if p.Backoff != defaultRetryBackoff*time.Second {
+		t.Error(`p.Backoff != defaultRetryBackoff*time.Second`)
+	}
+	if p.MaxBackoff != defaultRetryMaxBackoff*time.Second {
+		t.Error(`p.MaxBackoff != defaultRetryMaxBackoff*time.Second`)
+	}
+	if p.Deadline != defaultRetryDeadline*time.Second {
+		t.Error(`p.Deadline != defaultRetryDeadline*time.Second`)
+	}
 }
 
 func testConfigLoad(t *testing.T) {
@@ -112,6 +126,14 @@ func testConfigLoad(t *testing.T) {
 		t.Error(`remerg.Params["token"] != "yyyy"`)
 	}
 
+	// escalations
+	if !reflect.DeepEqual(c.Escalations["oncall"], []EscalationStep{
+		{Route: "notify", Wait: 300},
+		{Route: "emergency"},
+	}) {
+		t.Error(`c.Escalations["oncall"] is not valid`, c.Escalations)
+	}
+
 	// filters
 	if len(c.Filters) != 2 {
 		t.Fatal(`len(c.Filters) != 2`)
@@ -128,7 +150,28 @@ func testConfigLoad(t *testing.T) {
 	}
 }
 
+func testConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	c := NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	c.MaxDeadLetters = -1
+	if err := c.Validate(); err == nil {
+		t.Error(`err == nil`)
+	}
+
+	c = NewConfig()
+	c.MaxHistory = -1
+	if err := c.Validate(); err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
 func TestConfig(t *testing.T) {
 	t.Run("Default", testConfigDefault)
 	t.Run("Load", testConfigLoad)
+	t.Run("Validate", testConfigValidate)
 }
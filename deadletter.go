@@ -0,0 +1,188 @@
+package kkok
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxDeadLetters = 1000
+)
+
+var (
+	errDeadLetterNotFound = errors.New("no such dead letter")
+)
+
+// DeadLetter is an alert that could not be delivered through a transport.
+type DeadLetter struct {
+	// ID is the unique ID of the dead letter.
+	ID uint64 `json:"id"`
+
+	// Date is the time when the delivery failed at last.
+	Date time.Time `json:"date"`
+
+	// Route is the route ID of the transport.
+	Route string `json:"route"`
+
+	// Transport is the string representation of the transport.
+	Transport string `json:"transport"`
+
+	// Error is the error message of the last delivery attempt.
+	Error string `json:"error"`
+
+	// Alert is the alert that could not be delivered.
+	Alert *Alert `json:"alert"`
+
+	transport Transport
+}
+
+// deadLetterStore keeps a bounded number of dead letters.
+// If the store is full, the oldest dead letter is dropped.
+type deadLetterStore struct {
+	mu      sync.Mutex
+	max     int
+	lastID  uint64
+	letters []*DeadLetter
+}
+
+func newDeadLetterStore(max int) *deadLetterStore {
+	return &deadLetterStore{max: max}
+}
+
+func (s *deadLetterStore) setMax(max int) {
+	if max < 0 {
+		max = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.max = max
+	s.truncate()
+}
+
+func (s *deadLetterStore) truncate() {
+	if len(s.letters) <= s.max {
+		return
+	}
+	s.letters = append([]*DeadLetter(nil), s.letters[len(s.letters)-s.max:]...)
+}
+
+func (s *deadLetterStore) add(route string, t Transport, alerts []*Alert, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metricDeadLetters.WithLabelValues(route, t.String()).Add(float64(len(alerts)))
+
+	now := time.Now().UTC()
+	for _, a := range alerts {
+		s.lastID++
+		s.letters = append(s.letters, &DeadLetter{
+			ID:        s.lastID,
+			Date:      now,
+			Route:     route,
+			Transport: t.String(),
+			Error:     err.Error(),
+			Alert:     a,
+			transport: t,
+		})
+	}
+	s.truncate()
+}
+
+// list returns a (deep) copy of dead letters.
+func (s *deadLetterStore) list() []*DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := make([]*DeadLetter, len(s.letters))
+	for i, d := range s.letters {
+		c := *d
+		c.Alert = d.Alert.Clone()
+		l[i] = &c
+	}
+	return l
+}
+
+// take removes the dead letter of id from the store and returns it.
+// nil is returned if no such dead letter exists.
+func (s *deadLetterStore) take(id uint64) *DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, d := range s.letters {
+		if d.ID == id {
+			s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			return d
+		}
+	}
+	return nil
+}
+
+// restore puts back d taken by take with the error of the last attempt.
+func (s *deadLetterStore) restore(d *DeadLetter, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.Date = time.Now().UTC()
+	d.Error = err.Error()
+
+	// keep dead letters sorted by ID.
+	i := sort.Search(len(s.letters), func(i int) bool {
+		return s.letters[i].ID > d.ID
+	})
+	s.letters = append(s.letters, nil)
+	copy(s.letters[i+1:], s.letters[i:])
+	s.letters[i] = d
+	s.truncate()
+}
+
+func (s *deadLetterStore) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters = nil
+}
+
+// SetMaxDeadLetters sets the maximum number of dead letters to keep.
+// If the number exceeds max, older dead letters are dropped.
+// A negative max is regarded as 0.
+func (k *Kkok) SetMaxDeadLetters(max int) {
+	k.deadLetters.setMax(max)
+}
+
+// DeadLetters returns a snapshot of alerts that could not be delivered.
+func (k *Kkok) DeadLetters() []*DeadLetter {
+	return k.deadLetters.list()
+}
+
+// RetryDeadLetter tries to deliver the alert of a dead letter again
+// through the same transport that failed.  If succeeded, the dead
+// letter is removed.  The delivery is aborted when ctx is done.
+//
+// The dead letter is taken out of the store during the delivery
+// so that concurrent retries do not deliver the alert twice.
+func (k *Kkok) RetryDeadLetter(ctx context.Context, id uint64) error {
+	d := k.deadLetters.take(id)
+	if d == nil {
+		return errDeadLetterNotFound
+	}
+
+	err := DeliverContext(ctx, d.transport, []*Alert{d.Alert})
+	k.history.delivered(d.Route, d.transport, []*Alert{d.Alert}, err)
+	if err != nil {
+		k.deadLetters.restore(d, err)
+		return errors.Wrap(err, d.Transport)
+	}
+	k.incidents.delivered([]*Alert{d.Alert})
+	return nil
+}
+
+// PurgeDeadLetters removes all dead letters.
+func (k *Kkok) PurgeDeadLetters() {
+	k.deadLetters.purge()
+}
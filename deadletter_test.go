@@ -0,0 +1,110 @@
+package kkok
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	t.Parallel()
+
+	s := newDeadLetterStore(3)
+	tr := &testTransport{}
+	s.add("r1", tr, []*Alert{{Title: "1"}, {Title: "2"}}, errors.New("err1"))
+	s.add("r2", tr, []*Alert{{Title: "3"}, {Title: "4"}}, errors.New("err2"))
+
+	l := s.list()
+	if len(l) != 3 {
+		t.Fatal(`len(l) != 3`)
+	}
+	if l[0].ID != 2 {
+		t.Error(`l[0].ID != 2`)
+	}
+	if l[0].Route != "r1" {
+		t.Error(`l[0].Route != "r1"`)
+	}
+	if l[2].Alert.Title != "4" {
+		t.Error(`l[2].Alert.Title != "4"`)
+	}
+	if l[2].Error != "err2" {
+		t.Error(`l[2].Error != "err2"`)
+	}
+
+	d := s.take(3)
+	if d == nil {
+		t.Fatal(`d == nil`)
+	}
+	if s.take(3) != nil {
+		t.Error(`s.take(3) != nil`)
+	}
+	if len(s.list()) != 2 {
+		t.Error(`len(s.list()) != 2`)
+	}
+
+	// restored dead letters keep their IDs and order.
+	s.restore(d, errors.New("err3"))
+	l = s.list()
+	if len(l) != 3 {
+		t.Fatal(`len(l) != 3`)
+	}
+	if l[1].ID != 3 {
+		t.Error(`l[1].ID != 3`)
+	}
+	if l[1].Error != "err3" {
+		t.Error(`l[1].Error != "err3"`)
+	}
+
+	s.take(3)
+
+	s.setMax(1)
+	l = s.list()
+	if len(l) != 1 {
+		t.Fatal(`len(l) != 1`)
+	}
+	if l[0].ID != 4 {
+		t.Error(`l[0].ID != 4`)
+	}
+
+	// negative max is regarded as 0.
+	s.setMax(-1)
+	if len(s.list()) != 0 {
+		t.Error(`len(s.list()) != 0`)
+	}
+	s.add("r1", tr, []*Alert{{Title: "5"}}, errors.New("err3"))
+	if len(s.list()) != 0 {
+		t.Error(`len(s.list()) != 0`)
+	}
+
+	s.setMax(3)
+	s.add("r1", tr, []*Alert{{Title: "6"}}, errors.New("err4"))
+	s.purge()
+	if len(s.list()) != 0 {
+		t.Error(`len(s.list()) != 0`)
+	}
+}
+
+func TestRetryDeadLetterConcurrent(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	tr := &slowTransport{wait: 100 * time.Millisecond}
+	k.deadLetters.add("r1", tr, []*Alert{{Title: "1"}}, errors.New("err1"))
+
+	// only one of concurrent retries delivers the alert.
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- k.RetryDeadLetter(context.Background(), 1)
+		}()
+	}
+	err1, err2 := <-errs, <-errs
+	if !(err1 == nil && err2 == errDeadLetterNotFound) &&
+		!(err1 == errDeadLetterNotFound && err2 == nil) {
+		t.Error(`unexpected errors`, err1, err2)
+	}
+	if len(k.DeadLetters()) != 0 {
+		t.Error(`len(k.DeadLetters()) != 0`)
+	}
+}
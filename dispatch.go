@@ -4,31 +4,75 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
 )
 
-// alertPool pools Alert objects for some duration.
+// Pool is the interface for alert pool backends of Dispatcher.
+type Pool interface {
+	// Load restores alerts pooled by the previous process, if any.
+	// Dispatcher calls this once when it starts dispatching.
+	Load() error
+
+	// Put puts an alert into the pool.
+	Put(a *Alert) error
+
+	// Empty returns true if the pool is empty.
+	Empty() bool
+
+	// Len returns the number of pooled alerts.
+	Len() int
+
+	// Peek returns a (deep) copy of currently pooled alerts.
+	Peek() []*Alert
+
+	// Take returns pooled alerts and clears the pool.
+	Take() []*Alert
+
+	// Commit tells the pool that alerts returned by the last Take
+	// have been handled.
+	Commit() error
+}
+
+// alertPool pools Alert objects in memory.
+// This is the default Pool for Dispatcher.
 type alertPool struct {
 	mu     sync.Mutex
 	alerts []*Alert
 }
 
-// put puts an alert into the pool.
-func (p *alertPool) put(a *Alert) {
+// Load does nothing as alertPool keeps alerts only in memory.
+func (p *alertPool) Load() error {
+	return nil
+}
+
+// Put puts an alert into the pool.
+func (p *alertPool) Put(a *Alert) error {
 	p.mu.Lock()
 	p.alerts = append(p.alerts, a)
 	p.mu.Unlock()
+	return nil
 }
 
-// empty returns true if the pool is empty.
-func (p *alertPool) empty() bool {
+// Empty returns true if the pool is empty.
+func (p *alertPool) Empty() bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	return len(p.alerts) == 0
 }
 
-// peek returns a (deep) copy of currently pooled alerts.
-func (p *alertPool) peek() []*Alert {
+// Len returns the number of pooled alerts.
+func (p *alertPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.alerts)
+}
+
+// Peek returns a (deep) copy of currently pooled alerts.
+func (p *alertPool) Peek() []*Alert {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -43,8 +87,8 @@ func (p *alertPool) peek() []*Alert {
 	return c
 }
 
-// take returns pooled alerts and clears the pool.
-func (p *alertPool) take() []*Alert {
+// Take returns pooled alerts and clears the pool.
+func (p *alertPool) Take() []*Alert {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -53,23 +97,40 @@ func (p *alertPool) take() []*Alert {
 	return c
 }
 
+// Commit does nothing as alertPool keeps alerts only in memory.
+func (p *alertPool) Commit() error {
+	return nil
+}
+
 // AlertHandler is an interface for NewDispatcher.
 type AlertHandler interface {
-	Handle([]*Alert)
+	// Handle handles alerts.  It should return soon after ctx is done.
+	Handle(ctx context.Context, alerts []*Alert)
 }
 
 // Dispatcher accepts and pools alerts then dispatches them periodically.
 type Dispatcher struct {
-	alertPool
-	initInterval time.Duration
-	maxInterval  time.Duration
-	handler      AlertHandler
+	pool            Pool
+	initInterval    time.Duration
+	maxInterval     time.Duration
+	shutdownTimeout time.Duration
+	handler         AlertHandler
+	fingerprinter   *Fingerprinter
 }
 
 // NewDispatcher creates Dispatcher.
 // init and max is the initial and maximum duration between dispatches.
 // handler handles pooled alerts.  To start dispatching, invoke Run.
+//
+// Alerts are pooled in memory.  To use another pool backend,
+// use NewDispatcherWithPool.
 func NewDispatcher(init, max time.Duration, handler AlertHandler) *Dispatcher {
+	return NewDispatcherWithPool(init, max, handler, new(alertPool))
+}
+
+// NewDispatcherWithPool creates Dispatcher that pools alerts in pool.
+// Other arguments are the same as NewDispatcher.
+func NewDispatcherWithPool(init, max time.Duration, handler AlertHandler, pool Pool) *Dispatcher {
 	if init <= 0 {
 		init = 1 * time.Second
 	}
@@ -77,39 +138,132 @@ func NewDispatcher(init, max time.Duration, handler AlertHandler) *Dispatcher {
 		max = init
 	}
 	return &Dispatcher{
-		initInterval: init,
-		maxInterval:  max,
-		handler:      handler,
+		pool:            pool,
+		initInterval:    init,
+		maxInterval:     max,
+		shutdownTimeout: defaultShutdownTimeout * time.Second,
+		handler:         handler,
+		fingerprinter:   &Fingerprinter{},
 	}
 }
 
+// SetShutdownTimeout sets the maximum duration to handle alerts
+// after Run's context is canceled.  Default is 30 seconds.
+func (d *Dispatcher) SetShutdownTimeout(timeout time.Duration) {
+	d.shutdownTimeout = timeout
+}
+
+// SetFingerprinter sets the Fingerprinter to compute fingerprints
+// of posted alerts.  By default, From, Host, and Title are used.
+func (d *Dispatcher) SetFingerprinter(f *Fingerprinter) {
+	d.fingerprinter = f
+}
+
 // Post puts an alert into the pool.
+// The alert is counted as posted from an unnamed source.
 func (d *Dispatcher) Post(a *Alert) {
-	d.alertPool.put(a)
+	d.PostFrom("", a)
+}
+
+// PostFrom puts an alert generated by source into the pool.
+// source is used to count posted alerts for each source.
+// A new ID and the fingerprint are assigned to the alert if it
+// does not have them.
+func (d *Dispatcher) PostFrom(source string, a *Alert) {
+	if len(a.ID) == 0 {
+		a.ID = newAlertID()
+	}
+	if len(a.Fingerprint) == 0 {
+		a.Fingerprint = d.fingerprinter.Fingerprint(a)
+	}
+
+	err := d.pool.Put(a)
+	if err != nil {
+		log.Error("[kkok] failed to pool an alert", map[string]interface{}{
+			log.FnError: err.Error(),
+			"from":      a.From,
+			"title":     a.Title,
+		})
+	}
+	metricAlertsPosted.WithLabelValues(source).Inc()
+	metricPoolSize.Set(float64(d.pool.Len()))
+}
+
+// Peek returns a (deep) copy of currently pooled alerts.
+func (d *Dispatcher) Peek() []*Alert {
+	return d.pool.Peek()
 }
 
 // Run starts dispatching alerts until ctx is canceled.
-// This method will always return nil.
+//
+// Alerts saved in the pool by the previous process are restored
+// before dispatching.  This method returns non-nil error only
+// when it fails to restore them.
+//
+// After ctx is canceled, pooled alerts are handled once more.
+// The context given to the handler is canceled when the shutdown
+// timeout expires.  In that case, the alerts being handled are not
+// committed to the pool and Run returns immediately.
 func (d *Dispatcher) Run(ctx context.Context) error {
+	err := d.pool.Load()
+	if err != nil {
+		return errors.Wrap(err, "failed to restore pooled alerts")
+	}
+	metricPoolSize.Set(float64(d.pool.Len()))
+
+	hctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-hctx.Done():
+			return
+		}
+		select {
+		case <-time.After(d.shutdownTimeout):
+			cancel()
+		case <-hctx.Done():
+		}
+	}()
+
 	cur := d.initInterval
 
 	for {
+		metricDispatchInterval.Set(cur.Seconds())
+
 		select {
 		case <-ctx.Done():
 			// process pooled alerts before quit, if any.
-			if d.alertPool.empty() {
+			if d.pool.Empty() {
 				return nil
 			}
 		case <-time.After(cur):
 		}
 
-		alerts := d.alertPool.take()
+		alerts := d.pool.Take()
+		metricPoolSize.Set(float64(d.pool.Len()))
 		if len(alerts) == 0 {
 			cur = d.initInterval
 			continue
 		}
 
-		d.handler.Handle(alerts)
+		d.handler.Handle(hctx, alerts)
+
+		if hctx.Err() != nil {
+			// The handler may have been aborted.  Leave the alerts
+			// uncommitted so that the pool can restore them later.
+			log.Warn("[kkok] shutdown timeout expired before handling alerts", map[string]interface{}{
+				"nalerts": len(alerts),
+			})
+			return nil
+		}
+
+		err = d.pool.Commit()
+		if err != nil {
+			log.Error("[kkok] failed to commit the alert pool", map[string]interface{}{
+				log.FnError: err.Error(),
+			})
+		}
 
 		cur = cur * 2
 		if cur > d.maxInterval {
@@ -2,7 +2,9 @@ package kkok
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -23,20 +25,20 @@ func TestAlertPool(t *testing.T) {
 		Title: "title2",
 		Info:  map[string]interface{}{"info2": 2},
 	}
-	p.put(a1)
-	p.put(a2)
+	p.Put(a1)
+	p.Put(a2)
 
-	alerts := p.peek()
+	alerts := p.Peek()
 	if len(alerts) != 2 {
 		t.Error(`len(alerts) != 2`)
 	}
 
-	alerts = p.take()
+	alerts = p.Take()
 	if len(alerts) != 2 {
 		t.Error(`len(alerts) != 2`)
 	}
 
-	alerts = p.take()
+	alerts = p.Take()
 	if len(alerts) > 0 {
 		t.Error(`len(alerts) > 0`)
 	}
@@ -46,7 +48,7 @@ var nchan = make(chan int, 10)
 
 type testHandler struct{}
 
-func (t testHandler) Handle(alerts []*Alert) {
+func (t testHandler) Handle(ctx context.Context, alerts []*Alert) {
 	nchan <- len(alerts)
 	return
 }
@@ -63,34 +65,34 @@ func TestDispatcher(t *testing.T) {
 	env := well.NewEnvironment(context.Background())
 	env.Go(d.Run)
 
-	d.put(&Alert{})
-	d.put(&Alert{})
+	d.Post(&Alert{})
+	d.Post(&Alert{})
 
 	// this will NOT split alerts
 	time.Sleep(2 * time.Millisecond)
 
-	d.put(&Alert{})
+	d.Post(&Alert{})
 
 	// this will split alerts.
 	time.Sleep(6 * time.Millisecond)
-	d.put(&Alert{})
+	d.Post(&Alert{})
 
 	// wait long enough
 	time.Sleep(10 * time.Millisecond)
 
-	d.put(&Alert{})
-	d.put(&Alert{})
+	d.Post(&Alert{})
+	d.Post(&Alert{})
 
 	time.Sleep(4 * time.Millisecond)
 
-	d.put(&Alert{})
-	d.put(&Alert{})
+	d.Post(&Alert{})
+	d.Post(&Alert{})
 
 	// this will NOT split alerts as the interval is extended to 6 ms.
 	time.Sleep(4 * time.Millisecond)
 
-	d.put(&Alert{})
-	d.put(&Alert{})
+	d.Post(&Alert{})
+	d.Post(&Alert{})
 
 	n := <-nchan
 	if n != 3 {
@@ -115,3 +117,89 @@ func TestDispatcher(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type blockingHandler struct {
+	ch chan int
+}
+
+func (h blockingHandler) Handle(ctx context.Context, alerts []*Alert) {
+	<-ctx.Done()
+	h.ch <- len(alerts)
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	t.Parallel()
+
+	h := blockingHandler{make(chan int, 1)}
+	d := NewDispatcher(time.Hour, time.Hour, h)
+	d.SetShutdownTimeout(50 * time.Millisecond)
+	d.Post(&Alert{})
+	d.Post(&Alert{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- d.Run(ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`Run did not return`)
+	}
+
+	if n := <-h.ch; n != 2 {
+		t.Error(`n != 2`)
+	}
+}
+
+func TestDispatcherShutdownUncommitted(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	p, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := blockingHandler{make(chan int, 1)}
+	d := NewDispatcherWithPool(time.Hour, time.Hour, h, p)
+	d.SetShutdownTimeout(50 * time.Millisecond)
+	d.Post(&Alert{From: "from1"})
+	d.Post(&Alert{From: "from2"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = d.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := <-h.ch; n != 2 {
+		t.Error(`n != 2`)
+	}
+
+	// alerts whose handling was aborted must be restored.
+	p2, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p2.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := p2.Len(); n != 2 {
+		t.Error(`n != 2`, n)
+	}
+}
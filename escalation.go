@@ -0,0 +1,213 @@
+package kkok
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	errEscalationNotFound = errors.New("no such escalation")
+)
+
+// EscalationStep is a step of an escalation policy.
+type EscalationStep struct {
+	// Route is the route ID to deliver alerts at this step.
+	Route string `toml:"route" json:"route"`
+
+	// Wait is the seconds to wait for acknowledgement
+	// before proceeding to the next step.
+	Wait int `toml:"wait" json:"wait"`
+}
+
+// Escalation is an alert being escalated until acknowledged.
+type Escalation struct {
+	// Alert is the escalated alert.
+	Alert *Alert `json:"alert"`
+
+	// Step is the index of the step to be processed next.
+	// If Step equals to the number of steps, all steps have
+	// been processed.
+	Step int `json:"step"`
+
+	// Next is the time when the next step will be processed.
+	Next time.Time `json:"next"`
+}
+
+// escalator schedules escalations of alerts.
+type escalator struct {
+	mu       sync.Mutex
+	policies map[string][]EscalationStep
+
+	// pending maps alert IDs to escalations.
+	pending map[string]*Escalation
+}
+
+func newEscalator() *escalator {
+	return &escalator{
+		policies: make(map[string][]EscalationStep),
+		pending:  make(map[string]*Escalation),
+	}
+}
+
+func (e *escalator) addPolicy(id string, steps []EscalationStep) error {
+	if !reRouteID.MatchString(id) {
+		return errors.New("invalid escalation policy id: " + id)
+	}
+	if len(steps) == 0 {
+		return errors.New("no steps in escalation policy: " + id)
+	}
+	for _, s := range steps {
+		if !reRouteID.MatchString(s.Route) {
+			return errors.New("invalid route id in escalation policy " + id + ": " + s.Route)
+		}
+		if s.Wait < 0 {
+			return errors.New("negative wait in escalation policy: " + id)
+		}
+	}
+
+	e.mu.Lock()
+	e.policies[id] = steps
+	e.mu.Unlock()
+	return nil
+}
+
+// attach starts escalations for alerts having Escalation.
+// The first step will be processed immediately unless its route
+// is already in the routes of the alert.
+func (e *escalator) attach(alerts []*Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for _, a := range alerts {
+		if len(a.Escalation) == 0 {
+			continue
+		}
+		steps, ok := e.policies[a.Escalation]
+		if !ok {
+			log.Warn("[kkok] non-existing escalation policy", map[string]interface{}{
+				"escalation": a.Escalation,
+				"alert":      a.String(),
+			})
+			continue
+		}
+		if _, ok := e.pending[a.ID]; ok {
+			continue
+		}
+
+		es := &Escalation{
+			Alert: a.Clone(),
+			Next:  now,
+		}
+		// skip the first step if the alert is being delivered to the route.
+		for _, r := range a.Routes {
+			if r == steps[0].Route {
+				es.Step = 1
+				es.Next = now.Add(time.Duration(steps[0].Wait) * time.Second)
+				break
+			}
+		}
+		e.pending[a.ID] = es
+	}
+}
+
+// due returns alerts to be delivered at now.
+// Routes of returned alerts are those of the processed steps.
+func (e *escalator) due(now time.Time) []*Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []*Alert
+	for id, es := range e.pending {
+		if now.Before(es.Next) {
+			continue
+		}
+
+		steps := e.policies[es.Alert.Escalation]
+		if es.Step >= len(steps) {
+			log.Warn("[kkok] escalation exhausted", map[string]interface{}{
+				"escalation": es.Alert.Escalation,
+				"alert_id":   id,
+			})
+			delete(e.pending, id)
+			continue
+		}
+
+		s := steps[es.Step]
+		a := es.Alert.Clone()
+		a.Routes = []string{s.Route}
+		alerts = append(alerts, a)
+
+		es.Step++
+		es.Next = now.Add(time.Duration(s.Wait) * time.Second)
+	}
+	return alerts
+}
+
+func (e *escalator) ack(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.pending[id]; !ok {
+		return errEscalationNotFound
+	}
+	delete(e.pending, id)
+	return nil
+}
+
+// list returns a (deep) copy of pending escalations.
+func (e *escalator) list() []*Escalation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	l := make([]*Escalation, 0, len(e.pending))
+	for _, es := range e.pending {
+		c := *es
+		c.Alert = es.Alert.Clone()
+		l = append(l, &c)
+	}
+	return l
+}
+
+// AddEscalationPolicy adds or replaces an escalation policy.
+//
+// Alerts whose Escalation is id are delivered to the route of
+// each step in order until acknowledged.  The escalation waits
+// for Wait seconds of a step before proceeding to the next step.
+func (k *Kkok) AddEscalationPolicy(id string, steps []EscalationStep) error {
+	return k.escalator.addPolicy(id, steps)
+}
+
+// Escalations returns a snapshot of alerts being escalated.
+func (k *Kkok) Escalations() []*Escalation {
+	return k.escalator.list()
+}
+
+// AckAlert acknowledges an alert to stop its escalation.
+func (k *Kkok) AckAlert(id string) error {
+	err := k.escalator.ack(id)
+	if err != nil {
+		return err
+	}
+
+	log.Info("[kkok] alert acknowledged", map[string]interface{}{
+		"alert_id": id,
+	})
+	return nil
+}
+
+func (k *Kkok) escalate(ctx context.Context, now time.Time) {
+	alerts := k.escalator.due(now)
+	if len(alerts) == 0 {
+		return
+	}
+
+	log.Info("[kkok] escalating alerts", map[string]interface{}{
+		"nalerts": len(alerts),
+	})
+	k.sendAlerts(ctx, alerts)
+}
@@ -0,0 +1,209 @@
+package kkok
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testEscalatorPolicy(t *testing.T) {
+	t.Parallel()
+
+	e := newEscalator()
+	err := e.addPolicy("p1", []EscalationStep{{Route: "r1", Wait: 10}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = e.addPolicy("p 2", []EscalationStep{{Route: "r1"}})
+	if err == nil {
+		t.Error(`invalid policy id is accepted`)
+	}
+	err = e.addPolicy("p3", nil)
+	if err == nil {
+		t.Error(`empty steps are accepted`)
+	}
+	err = e.addPolicy("p4", []EscalationStep{{Route: "r 1"}})
+	if err == nil {
+		t.Error(`invalid route id is accepted`)
+	}
+	err = e.addPolicy("p5", []EscalationStep{{Route: "r1", Wait: -1}})
+	if err == nil {
+		t.Error(`negative wait is accepted`)
+	}
+}
+
+func testEscalatorSteps(t *testing.T) {
+	t.Parallel()
+
+	e := newEscalator()
+	err := e.addPolicy("p1", []EscalationStep{
+		{Route: "r1", Wait: 10},
+		{Route: "r2", Wait: 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1 := &Alert{ID: "a1", Escalation: "p1"}
+	a2 := &Alert{ID: "a2", Escalation: "p1"}
+	a3 := &Alert{ID: "a3", Escalation: "none"}
+	a4 := &Alert{ID: "a4"}
+	e.attach([]*Alert{a1, a2, a3, a4})
+	if len(e.list()) != 2 {
+		t.Fatal(`len(e.list()) != 2`)
+	}
+
+	now := time.Now()
+	alerts := e.due(now)
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	for _, a := range alerts {
+		if len(a.Routes) != 1 || a.Routes[0] != "r1" {
+			t.Error(`len(a.Routes) != 1 || a.Routes[0] != "r1"`)
+		}
+	}
+
+	// attaching again does not restart the escalation.
+	e.attach([]*Alert{a1})
+	if len(e.due(now)) != 0 {
+		t.Error(`len(e.due(now)) != 0`)
+	}
+
+	err = e.ack(a2.ID)
+	if err != nil {
+		t.Error(err)
+	}
+	err = e.ack(a2.ID)
+	if err != errEscalationNotFound {
+		t.Error(`err != errEscalationNotFound`)
+	}
+
+	alerts = e.due(now.Add(10 * time.Second))
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	if alerts[0].ID != "a1" {
+		t.Error(`alerts[0].ID != "a1"`)
+	}
+	if alerts[0].Routes[0] != "r2" {
+		t.Error(`alerts[0].Routes[0] != "r2"`)
+	}
+
+	l := e.list()
+	if len(l) != 1 {
+		t.Fatal(`len(l) != 1`)
+	}
+	if l[0].Step != 2 {
+		t.Error(`l[0].Step != 2`)
+	}
+
+	// all steps are processed.
+	alerts = e.due(now.Add(30 * time.Second))
+	if len(alerts) != 0 {
+		t.Error(`len(alerts) != 0`)
+	}
+	if len(e.list()) != 0 {
+		t.Error(`len(e.list()) != 0`)
+	}
+}
+
+func testEscalatorHandle(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddEscalationPolicy("p1", []EscalationStep{
+		{Route: "r2", Wait: 10},
+		{Route: "r3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	k.AddStaticFilter(f)
+
+	tr1 := &testTransport{}
+	k.AddRoute("r1", []Transport{tr1})
+	tr2 := &testTransport{}
+	k.AddRoute("r2", []Transport{tr2})
+	tr3 := &testTransport{}
+	k.AddRoute("r3", []Transport{tr3})
+
+	k.Handle(context.Background(), []*Alert{{ID: "a1", Escalation: "p1"}})
+	if len(tr1.alerts) != 1 {
+		t.Error(`len(tr1.alerts) != 1`)
+	}
+
+	now := time.Now()
+	k.escalate(context.Background(), now)
+	if len(tr2.alerts) != 1 {
+		t.Fatal(`len(tr2.alerts) != 1`)
+	}
+	if tr2.alerts[0].ID != "a1" {
+		t.Error(`tr2.alerts[0].ID != "a1"`)
+	}
+
+	err = k.AckAlert("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.escalate(context.Background(), now.Add(time.Minute))
+	if len(tr3.alerts) != 0 {
+		t.Error(`len(tr3.alerts) != 0`)
+	}
+}
+
+func testEscalatorSharedRoute(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddEscalationPolicy("p1", []EscalationStep{
+		{Route: "r1", Wait: 10},
+		{Route: "r2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	k.AddStaticFilter(f)
+
+	tr1 := &testTransport{}
+	k.AddRoute("r1", []Transport{tr1})
+	tr2 := &testTransport{}
+	k.AddRoute("r2", []Transport{tr2})
+
+	k.Handle(context.Background(), []*Alert{{ID: "a1", Escalation: "p1"}})
+	if len(tr1.alerts) != 1 {
+		t.Error(`len(tr1.alerts) != 1`)
+	}
+
+	// the first step is skipped as r1 has been paged.
+	tr1.alerts = nil
+	now := time.Now()
+	k.escalate(context.Background(), now)
+	if len(tr1.alerts) != 0 {
+		t.Error(`r1 is paged twice`)
+	}
+	if len(tr2.alerts) != 0 {
+		t.Error(`len(tr2.alerts) != 0`)
+	}
+
+	k.escalate(context.Background(), now.Add(10*time.Second))
+	if len(tr2.alerts) != 1 {
+		t.Error(`len(tr2.alerts) != 1`)
+	}
+}
+
+func TestEscalator(t *testing.T) {
+	t.Run("Policy", testEscalatorPolicy)
+	t.Run("Steps", testEscalatorSteps)
+	t.Run("Handle", testEscalatorHandle)
+	t.Run("SharedRoute", testEscalatorSharedRoute)
+}
@@ -0,0 +1,116 @@
+package kkok
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+// fallbackTransport marks a transport as a fallback of the preceding
+// transport in a route.
+type fallbackTransport struct {
+	Transport
+}
+
+// Params implements Transport interface.
+func (t fallbackTransport) Params() PluginParams {
+	pp := t.Transport.Params()
+	m := make(map[string]interface{}, len(pp.Params)+1)
+	for k, v := range pp.Params {
+		m[k] = v
+	}
+	m["fallback"] = true
+	return PluginParams{pp.Type, m}
+}
+
+// DeliverContext implements ContextTransport interface.
+func (t fallbackTransport) DeliverContext(ctx context.Context, alerts []*Alert) error {
+	return DeliverContext(ctx, t.Transport, alerts)
+}
+
+func isFallback(t Transport) bool {
+	_, ok := t.(fallbackTransport)
+	return ok
+}
+
+// transportChain is a transport followed by its fallback transports.
+//
+// Alerts are delivered through the first transport.  If it fails,
+// the next transport is tried, and so on.  If a transport returns
+// PartialError, only undelivered alerts are passed to the next one.
+type transportChain []Transport
+
+// Params returns the parameters of the first transport.
+func (c transportChain) Params() PluginParams {
+	return c[0].Params()
+}
+
+func (c transportChain) String() string {
+	s := make([]string, len(c))
+	for i, t := range c {
+		s[i] = t.String()
+	}
+	return strings.Join(s, "|")
+}
+
+func (c transportChain) Deliver(alerts []*Alert) error {
+	return c.DeliverContext(context.Background(), alerts)
+}
+
+func (c transportChain) DeliverContext(ctx context.Context, alerts []*Alert) error {
+	var err error
+	for i, t := range c {
+		err = DeliverContext(ctx, t, alerts)
+		if err == nil {
+			return nil
+		}
+		if i == len(c)-1 {
+			break
+		}
+
+		log.Warn("[kkok] falling back to another transport", map[string]interface{}{
+			log.FnError: err.Error(),
+			"transport": t.String(),
+			"fallback":  c[i+1].String(),
+		})
+		alerts = undelivered(alerts, err)
+	}
+	return err
+}
+
+// chainTransports groups transports of a route into chains of
+// a primary transport and its fallbacks.
+func chainTransports(transports []Transport) ([]Transport, error) {
+	var chains []Transport
+	var chain transportChain
+
+	for _, t := range transports {
+		if !isFallback(t) {
+			if len(chain) > 0 {
+				chains = append(chains, chain.transport())
+			}
+			chain = transportChain{t}
+			continue
+		}
+
+		if len(chain) == 0 {
+			return nil, errors.New("the first transport cannot be a fallback")
+		}
+		chain = append(chain, t)
+	}
+
+	if len(chain) > 0 {
+		chains = append(chains, chain.transport())
+	}
+	return chains, nil
+}
+
+// transport returns the only transport if the chain has no fallbacks.
+func (c transportChain) transport() Transport {
+	if len(c) == 1 {
+		return c[0]
+	}
+	return c
+}
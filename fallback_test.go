@@ -0,0 +1,127 @@
+package kkok
+
+import (
+	"context"
+	"testing"
+)
+
+func testFallbackParams(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewTransport("fallback_test", map[string]interface{}{
+		"fallback": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isFallback(tr) {
+		t.Error(`!isFallback(tr)`)
+	}
+	if _, ok := tr.(fallbackTransport).Transport.(*testTransport).params["fallback"]; ok {
+		t.Error(`fallback is passed to the constructor`)
+	}
+	if tr.Params().Params["fallback"] != true {
+		t.Error(`tr.Params().Params["fallback"] != true`)
+	}
+
+	tr, err = NewTransport("fallback_test", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isFallback(tr) {
+		t.Error(`isFallback(tr)`)
+	}
+
+	_, err = NewTransport("fallback_test", map[string]interface{}{
+		"fallback": "yes",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testFallbackChain(t *testing.T) {
+	t.Parallel()
+
+	_, err := chainTransports([]Transport{fallbackTransport{&testTransport{}}})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	k := NewKkok()
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	err = k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr1 := &failTransport{nfail: 1}
+	tr2 := &testTransport{}
+	tr3 := &testTransport{}
+	tr4 := &testTransport{}
+	err = k.AddRoute("r1", []Transport{
+		tr1,
+		fallbackTransport{tr2},
+		tr3,
+		fallbackTransport{tr4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k.Handle(context.Background(), []*Alert{{From: "from1"}})
+	if len(tr2.alerts) != 1 {
+		t.Error(`len(tr2.alerts) != 1`)
+	}
+	if len(tr3.alerts) != 1 {
+		t.Error(`len(tr3.alerts) != 1`)
+	}
+	if len(tr4.alerts) != 0 {
+		t.Error(`len(tr4.alerts) != 0`)
+	}
+
+	// tr1 succeeds this time
+	tr2.alerts = nil
+	k.Handle(context.Background(), []*Alert{{From: "from2"}})
+	if tr1.attempts != 2 {
+		t.Error(`tr1.attempts != 2`)
+	}
+	if len(tr2.alerts) != 0 {
+		t.Error(`len(tr2.alerts) != 0`)
+	}
+	if len(k.DeadLetters()) != 0 {
+		t.Error(`len(k.DeadLetters()) != 0`)
+	}
+}
+
+func testFallbackPartial(t *testing.T) {
+	t.Parallel()
+
+	tr1 := &partialTransport{}
+	tr2 := &testTransport{}
+	c := transportChain{tr1, fallbackTransport{tr2}}
+
+	alerts := []*Alert{{Title: "a1"}, {Title: "a2"}, {Title: "a3"}}
+	err := c.Deliver(alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr1.delivered) != 1 {
+		t.Error(`len(tr1.delivered) != 1`)
+	}
+	if len(tr2.alerts) != 2 {
+		t.Error(`len(tr2.alerts) != 2`, len(tr2.alerts))
+	}
+}
+
+func TestFallback(t *testing.T) {
+	RegisterTransport("fallback_test", func(params map[string]interface{}) (Transport, error) {
+		return &testTransport{params: params}, nil
+	})
+
+	t.Run("Params", testFallbackParams)
+	t.Run("Chain", testFallbackChain)
+	t.Run("Partial", testFallbackPartial)
+}
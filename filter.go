@@ -1,6 +1,7 @@
 package kkok
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
@@ -18,10 +19,27 @@ var (
 	reFilterID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 )
 
+// Policies for filter errors.
+const (
+	// OnErrorSkip skips the filter and passes alerts to the next filter.
+	// Modifications made by the failed filter are reverted.  This is
+	// the default.
+	OnErrorSkip = "skip"
+
+	// OnErrorDrop drops all alerts being processed.
+	OnErrorDrop = "drop"
+
+	// OnErrorRoute sends alerts being processed to the error route.
+	OnErrorRoute = "route"
+)
+
 // Filter is the interface that filter plugins must implement.
 //
 // Methods other than Params and Process are implemented in BaseFilter
 // so that a filter implementation can embed BaseFilter to provide them.
+//
+// kkok calls Reload, Process, and Flush of filters one batch of alerts
+// at a time, so they need not be safe for concurrent use.
 type Filter interface {
 
 	// Params returns PluginParams that can be used to construct
@@ -62,6 +80,71 @@ type Filter interface {
 
 	// Reload reloads JavaScript files.
 	Reload() error
+
+	// OnError returns the policy for errors of the filter.
+	// The value is one of OnErrorSkip, OnErrorDrop, or OnErrorRoute.
+	OnError() string
+
+	// ErrorRoute returns the route ID for OnErrorRoute policy.
+	ErrorRoute() string
+}
+
+// ContextFilter is an optional interface for filters that can
+// abort processing when a context is done.
+type ContextFilter interface {
+	Filter
+
+	// ProcessContext applies the filter for all alerts and returns
+	// the filtered alerts.  It should return soon after ctx is done.
+	ProcessContext(ctx context.Context, alerts []*Alert) ([]*Alert, error)
+}
+
+// ProcessContext applies f for alerts.
+//
+// If f does not implement ContextFilter, this returns ctx.Err()
+// if ctx is already done, otherwise calls f.Process.
+func ProcessContext(ctx context.Context, f Filter, alerts []*Alert) ([]*Alert, error) {
+	if cf, ok := f.(ContextFilter); ok {
+		return cf.ProcessContext(ctx, alerts)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return f.Process(alerts)
+}
+
+// Flusher is an optional interface for filters that generate
+// alerts by themselves, e.g. summaries of suppressed alerts.
+//
+// Flush is called periodically.  Returned alerts are processed
+// by the filters following the filter.
+type Flusher interface {
+	Filter
+
+	// Flush returns alerts generated by the filter at now.
+	Flush(now time.Time) []*Alert
+}
+
+// ReadOnlyFilter is an optional interface for filters that never
+// modify alerts passed to Process.  Such filters may return new
+// alerts instead.
+//
+// Before Process of other filters, kkok backs up alerts to revert
+// modifications made by the filter when it fails.  The backup is
+// skipped for filters implementing this interface.
+type ReadOnlyFilter interface {
+	Filter
+
+	// ReadOnly returns true if Process does not modify alerts.
+	ReadOnly() bool
+}
+
+func isReadOnly(f Filter) bool {
+	ro, ok := f.(ReadOnlyFilter)
+	return ok && ro.ReadOnly()
 }
 
 // FilterConstructor is a function signature for filter construction.
@@ -102,15 +185,17 @@ type BaseFilter struct {
 	VM
 
 	// constants
-	id        string
-	label     string
-	dynamic   bool
-	all       bool
-	origIf    interface{}
-	ifScript  *otto.Script
-	ifCommand *exec.Cmd
-	scripts   []string
-	expire    time.Time
+	id         string
+	label      string
+	dynamic    bool
+	all        bool
+	origIf     interface{}
+	ifScript   *otto.Script
+	ifCommand  *exec.Cmd
+	scripts    []string
+	expire     time.Time
+	onError    string
+	errorRoute string
 
 	// dynamic values
 	mu            sync.Mutex
@@ -122,15 +207,17 @@ type BaseFilter struct {
 //
 // Significant keys in params are:
 //
-//    label    string    Arbitrary string label of the filter.
-//    disabled bool      If true, this filter is disabled.
-//    expire   string    RFC3339 format time at which this filter expires.
-//    all      bool      If true, the filter process all alerts at once.
-//    if       string | []string
-//                       string must be a JavaScript expression.
-//                       []string must be a command and arguments
-//                       to be invoked.
-//    scripts  []string  JavaScript filenames.
+//    label       string    Arbitrary string label of the filter.
+//    disabled    bool      If true, this filter is disabled.
+//    expire      string    RFC3339 format time at which this filter expires.
+//    all         bool      If true, the filter process all alerts at once.
+//    if          string | []string
+//                          string must be a JavaScript expression.
+//                          []string must be a command and arguments
+//                          to be invoked.
+//    scripts     []string  JavaScript filenames.
+//    on_error    string    The policy for errors: "skip", "drop", or "route".
+//    error_route string    Route ID for "route" policy.
 func (b *BaseFilter) Init(id string, params map[string]interface{}) error {
 	if !reFilterID.MatchString(id) {
 		return errors.New("invalid filter id: " + id)
@@ -138,6 +225,37 @@ func (b *BaseFilter) Init(id string, params map[string]interface{}) error {
 
 	b.id = id
 
+	onError, err := util.GetString("on_error", params)
+	switch {
+	case err == nil:
+		switch onError {
+		case OnErrorSkip, OnErrorDrop, OnErrorRoute:
+		default:
+			return errors.New("invalid on_error: " + onError)
+		}
+		b.onError = onError
+	case util.IsNotFound(err):
+		b.onError = OnErrorSkip
+	default:
+		return errors.Wrap(err, "on_error")
+	}
+
+	errorRoute, err := util.GetString("error_route", params)
+	switch {
+	case err == nil:
+		if !reRouteID.MatchString(errorRoute) {
+			return errors.New("invalid error_route: " + errorRoute)
+		}
+		b.errorRoute = errorRoute
+	case util.IsNotFound(err):
+	default:
+		return errors.Wrap(err, "error_route")
+	}
+
+	if b.onError == OnErrorRoute && len(b.errorRoute) == 0 {
+		return errors.New("error_route is required for on_error = route")
+	}
+
 	if i, ok := params["label"]; ok {
 		label, ok := i.(string)
 		if !ok {
@@ -256,6 +374,12 @@ func (b *BaseFilter) AddParams(m map[string]interface{}) {
 	if b.dynamic && (!b.expire.IsZero()) {
 		m["expire"] = b.expire
 	}
+	if len(b.onError) > 0 && b.onError != OnErrorSkip {
+		m["on_error"] = b.onError
+	}
+	if len(b.errorRoute) > 0 {
+		m["error_route"] = b.errorRoute
+	}
 }
 
 // ID returns the ID of the filter.
@@ -315,6 +439,19 @@ func (b *BaseFilter) Inactivate(until time.Time) {
 	b.inactiveUntil = until
 }
 
+// OnError returns the policy for errors of the filter.
+func (b *BaseFilter) OnError() string {
+	if len(b.onError) == 0 {
+		return OnErrorSkip
+	}
+	return b.onError
+}
+
+// ErrorRoute returns the route ID for OnErrorRoute policy.
+func (b *BaseFilter) ErrorRoute() string {
+	return b.errorRoute
+}
+
 // All returns true iff the filter processes all alerts at once.
 // If false, the filter processes alerts one by one.
 func (b *BaseFilter) All() bool {
@@ -385,6 +385,55 @@ func testBaseFilterAddParams(t *testing.T) {
 	}
 }
 
+func testBaseFilterOnError(t *testing.T) {
+	t.Parallel()
+
+	f, err := newBaseFilter("id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.OnError() != OnErrorSkip {
+		t.Error(`f.OnError() != OnErrorSkip`)
+	}
+
+	f, err = newBaseFilter("id", map[string]interface{}{
+		"on_error":    "route",
+		"error_route": "errors",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.OnError() != OnErrorRoute {
+		t.Error(`f.OnError() != OnErrorRoute`)
+	}
+	if f.ErrorRoute() != "errors" {
+		t.Error(`f.ErrorRoute() != "errors"`)
+	}
+
+	m := make(map[string]interface{})
+	f.AddParams(m)
+	if m["on_error"] != "route" {
+		t.Error(`m["on_error"] != "route"`)
+	}
+	if m["error_route"] != "errors" {
+		t.Error(`m["error_route"] != "errors"`)
+	}
+
+	_, err = newBaseFilter("id", map[string]interface{}{
+		"on_error": "route",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	_, err = newBaseFilter("id", map[string]interface{}{
+		"on_error": "ignore",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
 func TestBaseFilter(t *testing.T) {
 	t.Run("All", testBaseFilterAll)
 	t.Run("One", testBaseFilterOne)
@@ -394,4 +443,5 @@ func TestBaseFilter(t *testing.T) {
 	t.Run("Command", testBaseFilterCommand)
 	t.Run("Expire", testBaseFilterExpire)
 	t.Run("AddParams", testBaseFilterAddParams)
+	t.Run("OnError", testBaseFilterOnError)
 }
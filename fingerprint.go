@@ -0,0 +1,60 @@
+package kkok
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+	"github.com/robertkrimen/otto"
+)
+
+// Fingerprinter computes fingerprints of alerts.
+//
+// A fingerprint identifies alerts that are regarded as the same.
+// By default, it is computed from From, Host, and Title of an alert.
+type Fingerprinter struct {
+	script *otto.Script
+}
+
+// NewFingerprinter creates a Fingerprinter.
+//
+// If expr is not empty, it is a JavaScript expression to compute
+// the source string of fingerprints from "alert" variable.
+// If expr is empty, From, Host, and Title are used.
+func NewFingerprinter(expr string) (*Fingerprinter, error) {
+	if len(expr) == 0 {
+		return &Fingerprinter{}, nil
+	}
+
+	s, err := CompileJS(expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "fingerprint")
+	}
+	return &Fingerprinter{s}, nil
+}
+
+func defaultFingerprintSource(a *Alert) string {
+	return a.From + "\x00" + a.Host + "\x00" + a.Title
+}
+
+// Fingerprint returns the fingerprint of a.
+//
+// If the JavaScript expression fails, the default source is used.
+func (f *Fingerprinter) Fingerprint(a *Alert) string {
+	src := defaultFingerprintSource(a)
+	if f.script != nil {
+		v, err := NewVM().EvalAlert(a, f.script)
+		if err == nil {
+			src = v.String()
+		} else {
+			log.Warn("[kkok] failed to compute a fingerprint", map[string]interface{}{
+				log.FnError: err.Error(),
+				"alert":     a.String(),
+			})
+		}
+	}
+
+	h := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(h[:16])
+}
@@ -0,0 +1,48 @@
+package kkok
+
+import "testing"
+
+func TestFingerprinter(t *testing.T) {
+	t.Parallel()
+
+	a1 := &Alert{From: "from1", Host: "host1", Title: "title1"}
+	a2 := &Alert{From: "from1", Host: "host1", Title: "title2"}
+	a3 := &Alert{From: "from1", Host: "host1", Title: "title1", Message: "msg"}
+
+	f, err := NewFingerprinter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp1 := f.Fingerprint(a1)
+	if len(fp1) != 32 {
+		t.Error(`len(fp1) != 32`)
+	}
+	if fp1 == f.Fingerprint(a2) {
+		t.Error(`fp1 == f.Fingerprint(a2)`)
+	}
+	if fp1 != f.Fingerprint(a3) {
+		t.Error(`fp1 != f.Fingerprint(a3)`)
+	}
+
+	f, err = NewFingerprinter("alert.From + '@' + alert.Host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Fingerprint(a1) != f.Fingerprint(a2) {
+		t.Error(`f.Fingerprint(a1) != f.Fingerprint(a2)`)
+	}
+
+	// falls back to the default on errors.
+	f, err = NewFingerprinter("alert.Foo.Bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Fingerprint(a1) != fp1 {
+		t.Error(`f.Fingerprint(a1) != fp1`)
+	}
+
+	_, err = NewFingerprinter("(")
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
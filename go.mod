@@ -4,8 +4,10 @@ require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/cybozu-go/log v1.5.0
 	github.com/cybozu-go/well v1.8.1
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/google/subcommands v0.0.0-20181012225330-46f0354f6315
 	github.com/pkg/errors v0.8.0
+	github.com/prometheus/client_golang v0.9.2
 	github.com/robertkrimen/otto v0.0.0-20180617131154-15f95af6e78d
 	golang.org/x/text v0.3.0
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
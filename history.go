@@ -0,0 +1,299 @@
+package kkok
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxHistory    = 10000
+	defaultHistoryMaxAge = 7 * 24 * 3600
+)
+
+// Delivery is the outcome of a delivery attempt.
+type Delivery struct {
+	// Date is the time when the delivery finished.
+	Date time.Time `json:"date"`
+
+	// Route is the route ID of the transport.
+	Route string `json:"route"`
+
+	// Transport is the string representation of the transport.
+	Transport string `json:"transport"`
+
+	// Error is the error message if the delivery failed.
+	Error string `json:"error,omitempty"`
+}
+
+// HistoryEntry is a record of a processed alert.
+type HistoryEntry struct {
+	// ID is the unique ID of the entry.
+	ID uint64 `json:"id"`
+
+	// Date is the time when the alert was processed by filters.
+	Date time.Time `json:"date"`
+
+	// Alert is the alert as it was after filters.
+	// Alert.Routes are the final routes of the alert.
+	Alert *Alert `json:"alert"`
+
+	// Filters is a list of filter IDs that modified or created the alert.
+	Filters []string `json:"filters"`
+
+	// Deliveries is a list of delivery outcomes.
+	Deliveries []Delivery `json:"deliveries"`
+
+	// alert is the original alert being delivered.
+	alert *Alert
+}
+
+func (e *HistoryEntry) clone() *HistoryEntry {
+	c := *e
+	c.Alert = e.Alert.Clone()
+	c.Filters = append([]string{}, e.Filters...)
+	c.Deliveries = append([]Delivery{}, e.Deliveries...)
+	c.alert = nil
+	return &c
+}
+
+// HistoryQuery specifies conditions to search the history.
+// Zero values match any entries.
+type HistoryQuery struct {
+	// From matches Alert.From.
+	From string
+
+	// Host matches Alert.Host.
+	Host string
+
+	// Route matches one of Alert.Routes.
+	Route string
+
+	// Since matches entries processed at or after it.
+	Since time.Time
+
+	// Until matches entries processed before it.
+	Until time.Time
+}
+
+func (q *HistoryQuery) match(e *HistoryEntry) bool {
+	if len(q.From) > 0 && e.Alert.From != q.From {
+		return false
+	}
+	if len(q.Host) > 0 && e.Alert.Host != q.Host {
+		return false
+	}
+	if !q.Since.IsZero() && e.Date.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Date.Before(q.Until) {
+		return false
+	}
+	if len(q.Route) == 0 {
+		return true
+	}
+	for _, r := range e.Alert.Routes {
+		if r == q.Route {
+			return true
+		}
+	}
+	return false
+}
+
+// historyStore keeps processed alerts bounded by the number of
+// entries and their age.  If max is 0, nothing is recorded.
+type historyStore struct {
+	mu      sync.Mutex
+	max     int
+	maxAge  time.Duration
+	lastID  uint64
+	entries []*HistoryEntry
+
+	// index maps alerts being delivered to their entries.
+	index map[*Alert]*HistoryEntry
+}
+
+func newHistoryStore(max int, maxAge time.Duration) *historyStore {
+	return &historyStore{
+		max:    max,
+		maxAge: maxAge,
+		index:  make(map[*Alert]*HistoryEntry),
+	}
+}
+
+func (s *historyStore) enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.max > 0
+}
+
+func (s *historyStore) setLimits(max int, maxAge time.Duration) {
+	if max < 0 {
+		max = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.max = max
+	s.maxAge = maxAge
+	s.truncate(time.Now())
+}
+
+func (s *historyStore) truncate(now time.Time) {
+	n := 0
+	if len(s.entries) > s.max {
+		n = len(s.entries) - s.max
+	}
+	if s.maxAge > 0 {
+		for n < len(s.entries) && now.Sub(s.entries[n].Date) > s.maxAge {
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	for _, e := range s.entries[:n] {
+		// the alert may have been recorded again in a newer entry.
+		if s.index[e.alert] == e {
+			delete(s.index, e.alert)
+		}
+	}
+	s.entries = append([]*HistoryEntry(nil), s.entries[n:]...)
+}
+
+// record adds alerts to the history.  filters maps alerts to
+// the IDs of filters that modified or created them.
+func (s *historyStore) record(alerts []*Alert, filters map[*Alert][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.max == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, a := range alerts {
+		s.lastID++
+		e := &HistoryEntry{
+			ID:      s.lastID,
+			Date:    now,
+			Alert:   a.Clone(),
+			Filters: append([]string{}, filters[a]...),
+			alert:   a,
+		}
+		s.entries = append(s.entries, e)
+		s.index[a] = e
+	}
+	s.truncate(now)
+}
+
+// delivered records the outcome of delivery of alerts.
+// Alerts not recorded in the history are ignored.
+func (s *historyStore) delivered(route string, t Transport, alerts []*Alert, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := Delivery{
+		Date:      time.Now().UTC(),
+		Route:     route,
+		Transport: t.String(),
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	for _, a := range alerts {
+		e, ok := s.index[a]
+		if !ok {
+			continue
+		}
+		e.Deliveries = append(e.Deliveries, d)
+	}
+}
+
+// search returns a (deep) copy of entries matching q.
+func (s *historyStore) search(q *HistoryQuery) []*HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.truncate(time.Now())
+
+	l := make([]*HistoryEntry, 0)
+	for _, e := range s.entries {
+		if q.match(e) {
+			l = append(l, e.clone())
+		}
+	}
+	return l
+}
+
+// filterTracker tracks filters that modified or created alerts.
+// A nil tracker does nothing.
+type filterTracker struct {
+	filters map[*Alert][]string
+
+	// last keeps JSON of alerts after the last filter.
+	last map[*Alert][]byte
+}
+
+func newFilterTracker(alerts []*Alert) *filterTracker {
+	t := &filterTracker{
+		filters: make(map[*Alert][]string),
+	}
+	t.last = t.marshal(alerts)
+	return t
+}
+
+func (t *filterTracker) marshal(alerts []*Alert) map[*Alert][]byte {
+	m := make(map[*Alert][]byte, len(alerts))
+	for _, a := range alerts {
+		if _, ok := m[a]; ok {
+			continue
+		}
+		// Alert can always be marshaled.
+		m[a], _ = json.Marshal(a)
+	}
+	return m
+}
+
+// update records filter id for alerts that are modified or created
+// since the last update.
+func (t *filterTracker) update(id string, alerts []*Alert) {
+	if t == nil {
+		return
+	}
+
+	current := t.marshal(alerts)
+	for a, data := range current {
+		if b, ok := t.last[a]; ok && bytes.Equal(b, data) {
+			continue
+		}
+		t.filters[a] = append(t.filters[a], id)
+	}
+	t.last = current
+}
+
+func (t *filterTracker) result() map[*Alert][]string {
+	if t == nil {
+		return nil
+	}
+	return t.filters
+}
+
+// SetHistoryLimits sets the maximum number and age of processed
+// alerts kept in the history.  If max is 0 or negative, the history
+// is disabled.
+// If maxAge is 0, entries are not expired by age.
+func (k *Kkok) SetHistoryLimits(max int, maxAge time.Duration) {
+	k.history.setLimits(max, maxAge)
+}
+
+// SearchHistory returns a snapshot of processed alerts matching q.
+// Entries are ordered from the oldest.
+func (k *Kkok) SearchHistory(q *HistoryQuery) []*HistoryEntry {
+	return k.history.search(q)
+}
@@ -0,0 +1,158 @@
+package kkok
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testHistoryStore(t *testing.T) {
+	t.Parallel()
+
+	s := newHistoryStore(3, 0)
+	tr := &testTransport{}
+	a1 := &Alert{From: "from1", Host: "host1", Routes: []string{"r1"}}
+	a2 := &Alert{From: "from2", Host: "host1", Routes: []string{"r1", "r2"}}
+	s.record([]*Alert{a1, a2}, map[*Alert][]string{a1: {"f1"}})
+	s.delivered("r1", tr, []*Alert{a1, a2}, nil)
+	s.delivered("r2", tr, []*Alert{a2}, errors.New("err"))
+
+	l := s.search(&HistoryQuery{})
+	if len(l) != 2 {
+		t.Fatal(`len(l) != 2`)
+	}
+	if len(l[0].Filters) != 1 || l[0].Filters[0] != "f1" {
+		t.Error(`len(l[0].Filters) != 1 || l[0].Filters[0] != "f1"`)
+	}
+	if len(l[1].Filters) != 0 {
+		t.Error(`len(l[1].Filters) != 0`)
+	}
+	if len(l[0].Deliveries) != 1 {
+		t.Error(`len(l[0].Deliveries) != 1`)
+	}
+	if len(l[1].Deliveries) != 2 {
+		t.Fatal(`len(l[1].Deliveries) != 2`)
+	}
+	if l[1].Deliveries[1].Error != "err" {
+		t.Error(`l[1].Deliveries[1].Error != "err"`)
+	}
+
+	l = s.search(&HistoryQuery{From: "from2"})
+	if len(l) != 1 || l[0].ID != 2 {
+		t.Error(`len(l) != 1 || l[0].ID != 2`)
+	}
+	l = s.search(&HistoryQuery{Host: "host1", Route: "r2"})
+	if len(l) != 1 || l[0].ID != 2 {
+		t.Error(`len(l) != 1 || l[0].ID != 2`)
+	}
+	l = s.search(&HistoryQuery{Host: "host2"})
+	if len(l) != 0 {
+		t.Error(`len(l) != 0`)
+	}
+	l = s.search(&HistoryQuery{Since: time.Now().Add(time.Hour)})
+	if len(l) != 0 {
+		t.Error(`len(l) != 0`)
+	}
+	l = s.search(&HistoryQuery{Until: time.Now().Add(time.Hour)})
+	if len(l) != 2 {
+		t.Error(`len(l) != 2`)
+	}
+
+	// a1 is dropped
+	a3 := &Alert{From: "from3"}
+	a4 := &Alert{From: "from4"}
+	s.record([]*Alert{a3, a4}, nil)
+	l = s.search(&HistoryQuery{})
+	if len(l) != 3 {
+		t.Fatal(`len(l) != 3`)
+	}
+	if l[0].ID != 2 {
+		t.Error(`l[0].ID != 2`)
+	}
+	if _, ok := s.index[a1]; ok {
+		t.Error(`a1 is still indexed`)
+	}
+
+	s.setLimits(10, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if len(s.search(&HistoryQuery{})) != 0 {
+		t.Error(`len(s.search(&HistoryQuery{})) != 0`)
+	}
+
+	s.setLimits(0, 0)
+	s.record([]*Alert{a1}, nil)
+	if len(s.search(&HistoryQuery{})) != 0 {
+		t.Error(`len(s.search(&HistoryQuery{})) != 0`)
+	}
+}
+
+func testHistoryRerecord(t *testing.T) {
+	t.Parallel()
+
+	s := newHistoryStore(2, 0)
+	tr := &testTransport{}
+	a1 := &Alert{From: "from1"}
+	a2 := &Alert{From: "from2"}
+
+	// a1 is recorded twice, e.g. by escalation.
+	s.record([]*Alert{a1}, nil)
+	s.record([]*Alert{a2}, nil)
+	s.record([]*Alert{a1}, nil)
+
+	// the older entry for a1 is dropped, but the newer one is kept indexed.
+	if _, ok := s.index[a1]; !ok {
+		t.Fatal(`a1 is not indexed`)
+	}
+	s.delivered("r1", tr, []*Alert{a1}, nil)
+
+	l := s.search(&HistoryQuery{From: "from1"})
+	if len(l) != 1 {
+		t.Fatal(`len(l) != 1`)
+	}
+	if len(l[0].Deliveries) != 1 {
+		t.Error(`len(l[0].Deliveries) != 1`)
+	}
+}
+
+func testHistoryHandle(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	f1 := &routeFilter{}
+	f1.Init("f1", nil)
+	f1.routes = []string{"r1"}
+	// f2 does not modify alerts.
+	f2 := &routeFilter{}
+	f2.Init("f2", nil)
+	f2.routes = []string{"r1"}
+	k.AddStaticFilter(f1)
+	k.AddStaticFilter(f2)
+	k.AddRoute("r1", []Transport{&testTransport{}})
+
+	k.Handle(context.Background(), []*Alert{{From: "from1"}})
+
+	l := k.SearchHistory(&HistoryQuery{Route: "r1"})
+	if len(l) != 1 {
+		t.Fatal(`len(l) != 1`)
+	}
+	e := l[0]
+	if len(e.Filters) != 1 || e.Filters[0] != "f1" {
+		t.Error(`len(e.Filters) != 1 || e.Filters[0] != "f1"`, e.Filters)
+	}
+	if len(e.Deliveries) != 1 {
+		t.Fatal(`len(e.Deliveries) != 1`)
+	}
+	if e.Deliveries[0].Transport != "test" {
+		t.Error(`e.Deliveries[0].Transport != "test"`)
+	}
+	if len(e.Deliveries[0].Error) != 0 {
+		t.Error(`len(e.Deliveries[0].Error) != 0`)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Run("Store", testHistoryStore)
+	t.Run("Handle", testHistoryHandle)
+	t.Run("Rerecord", testHistoryRerecord)
+}
@@ -0,0 +1,154 @@
+package kkok
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultIncidentTimeout = 86400
+)
+
+// Incident is the state of firing alerts sharing a fingerprint.
+type Incident struct {
+	// Fingerprint is the fingerprint of the alerts.
+	Fingerprint string
+
+	// AlertID is the ID of the first firing alert.
+	AlertID string
+
+	// Opened is the time when the first firing alert was handled.
+	Opened time.Time
+
+	// Updated is the time when the last firing alert was handled.
+	Updated time.Time
+
+	// Count is the number of firing alerts.
+	Count int
+
+	// Delivered is true if any of the firing alerts was delivered.
+	Delivered bool
+
+	// Resolved is the time when the incident was resolved.
+	// This is zero unless resolved.
+	Resolved time.Time
+}
+
+// incidentTable keeps open incidents keyed by fingerprints.
+// Incidents not updated for timeout are forgotten.
+type incidentTable struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	open    map[string]*Incident
+}
+
+func newIncidentTable(timeout time.Duration) *incidentTable {
+	return &incidentTable{
+		timeout: timeout,
+		open:    make(map[string]*Incident),
+	}
+}
+
+func (t *incidentTable) setTimeout(timeout time.Duration) {
+	t.mu.Lock()
+	t.timeout = timeout
+	t.mu.Unlock()
+}
+
+func (t *incidentTable) expire(now time.Time) {
+	if t.timeout <= 0 {
+		return
+	}
+	for fp, inc := range t.open {
+		if now.Sub(inc.Updated) > t.timeout {
+			delete(t.open, fp)
+		}
+	}
+}
+
+// track updates the incident of a and returns a snapshot of it.
+//
+// A firing alert opens or updates the incident.  A resolved alert
+// closes the incident.  If a has no fingerprint, or a is resolved
+// but there is no open incident, this returns nil.
+func (t *incidentTable) track(a *Alert, now time.Time) *Incident {
+	if len(a.Fingerprint) == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(now)
+
+	inc, ok := t.open[a.Fingerprint]
+	if a.Resolved() {
+		if !ok {
+			return nil
+		}
+		delete(t.open, a.Fingerprint)
+		c := *inc
+		c.Resolved = now.UTC()
+		return &c
+	}
+
+	if !ok {
+		inc = &Incident{
+			Fingerprint: a.Fingerprint,
+			AlertID:     a.ID,
+			Opened:      now.UTC(),
+		}
+		t.open[a.Fingerprint] = inc
+	}
+	inc.Updated = now.UTC()
+	inc.Count++
+	c := *inc
+	return &c
+}
+
+// delivered marks incidents of firing alerts as delivered.
+// Sub alerts are also taken into account.
+func (t *incidentTable) delivered(alerts []*Alert) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var mark func(alerts []*Alert)
+	mark = func(alerts []*Alert) {
+		for _, a := range alerts {
+			mark(a.Sub)
+			if a.Resolved() {
+				continue
+			}
+			if inc, ok := t.open[a.Fingerprint]; ok {
+				inc.Delivered = true
+			}
+		}
+	}
+	mark(alerts)
+}
+
+// list returns a copy of open incidents.
+func (t *incidentTable) list() []*Incident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(time.Now())
+
+	l := make([]*Incident, 0, len(t.open))
+	for _, inc := range t.open {
+		c := *inc
+		l = append(l, &c)
+	}
+	return l
+}
+
+// SetIncidentTimeout sets the duration to forget open incidents
+// that have not been updated.  0 means never.
+func (k *Kkok) SetIncidentTimeout(timeout time.Duration) {
+	k.incidents.setTimeout(timeout)
+}
+
+// Incidents returns a snapshot of open incidents.
+func (k *Kkok) Incidents() []*Incident {
+	return k.incidents.list()
+}
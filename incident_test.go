@@ -0,0 +1,99 @@
+package kkok
+
+import (
+	"testing"
+	"time"
+)
+
+func testIncidentTrack(t *testing.T) {
+	t.Parallel()
+
+	it := newIncidentTable(time.Hour)
+	now := time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	if it.track(&Alert{ID: "a0"}, now) != nil {
+		t.Error(`alert without fingerprint has an incident`)
+	}
+	if it.track(&Alert{Fingerprint: "fp", Status: StatusResolved}, now) != nil {
+		t.Error(`resolved alert without open incident has an incident`)
+	}
+
+	inc := it.track(&Alert{ID: "a1", Fingerprint: "fp"}, now)
+	if inc == nil {
+		t.Fatal(`inc == nil`)
+	}
+	if inc.AlertID != "a1" {
+		t.Error(`inc.AlertID != "a1"`)
+	}
+	if inc.Count != 1 {
+		t.Error(`inc.Count != 1`)
+	}
+	if !inc.Opened.Equal(now) {
+		t.Error(`!inc.Opened.Equal(now)`)
+	}
+
+	later := now.Add(time.Minute)
+	inc = it.track(&Alert{ID: "a2", Fingerprint: "fp", Status: StatusFiring}, later)
+	if inc.AlertID != "a1" {
+		t.Error(`inc.AlertID != "a1"`)
+	}
+	if inc.Count != 2 {
+		t.Error(`inc.Count != 2`)
+	}
+	if !inc.Updated.Equal(later) {
+		t.Error(`!inc.Updated.Equal(later)`)
+	}
+	if inc.Delivered {
+		t.Error(`inc.Delivered`)
+	}
+
+	it.delivered([]*Alert{
+		{Sub: []*Alert{{Fingerprint: "fp"}}},
+	})
+
+	inc = it.track(&Alert{ID: "a3", Fingerprint: "fp", Status: StatusResolved}, later)
+	if inc == nil {
+		t.Fatal(`inc == nil`)
+	}
+	if !inc.Delivered {
+		t.Error(`!inc.Delivered`)
+	}
+	if !inc.Resolved.Equal(later) {
+		t.Error(`!inc.Resolved.Equal(later)`)
+	}
+	if len(it.open) != 0 {
+		t.Error(`len(it.open) != 0`)
+	}
+}
+
+func testIncidentExpire(t *testing.T) {
+	t.Parallel()
+
+	it := newIncidentTable(time.Hour)
+	now := time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	it.track(&Alert{ID: "a1", Fingerprint: "fp1"}, now)
+	it.track(&Alert{ID: "a2", Fingerprint: "fp2"}, now.Add(30*time.Minute))
+
+	inc := it.track(&Alert{ID: "a3", Fingerprint: "fp1"}, now.Add(2*time.Hour))
+	if inc.AlertID != "a3" {
+		t.Error(`expired incident was not forgotten`)
+	}
+	if inc.Count != 1 {
+		t.Error(`inc.Count != 1`)
+	}
+	if len(it.open) != 1 {
+		t.Error(`len(it.open) != 1`)
+	}
+
+	it.setTimeout(0)
+	it.track(&Alert{ID: "a4", Fingerprint: "fp2"}, now.Add(100*time.Hour))
+	if len(it.open) != 2 {
+		t.Error(`incidents expired with zero timeout`)
+	}
+}
+
+func TestIncident(t *testing.T) {
+	t.Run("Track", testIncidentTrack)
+	t.Run("Expire", testIncidentExpire)
+}
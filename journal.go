@@ -0,0 +1,296 @@
+package kkok
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+// journalRecord is a line of the journal file.
+//
+// A record with Alert is written when an alert is pooled.
+// A record with Done tells that all alerts whose Seq are less than
+// or equal to Done have been handled.  Such records are no longer
+// written as the journal is compacted on Commit, but are still
+// understood to read journals of older versions.
+type journalRecord struct {
+	Seq   uint64 `json:"seq,omitempty"`
+	Alert *Alert `json:"alert,omitempty"`
+	Done  uint64 `json:"done,omitempty"`
+}
+
+type journalEntry struct {
+	seq   uint64
+	alert *Alert
+}
+
+// journalPool is a Pool that records pooled alerts in an append-only
+// journal file so that they can be restored after restarts.
+type journalPool struct {
+	filename string
+
+	mu      sync.Mutex
+	f       *os.File
+	loaded  bool
+	seq     uint64
+	taken   uint64
+	entries []journalEntry
+}
+
+// NewJournalPool creates a Pool that writes pooled alerts to the
+// journal file before accepting them.
+//
+// Alerts that were pooled but not handled by the previous process
+// are restored from the journal by Load.
+func NewJournalPool(filename string) (Pool, error) {
+	f, err := os.OpenFile(filename, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "journal")
+	}
+	f.Close()
+
+	return &journalPool{filename: filename}, nil
+}
+
+// readJournal reads records from the journal and returns entries
+// that have not been handled yet.
+func readJournal(r io.Reader, filename string) ([]journalEntry, error) {
+	var entries []journalEntry
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// the last write may have been interrupted.
+				log.Warn("[kkok] ignored an incomplete journal record", map[string]interface{}{
+					"filename": filename,
+				})
+			}
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var rec journalRecord
+		err = json.Unmarshal(line, &rec)
+		if err != nil {
+			log.Warn("[kkok] ignored a broken journal record", map[string]interface{}{
+				log.FnError: err.Error(),
+				"filename":  filename,
+			})
+			continue
+		}
+
+		if rec.Alert != nil {
+			entries = append(entries, journalEntry{rec.Seq, rec.Alert})
+			continue
+		}
+
+		n := 0
+		for _, e := range entries {
+			if e.seq > rec.Done {
+				entries[n] = e
+				n++
+			}
+		}
+		entries = entries[:n]
+	}
+}
+
+// Load restores alerts from the journal file, then rewrites the
+// journal to contain only the restored alerts.
+func (p *journalPool) Load() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.loaded {
+		return nil
+	}
+
+	f, err := os.Open(p.filename)
+	if err != nil {
+		return errors.Wrap(err, "journal")
+	}
+	restored, err := readJournal(f, p.filename)
+	f.Close()
+	if err != nil {
+		return errors.Wrap(err, "journal")
+	}
+
+	if len(restored) > 0 {
+		log.Info("[kkok] restored pooled alerts", map[string]interface{}{
+			"filename": p.filename,
+			"nalerts":  len(restored),
+		})
+	}
+
+	// alerts put before Load follow the restored ones.
+	entries := append(restored, p.entries...)
+	for i := range entries {
+		entries[i].seq = uint64(i + 1)
+	}
+
+	err = p.rewrite(entries)
+	if err != nil {
+		return errors.Wrap(err, "journal")
+	}
+
+	p.entries = entries
+	p.seq = uint64(len(entries))
+	p.loaded = true
+	return nil
+}
+
+// rewrite atomically replaces the journal with records of entries,
+// then reopens the journal for appending.
+func (p *journalPool) rewrite(entries []journalEntry) error {
+	tmpname := p.filename + ".tmp"
+	g, err := os.OpenFile(tmpname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(g)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		err = enc.Encode(&journalRecord{Seq: e.seq, Alert: e.alert})
+		if err != nil {
+			g.Close()
+			return err
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = g.Sync()
+	}
+	g.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpname, p.filename)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p.filename, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if p.f != nil {
+		p.f.Close()
+	}
+	p.f = f
+	return nil
+}
+
+func (p *journalPool) write(rec *journalRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = p.f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+// Put writes an alert to the journal and puts it into the pool.
+//
+// Even if this returns an error, the alert is pooled in memory.
+func (p *journalPool) Put(a *Alert) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.loaded {
+		// the journal will be written in Load.
+		p.entries = append(p.entries, journalEntry{0, a})
+		return nil
+	}
+
+	p.seq++
+	p.entries = append(p.entries, journalEntry{p.seq, a})
+	err := p.write(&journalRecord{Seq: p.seq, Alert: a})
+	if err != nil {
+		return errors.Wrap(err, "journal")
+	}
+	return nil
+}
+
+// Empty returns true if the pool is empty.
+func (p *journalPool) Empty() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.entries) == 0
+}
+
+// Len returns the number of pooled alerts.
+func (p *journalPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.entries)
+}
+
+// Peek returns a (deep) copy of currently pooled alerts.
+func (p *journalPool) Peek() []*Alert {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) == 0 {
+		return nil
+	}
+
+	c := make([]*Alert, len(p.entries))
+	for i, e := range p.entries {
+		c[i] = e.alert.Clone()
+	}
+	return c
+}
+
+// Take returns pooled alerts and clears the pool.
+//
+// The taken alerts remain in the journal until Commit is called.
+func (p *journalPool) Take() []*Alert {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) == 0 {
+		return nil
+	}
+
+	c := make([]*Alert, len(p.entries))
+	for i, e := range p.entries {
+		c[i] = e.alert
+	}
+	p.taken = p.entries[len(p.entries)-1].seq
+	p.entries = nil
+	return c
+}
+
+// Commit removes the alerts returned by the last Take from the
+// journal.  The journal is compacted to contain only alerts pooled
+// after the last Take.
+func (p *journalPool) Commit() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.loaded || p.taken == 0 {
+		return nil
+	}
+
+	err := p.rewrite(p.entries)
+	if err != nil {
+		return errors.Wrap(err, "journal")
+	}
+	p.taken = 0
+	return nil
+}
@@ -0,0 +1,237 @@
+package kkok
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testJournalRestore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	p, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Put(&Alert{From: "from1", Title: "title1"})
+	p.Put(&Alert{From: "from2", Title: "title2"})
+
+	alerts := p.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	err = p.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Put(&Alert{From: "from3", Title: "title3"})
+	p.Put(&Alert{From: "from4", Title: "title4"})
+	alerts = p.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+
+	// not committed
+	p.Put(&Alert{From: "from5", Title: "title5"})
+
+	p2, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p2.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts = p2.Peek()
+	if len(alerts) != 3 {
+		t.Fatal(`len(alerts) != 3`)
+	}
+	if alerts[0].From != "from3" {
+		t.Error(`alerts[0].From != "from3"`)
+	}
+	if alerts[2].Title != "title5" {
+		t.Error(`alerts[2].Title != "title5"`)
+	}
+}
+
+func testJournalTruncate(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	p, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Put(&Alert{From: "from1", Title: "title1"})
+	p.Take()
+	err = p.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 {
+		t.Error(`fi.Size() != 0`)
+	}
+
+	p.Put(&Alert{From: "from2", Title: "title2"})
+
+	p2, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p2.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := p2.Take()
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	if alerts[0].From != "from2" {
+		t.Error(`alerts[0].From != "from2"`)
+	}
+}
+
+func testJournalBroken(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	data := `{"seq":1,"alert":{"from":"from1","title":"title1"}}
+{"done":1}
+{"seq":2,"alert":{"from":"from2","title":"title2"}}
+{"seq":3,"alert":{"from":"fr`
+	err = ioutil.WriteFile(filename, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// alerts put before Load are kept after restored ones.
+	p.Put(&Alert{From: "from4", Title: "title4"})
+
+	err = p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := p.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].From != "from2" {
+		t.Error(`alerts[0].From != "from2"`)
+	}
+	if alerts[1].From != "from4" {
+		t.Error(`alerts[1].From != "from4"`)
+	}
+}
+
+func testJournalCompact(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal")
+
+	p, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Put(&Alert{From: "from1", Title: "title1"})
+	p.Put(&Alert{From: "from2", Title: "title2"})
+	p.Take()
+
+	// put while handling the taken alerts.
+	p.Put(&Alert{From: "from3", Title: "title3"})
+	err = p.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte{'\n'}); n != 1 {
+		t.Error(`n != 1`, n)
+	}
+
+	p.Put(&Alert{From: "from4", Title: "title4"})
+
+	p2, err := NewJournalPool(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p2.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := p2.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].From != "from3" {
+		t.Error(`alerts[0].From != "from3"`)
+	}
+	if alerts[1].From != "from4" {
+		t.Error(`alerts[1].From != "from4"`)
+	}
+}
+
+func TestJournalPool(t *testing.T) {
+	t.Run("Restore", testJournalRestore)
+	t.Run("Truncate", testJournalTruncate)
+	t.Run("Broken", testJournalBroken)
+	t.Run("Compact", testJournalCompact)
+}
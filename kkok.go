@@ -1,17 +1,42 @@
 package kkok
 
 import (
+	"context"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/cybozu-go/log"
 	"github.com/pkg/errors"
 )
 
+const (
+	schedulerInterval = time.Second
+)
+
 var (
 	reRouteID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 )
 
+// route is a list of transports.
+type route struct {
+	transports []Transport
+
+	// chains are transports grouped with their fallbacks.
+	chains []Transport
+
+	// dynamic is true if the route is added dynamically.
+	dynamic bool
+}
+
+func newRoute(transports []Transport, dynamic bool) (*route, error) {
+	chains, err := chainTransports(transports)
+	if err != nil {
+		return nil, err
+	}
+	return &route{transports, chains, dynamic}, nil
+}
+
 // Kkok is the struct to compose kkok.
 //
 // Internal APIs to work on generators/routes/filters are provided by this.
@@ -20,23 +45,76 @@ type Kkok struct {
 	lkr sync.Mutex
 
 	// routes maps route ID to a route (= list of transports).
-	routes map[string][]Transport
+	routes map[string]*route
 
 	// lock for filters
 	lkf sync.Mutex
 
 	// filters are ordered as defined.
 	filters []Filter
+
+	// lock to apply filters one batch at a time because filters
+	// are not safe for concurrent use.
+	lkp sync.Mutex
+
+	// lock for the state file
+	lks sync.Mutex
+
+	// stateFile is the filename to save dynamic filters and routes.
+	stateFile string
+
+	// retry is the policy to retry failed deliveries.
+	retry RetryPolicy
+
+	// deliveryTimeout limits the time to deliver alerts through
+	// a transport.
+	deliveryTimeout time.Duration
+
+	// deadLetters keeps alerts that could not be delivered.
+	deadLetters *deadLetterStore
+
+	// history keeps processed alerts and their delivery outcomes.
+	history *historyStore
+
+	// escalator keeps alerts being escalated until acknowledged.
+	escalator *escalator
+
+	// incidents pairs resolved alerts with firing ones.
+	incidents *incidentTable
+
+	// webhooks are sources receiving alerts via the API server.
+	webhooks *webhookTable
 }
 
 // NewKkok constructs a new empty Kkok.
 func NewKkok() *Kkok {
 	return &Kkok{
-		routes:  make(map[string][]Transport),
-		filters: make([]Filter, 0, 10),
+		routes:      make(map[string]*route),
+		filters:     make([]Filter, 0, 10),
+		deadLetters: newDeadLetterStore(defaultMaxDeadLetters),
+		history:     newHistoryStore(defaultMaxHistory, defaultHistoryMaxAge*time.Second),
+		escalator:   newEscalator(),
+		incidents:   newIncidentTable(defaultIncidentTimeout * time.Second),
+		webhooks:    newWebhookTable(),
 	}
 }
 
+// SetRetryPolicy sets the policy to retry failed deliveries.
+// This should be called before handling alerts.
+//
+// By default, failed deliveries are not retried.
+func (k *Kkok) SetRetryPolicy(p RetryPolicy) {
+	k.retry = p
+}
+
+// SetDeliveryTimeout sets the maximum duration to deliver alerts
+// through a transport.  This should be called before handling alerts.
+//
+// By default, deliveries do not time out.
+func (k *Kkok) SetDeliveryTimeout(timeout time.Duration) {
+	k.deliveryTimeout = timeout
+}
+
 // RouteIDs return a slice of route IDs.
 func (k *Kkok) RouteIDs() []string {
 	k.lkr.Lock()
@@ -63,59 +141,208 @@ func (k *Kkok) Filters() []Filter {
 }
 
 // Handle implements AlertHandler interface.
-func (k *Kkok) Handle(alerts []*Alert) {
+//
+// Filters and transports should give up processing alerts
+// when ctx is done.
+//
+// Before filters, the incident of each alert is tracked and
+// its snapshot is set to Alert.Incident.
+func (k *Kkok) Handle(ctx context.Context, alerts []*Alert) {
+	now := time.Now()
+	for _, a := range alerts {
+		a.Incident = k.incidents.track(a, now)
+	}
+	k.handle(ctx, k.Filters(), alerts)
+}
+
+// handle applies filters to alerts then sends them.
+func (k *Kkok) handle(ctx context.Context, filters []Filter, alerts []*Alert) {
 	if len(alerts) == 0 {
 		return
 	}
 
-	var err error
-	for _, f := range k.Filters() {
+	alerts = k.filter(ctx, filters, alerts)
+	k.sendAlerts(ctx, alerts)
+}
+
+// filter applies filters to alerts and records the result in the
+// history.  Alerts to be sent are returned.
+//
+// Dispatched alerts and alerts generated by Flusher filters are
+// filtered one batch at a time.
+func (k *Kkok) filter(ctx context.Context, filters []Filter, alerts []*Alert) []*Alert {
+	k.lkp.Lock()
+	defer k.lkp.Unlock()
+
+	var tracker *filterTracker
+	if k.history.enabled() {
+		tracker = newFilterTracker(alerts)
+	}
+
+	for _, f := range filters {
 		if f.Disabled() {
 			continue
 		}
 
-		err = f.Reload()
-		if err != nil {
-			log.Error("[kkok] failed to reload", map[string]interface{}{
-				log.FnError: err.Error(),
-				"filter":    f.ID(),
-			})
-			return
+		n := len(alerts)
+
+		// the filter may modify alerts before it fails.
+		var backup []*Alert
+		if f.OnError() != OnErrorDrop && !isReadOnly(f) {
+			backup = make([]*Alert, n)
+			for i, a := range alerts {
+				backup[i] = a.Clone()
+			}
 		}
 
-		alerts, err = f.Process(alerts)
+		filtered, err := k.process(ctx, f, alerts)
 		if err != nil {
+			for i, a := range backup {
+				*alerts[i] = *a
+			}
+
+			metricFilterErrors.WithLabelValues(f.ID()).Inc()
 			log.Error("[kkok] failed to filter alerts", map[string]interface{}{
 				log.FnError: err.Error(),
 				"filter":    f.ID(),
-				"nalerts":   len(alerts),
+				"nalerts":   n,
+				"on_error":  f.OnError(),
 			})
-			return
+
+			switch f.OnError() {
+			case OnErrorSkip:
+				continue
+			case OnErrorRoute:
+				for _, a := range alerts {
+					a.Routes = []string{f.ErrorRoute()}
+				}
+				return k.accept(alerts, tracker)
+			}
+			return nil
+		}
+		alerts = filtered
+		tracker.update(f.ID(), alerts)
+
+		if len(alerts) < n {
+			metricFilterReduced.WithLabelValues(f.ID()).Add(float64(n - len(alerts)))
 		}
 
 		if len(alerts) == 0 {
 			log.Info("[kkok] filters reduced all alerts", map[string]interface{}{
 				"filter": f.ID(),
 			})
-			return
+			return nil
+		}
+	}
+
+	return k.accept(alerts, tracker)
+}
+
+// accept prepares filtered alerts for delivery.
+func (k *Kkok) accept(alerts []*Alert, tracker *filterTracker) []*Alert {
+	for _, a := range alerts {
+		// alerts created by filters may not have IDs.
+		if len(a.ID) == 0 {
+			a.ID = newAlertID()
 		}
 	}
 
-	k.sendAlerts(alerts)
+	k.escalator.attach(alerts)
+	k.history.record(alerts, tracker.result())
+	return alerts
 }
 
-// AddRoute adds or replaces a route with id.
-func (k *Kkok) AddRoute(id string, route []Transport) error {
+// flush processes alerts generated by Flusher filters.
+func (k *Kkok) flush(ctx context.Context, now time.Time) {
+	filters := k.Filters()
+	for i, f := range filters {
+		fl, ok := f.(Flusher)
+		if !ok || f.Disabled() {
+			continue
+		}
+
+		k.lkp.Lock()
+		alerts := fl.Flush(now)
+		k.lkp.Unlock()
+		if len(alerts) == 0 {
+			continue
+		}
+		log.Info("[kkok] filter generated alerts", map[string]interface{}{
+			"filter":  f.ID(),
+			"nalerts": len(alerts),
+		})
+		k.handle(ctx, filters[i+1:], alerts)
+	}
+}
+
+// RunScheduler runs periodic jobs until ctx is canceled.
+// Jobs include escalations and processing alerts generated
+// by Flusher filters.  This always returns nil.
+func (k *Kkok) RunScheduler(ctx context.Context) error {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			k.escalate(ctx, now)
+			k.flush(ctx, now)
+		}
+	}
+}
+
+func (k *Kkok) process(ctx context.Context, f Filter, alerts []*Alert) ([]*Alert, error) {
+	err := f.Reload()
+	if err != nil {
+		return nil, errors.Wrap(err, "reload")
+	}
+	return ProcessContext(ctx, f, alerts)
+}
+
+// AddRoute adds or replaces a route with id statically.
+func (k *Kkok) AddRoute(id string, transports []Transport) error {
 	if !reRouteID.MatchString(id) {
 		return errors.New("invalid route id: " + id)
 	}
 
+	r, err := newRoute(transports, false)
+	if err != nil {
+		return err
+	}
+
 	k.lkr.Lock()
-	k.routes[id] = route
+	k.routes[id] = r
 	k.lkr.Unlock()
 	return nil
 }
 
+// PutRoute adds or replaces a route with id dynamically.
+// If the existing route is static, the new route remains static.
+//
+// If the state file is set, dynamic routes are saved in it.
+// Non-nil error is returned when the route is put but not saved.
+func (k *Kkok) PutRoute(id string, transports []Transport) error {
+	if !reRouteID.MatchString(id) {
+		return errors.New("invalid route id: " + id)
+	}
+
+	r, err := newRoute(transports, true)
+	if err != nil {
+		return err
+	}
+
+	k.lkr.Lock()
+	if old, ok := k.routes[id]; ok {
+		r.dynamic = old.dynamic
+	}
+	k.routes[id] = r
+	k.lkr.Unlock()
+
+	return k.saveState()
+}
+
 // AddStaticFilter adds a filter statically.
 func (k *Kkok) AddStaticFilter(filter Filter) error {
 	k.lkf.Lock()
@@ -136,7 +363,15 @@ func (k *Kkok) AddStaticFilter(filter Filter) error {
 }
 
 // PutFilter adds or replaces a filter with filter.ID().
-func (k *Kkok) PutFilter(filter Filter) {
+//
+// If the state file is set, dynamic filters are saved in it.
+// Non-nil error is returned when the filter is put but not saved.
+func (k *Kkok) PutFilter(filter Filter) error {
+	k.putFilter(filter)
+	return k.saveState()
+}
+
+func (k *Kkok) putFilter(filter Filter) {
 	k.lkf.Lock()
 	defer k.lkf.Unlock()
 
@@ -165,7 +400,28 @@ func (k *Kkok) getRoute(id string) []Transport {
 	k.lkr.Lock()
 	defer k.lkr.Unlock()
 
-	return k.routes[id]
+	r, ok := k.routes[id]
+	if !ok {
+		return nil
+	}
+	return r.transports
+}
+
+func (k *Kkok) removeRoute(id string) error {
+	k.lkr.Lock()
+	r, ok := k.routes[id]
+	if !ok {
+		k.lkr.Unlock()
+		return nil
+	}
+	if !r.dynamic {
+		k.lkr.Unlock()
+		return errors.New("static routes cannot be removed")
+	}
+	delete(k.routes, id)
+	k.lkr.Unlock()
+
+	return k.saveState()
 }
 
 func (k *Kkok) gc() {
@@ -180,6 +436,14 @@ func (k *Kkok) gc() {
 }
 
 func (k *Kkok) removeFilter(id string) error {
+	err := k.deleteFilter(id)
+	if err != nil {
+		return err
+	}
+	return k.saveState()
+}
+
+func (k *Kkok) deleteFilter(id string) error {
 	k.lkf.Lock()
 	defer k.lkf.Unlock()
 
@@ -214,7 +478,7 @@ func (k *Kkok) getFilter(id string) Filter {
 	return nil
 }
 
-func (k *Kkok) sendAlerts(alerts []*Alert) {
+func (k *Kkok) sendAlerts(ctx context.Context, alerts []*Alert) {
 	routedAlerts := make(map[string][]*Alert)
 	for _, a := range alerts {
 		for _, id := range a.Routes {
@@ -222,35 +486,62 @@ func (k *Kkok) sendAlerts(alerts []*Alert) {
 		}
 	}
 
+	// take a snapshot of routes not to block route updates.
+	routes := make(map[string][]Transport)
 	k.lkr.Lock()
-	defer func() {
-		k.lkr.Unlock()
-		for id := range routedAlerts {
+	for id := range routedAlerts {
+		r, ok := k.routes[id]
+		if !ok {
+			continue
+		}
+		routes[id] = r.chains
+	}
+	k.lkr.Unlock()
+
+	var wg sync.WaitGroup
+	for id, alerts := range routedAlerts {
+		chains, ok := routes[id]
+		if !ok {
 			log.Warn("[kkok] non-existing route", map[string]interface{}{
 				"route": id,
 			})
+			continue
 		}
-	}()
-
-	for id, r := range k.routes {
-		alerts = routedAlerts[id]
-		delete(routedAlerts, id)
 
 		log.Info("[kkok] sending alerts", map[string]interface{}{
 			"route":   id,
 			"nalerts": len(alerts),
 		})
 
-		for _, t := range r {
-			// t.Deliver will not take too long.
-			err := t.Deliver(alerts)
-			if err != nil {
-				log.Error("[kkok] failed to send alerts", map[string]interface{}{
-					log.FnError: err.Error(),
-					"route":     id,
-					"transport": t.String(),
-				})
-			}
+		for _, t := range chains {
+			wg.Add(1)
+			go func(id string, t Transport, alerts []*Alert) {
+				defer wg.Done()
+				k.deliver(ctx, id, withTimeout(t, k.deliveryTimeout), alerts)
+			}(id, t, alerts)
 		}
 	}
+	wg.Wait()
+}
+
+func (k *Kkok) deliver(ctx context.Context, route string, t Transport, alerts []*Alert) {
+	err := DeliverContext(ctx, t, alerts)
+	k.history.delivered(route, t, alerts, err)
+	if err == nil {
+		k.incidents.delivered(alerts)
+		metricDeliveries.WithLabelValues(route, t.String()).Inc()
+		return
+	}
+	metricDeliveryFailures.WithLabelValues(route, t.String()).Inc()
+
+	log.Error("[kkok] failed to send alerts", map[string]interface{}{
+		log.FnError: err.Error(),
+		"route":     route,
+		"transport": t.String(),
+	})
+	if k.retry.Enabled() {
+		k.goRetry(route, t, alerts, err)
+	} else {
+		k.deadLetters.add(route, t, undelivered(alerts, err), err)
+	}
 }
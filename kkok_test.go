@@ -1,6 +1,11 @@
 package kkok
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
 
 type dupFilter struct {
 	BaseFilter
@@ -67,6 +72,7 @@ func (f *routeFilter) Process(alerts []*Alert) ([]*Alert, error) {
 
 type testTransport struct {
 	alerts []*Alert
+	params map[string]interface{}
 }
 
 func (t *testTransport) Params() PluginParams {
@@ -200,14 +206,14 @@ func testHandleMultiFilters(t *testing.T) {
 	k.AddRoute("r1", []Transport{tr1})
 
 	a1 := &Alert{Routes: []string{"r1"}}
-	k.Handle([]*Alert{a1})
+	k.Handle(context.Background(), []*Alert{a1})
 	if len(tr1.alerts) != 0 {
 		t.Error(len(tr1.alerts) != 0)
 	}
 
 	a2 := &Alert{Routes: []string{"r1"}}
 	f2.Enable(false)
-	k.Handle([]*Alert{a2})
+	k.Handle(context.Background(), []*Alert{a2})
 	if len(tr1.alerts) != 2 {
 		t.Error(len(tr1.alerts) != 2)
 	}
@@ -234,7 +240,7 @@ func testHandleMultiRoutes(t *testing.T) {
 	k.AddRoute("r2", []Transport{tr2})
 
 	a1 := &Alert{Routes: []string{}}
-	k.Handle([]*Alert{a1})
+	k.Handle(context.Background(), []*Alert{a1})
 	if len(tr1.alerts) != 1 {
 		t.Error("len(tr1.alerts) != 1")
 	}
@@ -243,9 +249,214 @@ func testHandleMultiRoutes(t *testing.T) {
 	}
 }
 
+type errorFilter struct {
+	BaseFilter
+}
+
+func (f *errorFilter) Params() PluginParams {
+	p := PluginParams{
+		Type:   "error",
+		Params: make(map[string]interface{}),
+	}
+	f.BaseFilter.AddParams(p.Params)
+	return p
+}
+
+func (f *errorFilter) Process(alerts []*Alert) ([]*Alert, error) {
+	// modifications before errors should be reverted.
+	for _, a := range alerts {
+		a.Title = "modified"
+	}
+	return nil, errors.New("error")
+}
+
+func testHandleOnError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params map[string]interface{}
+		r1     int
+		r2     int
+	}{
+		{nil, 1, 0},
+		{map[string]interface{}{"on_error": "skip"}, 1, 0},
+		{map[string]interface{}{"on_error": "drop"}, 0, 0},
+		{map[string]interface{}{"on_error": "route", "error_route": "r2"}, 0, 1},
+	}
+
+	for _, c := range testCases {
+		k := NewKkok()
+
+		f1 := &errorFilter{}
+		err := f1.Init("f1", c.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = k.AddStaticFilter(f1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f2 := &routeFilter{}
+		f2.Init("f2", nil)
+		f2.routes = []string{"r1"}
+		err = k.AddStaticFilter(f2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tr1 := &testTransport{}
+		k.AddRoute("r1", []Transport{tr1})
+		tr2 := &testTransport{}
+		k.AddRoute("r2", []Transport{tr2})
+
+		k.Handle(context.Background(), []*Alert{{Title: "title"}})
+		if len(tr1.alerts) != c.r1 {
+			t.Error(`len(tr1.alerts) != c.r1`, c.params)
+		}
+		if len(tr2.alerts) != c.r2 {
+			t.Error(`len(tr2.alerts) != c.r2`, c.params)
+		}
+		for _, a := range append(tr1.alerts, tr2.alerts...) {
+			if a.Title != "title" {
+				t.Error(`a.Title != "title"`, c.params)
+			}
+			if len(a.ID) == 0 {
+				t.Error(`no ID is assigned`, c.params)
+			}
+		}
+	}
+}
+
+type flushFilter struct {
+	discardFilter
+}
+
+func (f *flushFilter) Flush(now time.Time) []*Alert {
+	return []*Alert{{From: "flush"}}
+}
+
+func testFlush(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+
+	f1 := &routeFilter{}
+	f1.Init("f1", nil)
+	f1.routes = []string{"r1"}
+	err := k.AddStaticFilter(f1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f2 := &flushFilter{}
+	f2.Init("f2", nil)
+	err = k.AddStaticFilter(f2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f3 := &routeFilter{}
+	f3.Init("f3", nil)
+	f3.routes = []string{"r2"}
+	err = k.AddStaticFilter(f3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr1 := &testTransport{}
+	k.AddRoute("r1", []Transport{tr1})
+	tr2 := &testTransport{}
+	k.AddRoute("r2", []Transport{tr2})
+
+	k.flush(context.Background(), time.Now())
+	if len(tr1.alerts) != 0 {
+		t.Error(`len(tr1.alerts) != 0`)
+	}
+	if len(tr2.alerts) != 1 {
+		t.Fatal(`len(tr2.alerts) != 1`)
+	}
+	if tr2.alerts[0].From != "flush" {
+		t.Error(`tr2.alerts[0].From != "flush"`)
+	}
+}
+
+// countFilter counts alerts without locks.
+type countFilter struct {
+	BaseFilter
+
+	count int
+}
+
+func (f *countFilter) Params() PluginParams {
+	p := PluginParams{
+		Type:   "count",
+		Params: make(map[string]interface{}),
+	}
+	f.BaseFilter.AddParams(p.Params)
+	return p
+}
+
+func (f *countFilter) Process(alerts []*Alert) ([]*Alert, error) {
+	f.count += len(alerts)
+	// widen the window for concurrent calls.
+	time.Sleep(time.Millisecond)
+	return alerts, nil
+}
+
+// passFlushFilter passes alerts and generates an alert on Flush.
+type passFlushFilter struct {
+	flushFilter
+}
+
+func (f *passFlushFilter) Process(alerts []*Alert) ([]*Alert, error) {
+	return alerts, nil
+}
+
+func testFlushConcurrent(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+
+	f1 := &passFlushFilter{}
+	f1.Init("f1", nil)
+	err := k.AddStaticFilter(f1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f2 := &countFilter{}
+	f2.Init("f2", nil)
+	err = k.AddStaticFilter(f2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// f2 processes alerts from Handle and Flush concurrently.
+	const n = 100
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < n; i++ {
+			k.Handle(context.Background(), []*Alert{{From: "handle"}})
+		}
+		close(done)
+	}()
+	for i := 0; i < n; i++ {
+		k.flush(context.Background(), time.Now())
+	}
+	<-done
+
+	if f2.count != 2*n {
+		t.Error(`f2.count != 2*n`, f2.count)
+	}
+}
+
 func TestKkok(t *testing.T) {
 	t.Run("Filters", testFilters)
 	t.Run("Routes", testRoutes)
 	t.Run("HandleMultiFilters", testHandleMultiFilters)
 	t.Run("HandleMultiRoutes", testHandleMultiRoutes)
+	t.Run("HandleOnError", testHandleOnError)
+	t.Run("Flush", testFlush)
+	t.Run("FlushConcurrent", testFlushConcurrent)
 }
@@ -0,0 +1,98 @@
+package kkok
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "kkok"
+)
+
+var (
+	metricAlertsPosted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "alerts_posted_total",
+			Help:      "The number of alerts posted to the pool.",
+		},
+		[]string{"source"},
+	)
+
+	metricFilterReduced = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "filter_reduced_alerts_total",
+			Help:      "The number of alerts dropped or merged by filters.",
+		},
+		[]string{"filter"},
+	)
+
+	metricFilterErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "filter_errors_total",
+			Help:      "The number of errors returned by filters.",
+		},
+		[]string{"filter"},
+	)
+
+	metricDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deliveries_total",
+			Help:      "The number of successful deliveries including retries.",
+		},
+		[]string{"route", "transport"},
+	)
+
+	metricDeliveryFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "delivery_failures_total",
+			Help:      "The number of deliveries failed at the first attempt.",
+		},
+		[]string{"route", "transport"},
+	)
+
+	metricDeadLetters = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "dead_letters_total",
+			Help:      "The number of alerts that could not be delivered.",
+		},
+		[]string{"route", "transport"},
+	)
+
+	metricPoolSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "pool_alerts",
+			Help:      "The number of alerts in the pool.",
+		},
+	)
+
+	metricDispatchInterval = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "dispatch_interval_seconds",
+			Help:      "The current interval between dispatches.",
+		},
+	)
+
+	// metricsHandler serves metrics in Prometheus exposition format.
+	metricsHandler = promhttp.Handler()
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricAlertsPosted,
+		metricFilterReduced,
+		metricFilterErrors,
+		metricDeliveries,
+		metricDeliveryFailures,
+		metricDeadLetters,
+		metricPoolSize,
+		metricDispatchInterval,
+	)
+}
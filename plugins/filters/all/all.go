@@ -3,8 +3,10 @@ package all
 
 import (
 	// import all static plugins
+	_ "github.com/cybozu-go/kkok/plugins/filters/dedup"
 	_ "github.com/cybozu-go/kkok/plugins/filters/discard"
 	_ "github.com/cybozu-go/kkok/plugins/filters/edit"
+	_ "github.com/cybozu-go/kkok/plugins/filters/escalate"
 	_ "github.com/cybozu-go/kkok/plugins/filters/exec"
 	_ "github.com/cybozu-go/kkok/plugins/filters/freq"
 	_ "github.com/cybozu-go/kkok/plugins/filters/group"
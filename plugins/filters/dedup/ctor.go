@@ -0,0 +1,61 @@
+package dedup
+
+import (
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/pkg/errors"
+)
+
+func ctor(id string, params map[string]interface{}) (kkok.Filter, error) {
+	f := newFilter()
+
+	v, err := util.GetString("by", params)
+	switch {
+	case err == nil && len(v) > 0:
+		s, err := kkok.CompileJS(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "dedup: by")
+		}
+		f.by = s
+		f.origBy = v
+	case err == nil || util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "dedup: by")
+	}
+
+	windowSeconds, err := util.GetInt("window", params)
+	switch {
+	case err == nil:
+		if windowSeconds <= 0 {
+			return nil, errors.New("dedup: invalid window")
+		}
+		f.window = time.Duration(windowSeconds) * time.Second
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "dedup: window")
+	}
+
+	key, err := util.GetString("key", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "dedup: key")
+	}
+	f.key = key
+
+	summary, err := util.GetBool("summary", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "dedup: summary")
+	}
+	f.summary = summary
+
+	err = f.Init(id, params)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func init() {
+	kkok.RegisterFilter(filterType, ctor)
+}
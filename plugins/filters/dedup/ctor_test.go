@@ -0,0 +1,66 @@
+package dedup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ctorTest struct {
+	params map[string]interface{}
+	filter *filter
+}
+
+var ctorTestData = map[string]ctorTest{
+	"f1": {nil, &filter{}},
+	"f2": {map[string]interface{}{"by": "alert.Host"}, &filter{
+		origBy: "alert.Host",
+	}},
+	"f3": {map[string]interface{}{"by": "}"}, nil},
+	"f4": {map[string]interface{}{"window": 60}, &filter{
+		window: 60 * time.Second,
+	}},
+	"f5": {map[string]interface{}{"window": 0}, nil},
+	"f6": {map[string]interface{}{"key": "count"}, &filter{
+		key: "count",
+	}},
+	"f7": {map[string]interface{}{"summary": true}, &filter{
+		summary: true,
+	}},
+	"f8": {map[string]interface{}{"summary": "true"}, nil},
+}
+
+func testCtorOne(t *testing.T, id string, data ctorTest) {
+	t.Parallel()
+
+	f, err := ctor(id, data.params)
+	if err != nil {
+		if data.filter != nil {
+			// unexpected
+			t.Error(err)
+		}
+		return
+	}
+
+	ff, ok := f.(*filter)
+	if !ok {
+		t.Fatal("not a proper filter")
+	}
+	ff.by = nil
+	if !reflect.DeepEqual(ff, data.filter) {
+		t.Error(`!reflect.DeepEqual(ff, data.filter)`, ff, data.filter)
+	}
+}
+
+func TestCtor(t *testing.T) {
+	for id, data := range ctorTestData {
+		if data.filter != nil {
+			data.filter.Init(id, data.params)
+			if data.filter.window == 0 {
+				data.filter.window = defaultWindow
+			}
+			data.filter.windows = make(map[string]*window)
+		}
+		t.Run(id, func(t *testing.T) { testCtorOne(t, id, data) })
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Package dedup provides a filter to collapse duplicate alerts.
+
+Alerts sharing the same key are regarded as duplicates.  The first
+alert of a key passes the filter and opens a suppression window.
+Duplicates arriving before the window closes are removed.
+
+The key is the alert's Fingerprint and Status by default.  If "by" is given,
+the value of the JavaScript expression is used as the key.  Objects and
+arrays such as [alert.From, alert.Host] are compared by their JSON encoding.
+
+The number of collapsed alerts is stored in Info of the passed alert.
+The Info key is configurable but the default is the filter ID.
+Because the passed alert is sent immediately, only duplicates that
+arrive in the same dispatch are counted in it.
+
+If "summary" is true, the filter emits a summary alert when a window
+closes if some duplicates were suppressed after the first alert had
+been sent.  The summary alert is a copy of the first alert whose Info
+has the total number of alerts in the window.  Summary alerts are
+processed by the filters following this filter.
+
+In addition to the standard filter construction parameters, this
+plugin takes these parameters:
+
+    Name            Type           Default       Description
+    by              string         ""            JavaScript expression.
+    window          int            300           Seconds of the window.
+    key             string         ""            Key of Info.
+    summary         bool           false         If true, emit summaries.
+
+Example snippet for TOML configuration:
+
+    [[filter]]
+    type        = "dedup"
+    id          = "dedup_disk"
+    if          = "alert.From == 'disk monitor'"
+    by          = "alert.Host"
+    window      = 600
+    summary     = true
+
+This filter passes only the first alert from "disk monitor" for each
+Host in 10 minutes.  When 10 minutes elapsed, a summary alert is emitted
+if there were suppressed alerts.
+*/
+package dedup
@@ -0,0 +1,230 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/pkg/errors"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	filterType = "dedup"
+
+	defaultWindow = 300 * time.Second
+)
+
+// window keeps the state of a suppression window for a key.
+type window struct {
+	// alert is the first alert passed the filter.
+	alert *kkok.Alert
+
+	// until is the time when the window closes.
+	until time.Time
+
+	// count is the number of alerts including the first one.
+	count int
+
+	// suppressed is the number of alerts suppressed after the first
+	// one has been sent.
+	suppressed int
+}
+
+type filter struct {
+	kkok.BaseFilter
+
+	// constants
+	by      *otto.Script
+	origBy  string
+	window  time.Duration
+	key     string
+	summary bool
+
+	// states
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+func newFilter() *filter {
+	return &filter{
+		window:  defaultWindow,
+		windows: make(map[string]*window),
+	}
+}
+
+func (f *filter) Params() kkok.PluginParams {
+	m := map[string]interface{}{
+		"window":  int(math.Trunc(f.window.Seconds())),
+		"summary": f.summary,
+	}
+	if len(f.origBy) > 0 {
+		m["by"] = f.origBy
+	}
+	if len(f.key) > 0 {
+		m["key"] = f.key
+	}
+
+	f.BaseFilter.AddParams(m)
+
+	return kkok.PluginParams{
+		Type:   filterType,
+		Params: m,
+	}
+}
+
+func (f *filter) infoKey() string {
+	if len(f.key) > 0 {
+		return f.key
+	}
+	return f.ID()
+}
+
+// dedupKey returns the key of a.  If "by" evaluates to an object or
+// an array, it is encoded into JSON to be used as a map key.
+func (f *filter) dedupKey(a *kkok.Alert) (string, error) {
+	if f.by == nil {
+		// resolved alerts are not duplicates of firing alerts.
+		if len(a.Fingerprint) > 0 {
+			return a.Status + "\x00" + a.Fingerprint, nil
+		}
+		return a.Status + "\x00" + a.From + "\x00" + a.Host + "\x00" + a.Title, nil
+	}
+
+	v, err := f.BaseFilter.EvalAlert(a, f.by)
+	if err != nil {
+		return "", err
+	}
+	if !v.IsObject() {
+		return v.String(), nil
+	}
+
+	e, err := v.Export()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// summarize returns a summary alert of a closed window.
+// If no alerts were suppressed, this returns nil.
+func (f *filter) summarize(w *window, now time.Time) *kkok.Alert {
+	if !f.summary || w.suppressed == 0 {
+		return nil
+	}
+
+	a := w.alert.Clone()
+	a.ID = ""
+	a.Date = now.UTC()
+	a.Message = fmt.Sprintf("%d similar alerts were suppressed since %s.\n\n%s",
+		w.suppressed, w.alert.Date.UTC().Format(time.RFC3339), w.alert.Message)
+	a.SetInfo(f.infoKey(), w.count)
+	return a
+}
+
+// dedup returns true if a should pass the filter.
+// The caller must hold f.mu.
+func (f *filter) dedup(a *kkok.Alert, now time.Time, pending map[*window]bool) (bool, []*kkok.Alert, error) {
+	key, err := f.dedupKey(a)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var summaries []*kkok.Alert
+	w, ok := f.windows[key]
+	if ok && !now.Before(w.until) {
+		if s := f.summarize(w, now); s != nil {
+			summaries = append(summaries, s)
+		}
+		ok = false
+	}
+
+	if !ok {
+		w = &window{
+			alert: a,
+			until: now.Add(f.window),
+			count: 1,
+		}
+		f.windows[key] = w
+		pending[w] = true
+		a.SetInfo(f.infoKey(), 1)
+		return true, summaries, nil
+	}
+
+	w.count++
+	if pending[w] {
+		// the first alert is not sent yet.
+		w.alert.SetInfo(f.infoKey(), w.count)
+	} else {
+		w.suppressed++
+	}
+	return false, summaries, nil
+}
+
+func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	pending := make(map[*window]bool)
+
+	if f.BaseFilter.All() {
+		ok, err := f.BaseFilter.IfAll(alerts)
+		if err != nil {
+			return nil, errors.Wrap(err, "dedup:"+f.ID())
+		}
+		if !ok {
+			return alerts, nil
+		}
+	}
+
+	filtered := make([]*kkok.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if !f.BaseFilter.All() {
+			ok, err := f.BaseFilter.If(a)
+			if err != nil {
+				return nil, errors.Wrap(err, "dedup:"+f.ID())
+			}
+			if !ok {
+				filtered = append(filtered, a)
+				continue
+			}
+		}
+
+		pass, summaries, err := f.dedup(a, now, pending)
+		if err != nil {
+			return nil, errors.Wrap(err, "dedup:"+f.ID())
+		}
+		filtered = append(filtered, summaries...)
+		if pass {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered, nil
+}
+
+// Flush implements kkok.Flusher.
+// It returns summaries of closed windows.
+func (f *filter) Flush(now time.Time) []*kkok.Alert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var summaries []*kkok.Alert
+	for key, w := range f.windows {
+		if now.Before(w.until) {
+			continue
+		}
+		if s := f.summarize(w, now); s != nil {
+			summaries = append(summaries, s)
+		}
+		delete(f.windows, key)
+	}
+	return summaries
+}
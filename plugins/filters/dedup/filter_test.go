@@ -0,0 +1,175 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+func testFilterDedup(t *testing.T) {
+	t.Parallel()
+
+	f := newFilter()
+	err := f.Init("f", map[string]interface{}{
+		"if": "alert.From != 'other'",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := []*kkok.Alert{
+		{From: "from1", Host: "host1", Title: "title1"},
+		{From: "from1", Host: "host1", Title: "title1"},
+		{From: "from1", Host: "host2", Title: "title1"},
+		{From: "other", Host: "host1", Title: "title1"},
+		{From: "other", Host: "host1", Title: "title1"},
+		{From: "from1", Host: "host1", Title: "title1"},
+	}
+	alerts, err = f.Process(alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 4 {
+		t.Fatal(`len(alerts) != 4`)
+	}
+	if alerts[0].Info["f"] != 3 {
+		t.Error(`alerts[0].Info["f"] != 3`, alerts[0].Info)
+	}
+	if alerts[1].Info["f"] != 1 {
+		t.Error(`alerts[1].Info["f"] != 1`)
+	}
+	if alerts[2].Info != nil {
+		t.Error(`alerts[2].Info != nil`)
+	}
+
+	// suppressed in the window
+	alerts, err = f.Process([]*kkok.Alert{
+		{From: "from1", Host: "host1", Title: "title1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Error(`len(alerts) != 0`)
+	}
+}
+
+func testFilterFingerprint(t *testing.T) {
+	t.Parallel()
+
+	f := newFilter()
+	err := f.Init("f", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := f.Process([]*kkok.Alert{
+		{Fingerprint: "fp1", Title: "title1"},
+		{Fingerprint: "fp1", Title: "title2"},
+		{Fingerprint: "fp2", Title: "title1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Error(`len(alerts) != 2`)
+	}
+}
+
+func testFilterSummary(t *testing.T) {
+	t.Parallel()
+
+	ff, err := ctor("f", map[string]interface{}{
+		"by":      "alert.Host",
+		"window":  10,
+		"key":     "count",
+		"summary": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := ff.(*filter)
+
+	alerts, err := f.Process([]*kkok.Alert{{ID: "a1", Host: "host1", Title: "title1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+
+	alerts, err = f.Process([]*kkok.Alert{
+		{Host: "host1", Title: "title2"},
+		{Host: "host1", Title: "title3"},
+		{Host: "host2", Title: "title1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+
+	if len(f.Flush(time.Now())) != 0 {
+		t.Error(`len(f.Flush(time.Now())) != 0`)
+	}
+
+	summaries := f.Flush(time.Now().Add(time.Minute))
+	if len(summaries) != 1 {
+		t.Fatal(`len(summaries) != 1`)
+	}
+	s := summaries[0]
+	if s.Title != "title1" {
+		t.Error(`s.Title != "title1"`)
+	}
+	if s.Host != "host1" {
+		t.Error(`s.Host != "host1"`)
+	}
+	if len(s.ID) != 0 {
+		t.Error(`len(s.ID) != 0`)
+	}
+	if s.Info["count"] != 3 {
+		t.Error(`s.Info["count"] != 3`, s.Info)
+	}
+	if len(f.windows) != 0 {
+		t.Error(`len(f.windows) != 0`)
+	}
+}
+
+func testFilterComposite(t *testing.T) {
+	t.Parallel()
+
+	for _, by := range []string{
+		"[alert.From, alert.Host]",
+		"({from: alert.From, host: alert.Host})",
+	} {
+		ff, err := ctor("f", map[string]interface{}{
+			"by": by,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := ff.(*filter)
+
+		alerts, err := f.Process([]*kkok.Alert{
+			{From: "from1", Host: "host1", Title: "title1"},
+			{From: "from1", Host: "host1", Title: "title2"},
+			{From: "from1", Host: "host2", Title: "title3"},
+			{From: "from2", Host: "host1", Title: "title4"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 3 {
+			t.Error(`len(alerts) != 3`, by, len(alerts))
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Run("Dedup", testFilterDedup)
+	t.Run("Fingerprint", testFilterFingerprint)
+	t.Run("Summary", testFilterSummary)
+	t.Run("Composite", testFilterComposite)
+}
@@ -17,6 +17,12 @@ func (f *filter) Params() kkok.PluginParams {
 	}
 }
 
+// ReadOnly implements kkok.ReadOnlyFilter.
+// Process only drops alerts.
+func (f *filter) ReadOnly() bool {
+	return true
+}
+
 func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 	if f.BaseFilter.All() {
 		ok, err := f.BaseFilter.IfAll(alerts)
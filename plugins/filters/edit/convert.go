@@ -28,6 +28,18 @@ func fromObject(obj *otto.Object) (*kkok.Alert, error) {
 	a := &kkok.Alert{}
 	for k, v := range i.(map[string]interface{}) {
 		switch k {
+		case "ID":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("ID is not a string")
+			}
+			a.ID = s
+		case "Fingerprint":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("Fingerprint is not a string")
+			}
+			a.Fingerprint = s
 		case "From":
 			s, ok := v.(string)
 			if !ok {
@@ -115,6 +127,24 @@ func fromObject(obj *otto.Object) (*kkok.Alert, error) {
 				a.Sub = sub
 			}
 			// ignore otherwise
+		case "Status":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("Status is not a string")
+			}
+			a.Status = s
+		case "Incident":
+			inc, ok := v.(*kkok.Incident)
+			if ok {
+				a.Incident = inc
+			}
+			// ignore otherwise
+		case "Escalation":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("Escalation is not a string")
+			}
+			a.Escalation = s
 		}
 	}
 
@@ -145,6 +175,8 @@ for( i = 0; i < alert.Sub.length; i++) {
     sub.push(alert.Sub[i]);
 }
 ({
+    "ID": alert.ID,
+    "Fingerprint": alert.Fingerprint,
     "From": alert.From,
     "Date": new Date(alert.Date.UTC().Format("2006-01-02T15:04:05.000Z07:00")),
     "Host": alert.Host,
@@ -154,6 +186,9 @@ for( i = 0; i < alert.Sub.length; i++) {
     "Info": info,
     "Stats": stats,
     "Sub": sub,
+    "Escalation": alert.Escalation,
+    "Status": alert.Status,
+    "Incident": alert.Incident,
 })`)
 	if err != nil {
 		panic(err)
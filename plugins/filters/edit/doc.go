@@ -8,16 +8,21 @@ a pure JavaScript object to grant users to edit it freely.
 Specifically, an alert in this filter is a JavaScript object with
 these properties:
 
-    Name     Type           Reference
-    From     string
-    Date     Date           https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Date
-    Host     string
-    Title    string
-    Message  string
-    Routes   Array          https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Array
-    Info     Object         https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object
-    Stats    Object         ditto
-    Sub      []*kkok.Alert  (Go slice, only for reference)
+    Name        Type            Reference
+    ID          string
+    Fingerprint string
+    From        string
+    Date        Date            https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Date
+    Host        string
+    Title       string
+    Message     string
+    Routes      Array           https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Array
+    Info        Object          https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object
+    Stats       Object          ditto
+    Sub         []*kkok.Alert   (Go slice, only for reference)
+    Escalation  string
+    Status      string
+    Incident    *kkok.Incident  (Go pointer, only for reference)
 
 This filter does not (yet) support "all" construction parameter.
 Use "exec" filter in case this filter is too limited.
@@ -46,7 +46,14 @@ func (f *filter) edit(a *kkok.Alert) (*kkok.Alert, error) {
 	return fromObject(obj)
 }
 
+// ReadOnly implements kkok.ReadOnlyFilter.
+// Process replaces alerts with edited copies.
+func (f *filter) ReadOnly() bool {
+	return true
+}
+
 func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
+	edited := make([]*kkok.Alert, len(alerts))
 	for i, a := range alerts {
 		ok, err := f.BaseFilter.If(a)
 		if err != nil {
@@ -54,6 +61,7 @@ func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 		}
 
 		if !ok {
+			edited[i] = a
 			continue
 		}
 
@@ -61,8 +69,8 @@ func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "edit:"+f.ID())
 		}
-		alerts[i] = aa
+		edited[i] = aa
 	}
 
-	return alerts, nil
+	return edited, nil
 }
@@ -0,0 +1,30 @@
+package escalate
+
+import (
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/pkg/errors"
+)
+
+func ctor(id string, params map[string]interface{}) (kkok.Filter, error) {
+	policy, err := util.GetString("policy", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "escalate: policy")
+	}
+	if len(policy) == 0 {
+		return nil, errors.New("escalate: empty policy")
+	}
+
+	f := &filter{
+		policy: policy,
+	}
+	err = f.Init(id, params)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func init() {
+	kkok.RegisterFilter(filterType, ctor)
+}
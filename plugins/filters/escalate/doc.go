@@ -0,0 +1,38 @@
+/*
+Package escalate provides a filter to escalate alerts until they
+are acknowledged.
+
+An escalation policy is a list of steps defined in the configuration
+file.  Each step has a route ID and seconds to wait for acknowledgement
+before proceeding to the next step.  Alerts escalated by this filter
+are delivered to the route of the first step immediately, then to
+the routes of the following steps unless acknowledged by REST API.
+The first step is skipped if its route is already in the alert routes.
+
+In addition to the standard filter construction parameters, this
+plugin takes these parameters:
+
+    Name            Type           Default       Description
+    policy          string                       Escalation policy ID.  Required.
+
+Example snippet for TOML configuration:
+
+    [[escalation.oncall]]
+    route = "primary"
+    wait  = 300
+
+    [[escalation.oncall]]
+    route = "secondary"
+    wait  = 600
+
+    [[filter]]
+    type        = "escalate"
+    id          = "raid"
+    if          = "alert.From == 'RAID monitor'"
+    policy      = "oncall"
+
+This filter escalates alerts from "RAID monitor".  They are delivered
+to "primary" route first, then to "secondary" route if not acknowledged
+within 300 seconds.
+*/
+package escalate
@@ -0,0 +1,50 @@
+package escalate
+
+import "github.com/cybozu-go/kkok"
+
+const filterType = "escalate"
+
+type filter struct {
+	kkok.BaseFilter
+
+	policy string
+}
+
+func (f *filter) Params() kkok.PluginParams {
+	m := map[string]interface{}{
+		"policy": f.policy,
+	}
+	f.BaseFilter.AddParams(m)
+	return kkok.PluginParams{
+		Type:   filterType,
+		Params: m,
+	}
+}
+
+func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
+	if f.BaseFilter.All() {
+		ok, err := f.BaseFilter.IfAll(alerts)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			for _, a := range alerts {
+				a.Escalation = f.policy
+			}
+		}
+		return alerts, nil
+	}
+
+	for _, a := range alerts {
+		ok, err := f.BaseFilter.If(a)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			a.Escalation = f.policy
+		}
+	}
+	return alerts, nil
+}
@@ -0,0 +1,108 @@
+package escalate
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/kkok"
+)
+
+func testFilterAll(t *testing.T) {
+	t.Parallel()
+
+	f := &filter{policy: "oncall"}
+	err := f.Init("f", map[string]interface{}{
+		"all": true,
+		"if":  "alerts.length > 2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := []*kkok.Alert{{}, {}}
+	alerts, err = f.Process(alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range alerts {
+		if len(a.Escalation) != 0 {
+			t.Error(`len(a.Escalation) != 0`)
+		}
+	}
+
+	alerts = []*kkok.Alert{{}, {}, {}}
+	alerts, err = f.Process(alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range alerts {
+		if a.Escalation != "oncall" {
+			t.Error(`a.Escalation != "oncall"`)
+		}
+	}
+}
+
+func testFilterOne(t *testing.T) {
+	t.Parallel()
+
+	f := &filter{policy: "oncall"}
+	err := f.Init("f", map[string]interface{}{
+		"if": "alert.From == 'from1'",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := []*kkok.Alert{
+		{From: "from1"},
+		{From: "from2"},
+	}
+
+	alerts, err = f.Process(alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].Escalation != "oncall" {
+		t.Error(`alerts[0].Escalation != "oncall"`)
+	}
+	if len(alerts[1].Escalation) != 0 {
+		t.Error(`len(alerts[1].Escalation) != 0`)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Run("All", testFilterAll)
+	t.Run("One", testFilterOne)
+}
+
+func TestCtor(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor("test", nil)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	_, err = ctor("test", map[string]interface{}{"policy": ""})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	f, err := ctor("test", map[string]interface{}{"policy": "oncall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.ID() != "test" {
+		t.Error(`f.ID() != "test"`)
+	}
+
+	pp := f.Params()
+	if pp.Type != filterType {
+		t.Error(`pp.Type != filterType`)
+	}
+	if pp.Params["policy"] != "oncall" {
+		t.Error(`pp.Params["policy"] != "oncall"`)
+	}
+}
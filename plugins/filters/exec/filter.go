@@ -45,8 +45,7 @@ func (f *filter) Params() kkok.PluginParams {
 	}
 }
 
-func (f *filter) exec(j []byte) ([]byte, error) {
-	ctx := context.Background()
+func (f *filter) exec(ctx context.Context, j []byte) ([]byte, error) {
 	if f.timeout != 0 {
 		ctx2, cancel := context.WithTimeout(ctx, f.timeout)
 		ctx = ctx2
@@ -60,7 +59,17 @@ func (f *filter) exec(j []byte) ([]byte, error) {
 	return command.Output()
 }
 
+// ReadOnly implements kkok.ReadOnlyFilter.
+// Process replaces alerts with the command output.
+func (f *filter) ReadOnly() bool {
+	return true
+}
+
 func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
+	return f.ProcessContext(context.Background(), alerts)
+}
+
+func (f *filter) ProcessContext(ctx context.Context, alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 	var newAlerts []*kkok.Alert
 
 	if f.BaseFilter.All() {
@@ -78,7 +87,7 @@ func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 			return nil, errors.Wrap(err, "json.Marshal(alerts)")
 		}
 
-		jj, err := f.exec(j)
+		jj, err := f.exec(ctx, j)
 		if err != nil {
 			return nil, errors.Wrap(err, "exec:"+f.ID())
 		}
@@ -106,7 +115,7 @@ func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 			return nil, errors.Wrap(err, "json.Marshal(a)")
 		}
 
-		jj, err := f.exec(j)
+		jj, err := f.exec(ctx, j)
 		if err != nil {
 			return nil, errors.Wrap(err, "exec:"+f.ID())
 		}
@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"context"
 	"os/exec"
 	"reflect"
 	"testing"
@@ -70,6 +71,7 @@ func testExec(t *testing.T) {
 	t.Run("Success", testExecSuccess)
 	t.Run("Error", testExecError)
 	t.Run("Timeout", testExecTimeout)
+	t.Run("Cancel", testExecCancel)
 }
 
 func testExecSuccess(t *testing.T) {
@@ -82,7 +84,7 @@ func testExecSuccess(t *testing.T) {
 	f := newFilter()
 	f.command = []string{"cat"}
 
-	j, err := f.exec([]byte("abc"))
+	j, err := f.exec(context.Background(), []byte("abc"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,7 +106,7 @@ echo foo 1>&2
 exit 3
 `}
 
-	_, err := f.exec([]byte("abc"))
+	_, err := f.exec(context.Background(), []byte("abc"))
 	if err == nil {
 		t.Error(`err == nil`)
 	}
@@ -121,13 +123,31 @@ func testExecTimeout(t *testing.T) {
 	f.timeout = 10 * time.Millisecond
 	f.command = []string{"sleep", "1"}
 
-	_, err := f.exec([]byte("abc"))
+	_, err := f.exec(context.Background(), []byte("abc"))
 	if err == nil {
 		t.Error(`err == nil`)
 	}
 	t.Log(err)
 }
 
+func testExecCancel(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not found")
+	}
+
+	f := newFilter()
+	f.command = []string{"sleep", "1"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := f.ProcessContext(ctx, testAlertsData)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
 func testProcess(t *testing.T) {
 	t.Run("One", testProcessOne)
 	t.Run("All", testProcessAll)
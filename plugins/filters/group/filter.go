@@ -108,6 +108,12 @@ func (f *filter) mergeAlerts(alerts []*kkok.Alert) *kkok.Alert {
 	return newAlert
 }
 
+// ReadOnly implements kkok.ReadOnlyFilter.
+// Process merges alerts into new ones.
+func (f *filter) ReadOnly() bool {
+	return true
+}
+
 func (f *filter) Process(alerts []*kkok.Alert) ([]*kkok.Alert, error) {
 	var newAlerts []*kkok.Alert
 	var groups map[interface{}][]*kkok.Alert
@@ -3,5 +3,10 @@ package all
 
 import (
 	// import all static plugins
+	_ "github.com/cybozu-go/kkok/plugins/sources/exec"
+	_ "github.com/cybozu-go/kkok/plugins/sources/imap"
 	_ "github.com/cybozu-go/kkok/plugins/sources/maildir"
+	_ "github.com/cybozu-go/kkok/plugins/sources/syslog"
+	_ "github.com/cybozu-go/kkok/plugins/sources/tail"
+	_ "github.com/cybozu-go/kkok/plugins/sources/webhook"
 )
@@ -0,0 +1,55 @@
+/*
+Package exec runs a command periodically to generate alerts.
+
+The command is run at startup and then at regular intervals.
+Alerts are generated in one of these modes:
+
+"status" mode works with Nagios-plugin style commands.  If the command
+exits with non-zero status, an alert is generated.  The first line of
+stdout becomes "Title", and the rest becomes "Message".  If stdout is
+empty, "Title" will be "COMMAND exited with status N".
+
+"json" mode reads alerts from stdout.  The output may be a JSON object,
+a JSON array of objects, or a sequence of JSON objects.  Each object
+is decoded as kkok.Alert.  If "From", "Host", or "Date" is missing,
+it is complemented as described below.  Invalid alerts are ignored.
+If the command exits with non-zero status, no alerts are generated.
+
+In both modes, commands that cannot be started or that timed out
+are logged and generate no alerts.
+
+Construction parameters:
+
+    Name        Type               Default       Description
+    command     []string                         Command and arguments.  Required.
+    mode        string             status        "status" or "json".
+    from        string             command name  "From" field of alerts.
+    interval    int                60            Interval to run the command (seconds).
+    timeout     int                30            Seconds before killing the command.
+                                                 If 0, the command will not be killed.
+
+Example snippet for TOML configuration:
+
+    [[source]]
+    type     = "exec"
+    command  = ["/usr/lib/nagios/plugins/check_disk", "-w", "10%", "-c", "5%", "-p", "/"]
+    interval = 300
+
+In "status" mode, alerts are generated as follows:
+
+    Field       Value
+    From        "from" parameter.
+    Date        The current time.
+    Host        The host name of kkok.
+    Title       The first line of stdout.
+    Message     The rest of stdout.
+    Info        See below.
+
+Info has these members:
+
+    Name        Type               Description
+    exit_status int                The exit status of the command.
+    state       string             "warning" for 1, "unknown" for 3,
+                                   "critical" for 2 and others.
+*/
+package exec
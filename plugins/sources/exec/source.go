@@ -0,0 +1,295 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
+)
+
+const (
+	modeStatus = "status"
+	modeJSON   = "json"
+
+	defaultInterval = 60 // seconds
+	defaultTimeout  = 30 // seconds
+
+	maxTitleLength = 250
+)
+
+var stateNames = map[int]string{
+	1: "warning",
+	2: "critical",
+	3: "unknown",
+}
+
+// source implements kkok.Source.
+type source struct {
+	command  string
+	args     []string
+	mode     string
+	from     string
+	host     string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// Run runs the command once at startup, then at every interval.
+func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	for {
+		for _, a := range s.check(ctx) {
+			post(a)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// check runs the command once and returns generated alerts.
+func (s *source) check(ctx context.Context) []*kkok.Alert {
+	if s.timeout != 0 {
+		ctx2, cancel := context.WithTimeout(ctx, s.timeout)
+		ctx = ctx2
+		defer cancel()
+	}
+
+	stdout := new(bytes.Buffer)
+	command := well.CommandContext(ctx, s.command, s.args...)
+	command.Stdout = stdout
+	err := command.Run()
+
+	exitStatus := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok || ctx.Err() != nil {
+			log.Error("[exec] failed to run", map[string]interface{}{
+				log.FnError: err.Error(),
+				"command":   s.command,
+			})
+			return nil
+		}
+		exitStatus = exitErr.ExitCode()
+	}
+
+	if s.mode == modeJSON {
+		if exitStatus != 0 {
+			log.Error("[exec] command failed", map[string]interface{}{
+				"command":     s.command,
+				"exit_status": exitStatus,
+			})
+			return nil
+		}
+		alerts, err := s.decode(stdout)
+		if err != nil {
+			log.Error("[exec] invalid output", map[string]interface{}{
+				log.FnError: err.Error(),
+				"command":   s.command,
+			})
+			return nil
+		}
+		return alerts
+	}
+
+	if exitStatus == 0 {
+		return nil
+	}
+	return []*kkok.Alert{s.statusAlert(exitStatus, stdout.String())}
+}
+
+// statusAlert generates an alert from the exit status and the output
+// of a Nagios-plugin style command.
+func (s *source) statusAlert(exitStatus int, output string) *kkok.Alert {
+	output = strings.TrimSpace(output)
+	title := output
+	message := ""
+	if idx := strings.IndexByte(output, '\n'); idx != -1 {
+		title = strings.TrimSpace(output[:idx])
+		message = strings.TrimSpace(output[idx+1:])
+	}
+	if len(title) == 0 {
+		title = fmt.Sprintf("%s exited with status %d", filepath.Base(s.command), exitStatus)
+	}
+	if len(title) > maxTitleLength {
+		title = title[:maxTitleLength]
+		for !utf8.ValidString(title) {
+			title = title[:len(title)-1]
+		}
+		message = output
+	}
+
+	state, ok := stateNames[exitStatus]
+	if !ok {
+		state = "critical"
+	}
+
+	return &kkok.Alert{
+		From:    s.from,
+		Date:    time.Now().UTC(),
+		Host:    s.host,
+		Title:   title,
+		Message: message,
+		Info: map[string]interface{}{
+			"exit_status": exitStatus,
+			"state":       state,
+		},
+	}
+}
+
+// decode reads a JSON object, a JSON array of objects, or a stream
+// of JSON objects of alerts.  Missing From, Date, and Host are
+// complemented.  Fields assigned by kkok such as ID are cleared.
+// Invalid alerts are ignored.
+func (s *source) decode(r io.Reader) ([]*kkok.Alert, error) {
+	var alerts []*kkok.Alert
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &alerts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		for {
+			a := new(kkok.Alert)
+			err = dec.Decode(a)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			alerts = append(alerts, a)
+		}
+	}
+
+	now := time.Now().UTC()
+	valid := make([]*kkok.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if a == nil {
+			continue
+		}
+		if len(a.From) == 0 {
+			a.From = s.from
+		}
+		if len(a.Host) == 0 {
+			a.Host = s.host
+		}
+		if a.Date.IsZero() {
+			a.Date = now
+		}
+
+		// these are assigned by kkok.
+		a.ID = ""
+		a.Fingerprint = ""
+		a.Routes = nil
+		a.Sub = nil
+		a.Escalation = ""
+		a.Incident = nil
+
+		err := a.Validate()
+		if err != nil {
+			log.Warn("[exec] invalid alert", map[string]interface{}{
+				log.FnError: err.Error(),
+				"command":   s.command,
+			})
+			continue
+		}
+		valid = append(valid, a)
+	}
+	return valid, nil
+}
+
+func ctor(params map[string]interface{}) (kkok.Source, error) {
+	cl, err := util.GetStringSlice("command", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "exec: command")
+	}
+	if len(cl) == 0 {
+		return nil, errors.New("exec: empty command")
+	}
+
+	s := &source{
+		command:  cl[0],
+		args:     cl[1:],
+		mode:     modeStatus,
+		from:     filepath.Base(cl[0]),
+		interval: defaultInterval * time.Second,
+		timeout:  defaultTimeout * time.Second,
+	}
+
+	switch mode, err := util.GetString("mode", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "exec: mode")
+	default:
+		if mode != modeStatus && mode != modeJSON {
+			return nil, errors.New("exec: invalid mode: " + mode)
+		}
+		s.mode = mode
+	}
+
+	switch from, err := util.GetString("from", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "exec: from")
+	default:
+		s.from = from
+	}
+
+	switch i, err := util.GetInt("interval", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "exec: interval")
+	default:
+		if i <= 0 {
+			return nil, errors.New("exec: invalid interval")
+		}
+		s.interval = time.Duration(i) * time.Second
+	}
+
+	switch ts, err := util.GetInt("timeout", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "exec: timeout")
+	default:
+		if ts < 0 {
+			return nil, errors.New("exec: invalid timeout")
+		}
+		s.timeout = time.Duration(ts) * time.Second
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "exec: hostname")
+	}
+	s.host = host
+
+	return s, nil
+}
+
+func init() {
+	kkok.RegisterSource("exec", ctor)
+}
@@ -0,0 +1,230 @@
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+func testSourceParams(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{})
+	if err == nil {
+		t.Error(`command is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"command": []interface{}{},
+	})
+	if err == nil {
+		t.Error(`empty command is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"command": []interface{}{"true"},
+		"mode":    "xml",
+	})
+	if err == nil {
+		t.Error(`invalid mode is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"command":  []interface{}{"true"},
+		"interval": 0,
+	})
+	if err == nil {
+		t.Error(`invalid interval is accepted`)
+	}
+
+	src, err := ctor(map[string]interface{}{
+		"command": []interface{}{"/usr/bin/check_foo", "-w", "10"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+	if s.mode != modeStatus {
+		t.Error(`s.mode != modeStatus`)
+	}
+	if s.from != "check_foo" {
+		t.Error(`s.from != "check_foo"`)
+	}
+	if len(s.args) != 2 {
+		t.Error(`len(s.args) != 2`)
+	}
+	if s.interval != 60*time.Second {
+		t.Error(`s.interval != 60*time.Second`)
+	}
+}
+
+func testSourceStatus(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", "echo OK; exit 0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := src.(*source).check(context.Background()); len(alerts) != 0 {
+		t.Error(`alert for successful command`)
+	}
+
+	src, err = ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", "echo DISK CRITICAL; echo detail1; echo detail2; exit 2"},
+		"from":    "disk",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts := src.(*source).check(context.Background())
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	a := alerts[0]
+	if a.From != "disk" {
+		t.Error(`a.From != "disk"`)
+	}
+	if a.Title != "DISK CRITICAL" {
+		t.Error(`a.Title != "DISK CRITICAL"`)
+	}
+	if a.Message != "detail1\ndetail2" {
+		t.Error(`a.Message != "detail1\ndetail2"`)
+	}
+	if a.Info["exit_status"] != 2 {
+		t.Error(`a.Info["exit_status"] != 2`)
+	}
+	if a.Info["state"] != "critical" {
+		t.Error(`a.Info["state"] != "critical"`)
+	}
+	if err := a.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	src, err = ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", "exit 1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts = src.(*source).check(context.Background())
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	if alerts[0].Title != "sh exited with status 1" {
+		t.Error(`alerts[0].Title != "sh exited with status 1"`)
+	}
+	if alerts[0].Info["state"] != "warning" {
+		t.Error(`alerts[0].Info["state"] != "warning"`)
+	}
+}
+
+func testSourceJSON(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", `echo '{"Title": "t1"}'; echo '{"From": "f2", "Title": "t2", "Host": "h2", "ID": "id2", "Fingerprint": "fp2", "Escalation": "e2"}'; echo '{"From": "f3"}'`},
+		"mode":    "json",
+		"from":    "checker",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+	alerts := s.check(context.Background())
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].From != "checker" {
+		t.Error(`alerts[0].From != "checker"`)
+	}
+	if alerts[0].Host != s.host {
+		t.Error(`alerts[0].Host != s.host`)
+	}
+	if alerts[0].Date.IsZero() {
+		t.Error(`alerts[0].Date.IsZero()`)
+	}
+	if alerts[1].From != "f2" || alerts[1].Host != "h2" {
+		t.Error(`alerts[1].From != "f2" || alerts[1].Host != "h2"`)
+	}
+	if len(alerts[1].ID) != 0 || len(alerts[1].Fingerprint) != 0 || len(alerts[1].Escalation) != 0 {
+		t.Error(`fields assigned by kkok are not cleared`)
+	}
+
+	src, err = ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", `echo '[{"Title": "t1"}, {"Title": "t2"}]'`},
+		"mode":    "json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := src.(*source).check(context.Background()); len(alerts) != 2 {
+		t.Error(`len(alerts) != 2`)
+	}
+
+	src, err = ctor(map[string]interface{}{
+		"command": []interface{}{"sh", "-c", `echo '{"Title": "t1"}'; exit 1`},
+		"mode":    "json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := src.(*source).check(context.Background()); len(alerts) != 0 {
+		t.Error(`alerts from failed command`)
+	}
+}
+
+func testSourceTimeout(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"command": []interface{}{"sleep", "10"},
+		"timeout": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := src.(*source).check(context.Background()); len(alerts) != 0 {
+		t.Error(`alerts from timed out command`)
+	}
+}
+
+func testSourceRun(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"command":  []interface{}{"sh", "-c", "exit 2"},
+		"interval": 3600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan *kkok.Alert, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Run(ctx, func(a *kkok.Alert) {
+		ch <- a
+	})
+
+	// the command is run at startup without waiting the interval.
+	select {
+	case a := <-ch:
+		if a.Info["state"] != "critical" {
+			t.Error(`a.Info["state"] != "critical"`)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error(`no alert at startup`)
+	}
+}
+
+func TestSource(t *testing.T) {
+	t.Run("Params", testSourceParams)
+	t.Run("Status", testSourceStatus)
+	t.Run("JSON", testSourceJSON)
+	t.Run("Timeout", testSourceTimeout)
+	t.Run("Run", testSourceRun)
+}
@@ -0,0 +1,265 @@
+package imap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxLiteralSize = 10 * 1024 * 1024 // 10 MiB
+)
+
+var (
+	reLiteral = regexp.MustCompile(`\{(\d+)\}$`)
+	reUID     = regexp.MustCompile(`\bUID (\d+)`)
+)
+
+// response is an untagged or tagged response.
+// Literals are removed from line and stored in literals.
+type response struct {
+	line     string
+	literals [][]byte
+}
+
+// client is a minimal IMAP4rev1 client (RFC3501).
+//
+// The connection is closed when the context passed to dial is done
+// so that a stalled server does not block the client.
+type client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	tag     int
+	timeout time.Duration
+	ctx     context.Context
+	done    chan struct{}
+}
+
+func dial(ctx context.Context, address string, useTLS bool, tlsConfig *tls.Config, timeout time.Duration) (*client, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		conn:    conn,
+		timeout: timeout,
+		ctx:     ctx,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-c.done:
+		}
+	}()
+
+	c.extendDeadline()
+	if useTLS {
+		tc := tls.Client(conn, tlsConfig)
+		err = tc.Handshake()
+		if err != nil {
+			c.close()
+			return nil, c.wrap(err)
+		}
+		c.conn = tc
+	}
+	c.r = bufio.NewReader(c.conn)
+
+	greeting, err := c.readResponse()
+	if err != nil {
+		c.close()
+		return nil, c.wrap(err)
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") {
+		c.close()
+		return nil, errors.New("unexpected greeting: " + greeting.line)
+	}
+	return c, nil
+}
+
+// extendDeadline sets the deadline for the next command.
+// The deadline does not exceed that of the context.
+func (c *client) extendDeadline() {
+	deadline, ok := c.ctx.Deadline()
+	if c.timeout > 0 {
+		t := time.Now().Add(c.timeout)
+		if !ok || t.Before(deadline) {
+			deadline, ok = t, true
+		}
+	}
+	if ok {
+		c.conn.SetDeadline(deadline)
+	}
+}
+
+// wrap returns the context error instead of err if the context is done.
+func (c *client) wrap(err error) error {
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	return err
+}
+
+func (c *client) close() error {
+	close(c.done)
+	return c.conn.Close()
+}
+
+func (c *client) readResponse() (*response, error) {
+	res := new(response)
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		m := reLiteral.FindStringSubmatch(line)
+		if m == nil {
+			res.line += line
+			return res, nil
+		}
+
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n > maxLiteralSize {
+			return nil, errors.New("too large literal: " + m[1])
+		}
+		lit := make([]byte, n)
+		_, err = io.ReadFull(c.r, lit)
+		if err != nil {
+			return nil, err
+		}
+		res.line += line[:len(line)-len(m[0])]
+		res.literals = append(res.literals, lit)
+	}
+}
+
+// execute sends a command and returns untagged responses.
+// An error is returned unless the command completes with OK.
+func (c *client) execute(command string) ([]*response, error) {
+	c.extendDeadline()
+	c.tag++
+	tag := "k" + strconv.Itoa(c.tag)
+	_, err := io.WriteString(c.conn, tag+" "+command+"\r\n")
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+
+	var untagged []*response
+	for {
+		res, err := c.readResponse()
+		if err != nil {
+			return nil, c.wrap(err)
+		}
+		if !strings.HasPrefix(res.line, tag+" ") {
+			untagged = append(untagged, res)
+			continue
+		}
+
+		status := res.line[len(tag)+1:]
+		if !strings.HasPrefix(status, "OK") {
+			// do not include arguments that may contain passwords.
+			f := strings.Fields(command)
+			name := f[0]
+			if name == "UID" && len(f) > 1 {
+				name += " " + f[1]
+			}
+			return nil, errors.New(name + ": " + status)
+		}
+		return untagged, nil
+	}
+}
+
+// quote returns s as an IMAP quoted string.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+func (c *client) login(user, password string) error {
+	_, err := c.execute("LOGIN " + quote(user) + " " + quote(password))
+	return err
+}
+
+func (c *client) selectMailbox(mailbox string) error {
+	_, err := c.execute("SELECT " + quote(mailbox))
+	return err
+}
+
+// searchUnseen returns UIDs of unseen messages.
+func (c *client) searchUnseen() ([]uint32, error) {
+	untagged, err := c.execute("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+	for _, res := range untagged {
+		if !strings.HasPrefix(res.line, "* SEARCH") {
+			continue
+		}
+		for _, f := range strings.Fields(res.line[8:]) {
+			uid, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, errors.New("invalid SEARCH response: " + res.line)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the whole message of uid without setting \Seen flag.
+func (c *client) fetch(uid uint32) ([]byte, error) {
+	suid := strconv.FormatUint(uint64(uid), 10)
+	untagged, err := c.execute("UID FETCH " + suid + " BODY.PEEK[]")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range untagged {
+		if !strings.Contains(res.line, " FETCH ") || len(res.literals) == 0 {
+			continue
+		}
+		m := reUID.FindStringSubmatch(res.line)
+		if m != nil && m[1] != suid {
+			continue
+		}
+		return res.literals[0], nil
+	}
+	return nil, errors.New("no such message: " + suid)
+}
+
+func (c *client) addFlag(uid uint32, flag string) error {
+	suid := strconv.FormatUint(uint64(uid), 10)
+	_, err := c.execute("UID STORE " + suid + " +FLAGS.SILENT (" + flag + ")")
+	return err
+}
+
+func (c *client) copy(uid uint32, mailbox string) error {
+	suid := strconv.FormatUint(uint64(uid), 10)
+	_, err := c.execute("UID COPY " + suid + " " + quote(mailbox))
+	return err
+}
+
+func (c *client) expunge() error {
+	_, err := c.execute("EXPUNGE")
+	return err
+}
+
+func (c *client) logout() error {
+	_, err := c.execute("LOGOUT")
+	return err
+}
@@ -0,0 +1,42 @@
+/*
+Package imap reads mails in an IMAP mailbox to generate alerts.
+
+This plugin connects to an IMAP server at startup and then at regular
+intervals, and processes unseen messages in the mailbox.  Alerts are generated
+from mails in the same way as maildir source plugin.  See
+https://godoc.org/github.com/cybozu-go/kkok/plugins/sources/maildir
+for details.
+
+Processed messages, including those failed to be parsed, are
+handled according to "action" parameter:
+
+    Action      Description
+    seen        Set \Seen flag.
+    delete      Set \Deleted flag, then expunge.
+    move        Copy to "move_to" mailbox, then delete.
+
+Construction parameters:
+
+    Name                  Type      Default   Description
+    address               string              Server address in HOST:PORT form.
+    tls                   bool      true      Use IMAPS (implicit TLS).
+    insecure_skip_verify  bool      false     Skip verification of server certificates.
+    user                  string              User name to login.
+    password              string              Password to login.
+    mailbox               string    INBOX     Mailbox name.
+    action                string    seen      "seen", "delete", or "move".
+    move_to               string              Destination mailbox for "move".
+    interval              int       60        Polling interval (seconds).
+    timeout               int       30        Timeout of each IMAP command (seconds).
+
+Example snippet for TOML configuration:
+
+    [[source]]
+    type     = "imap"
+    address  = "imap.example.com:993"
+    user     = "kkok"
+    password = "secret"
+    action   = "move"
+    move_to  = "Processed"
+*/
+package imap
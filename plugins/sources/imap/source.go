@@ -0,0 +1,246 @@
+package imap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/plugins/sources/maildir"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	actionSeen   = "seen"
+	actionDelete = "delete"
+	actionMove   = "move"
+
+	defaultMailbox  = "INBOX"
+	defaultInterval = 60 // seconds
+	defaultTimeout  = 30 // seconds
+)
+
+// source implements kkok.Source.
+type source struct {
+	address  string
+	useTLS   bool
+	tls      *tls.Config
+	user     string
+	password string
+	mailbox  string
+	action   string
+	moveTo   string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// Run polls the mailbox once at startup, then at every interval.
+func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	for {
+		alerts, err := s.poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("[imap] failed to poll", map[string]interface{}{
+				log.FnError: err.Error(),
+				"address":   s.address,
+				"mailbox":   s.mailbox,
+			})
+		}
+		for _, a := range alerts {
+			post(a)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// poll connects to the server and generates alerts from unseen
+// messages.  Alerts generated before an error are also returned.
+// Polling is aborted when ctx is done.
+func (s *source) poll(ctx context.Context) ([]*kkok.Alert, error) {
+	c, err := dial(ctx, s.address, s.useTLS, s.tls, s.timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+	defer c.close()
+
+	err = c.login(s.user, s.password)
+	if err != nil {
+		return nil, err
+	}
+	err = c.selectMailbox(s.mailbox)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := c.searchUnseen()
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*kkok.Alert
+	expunge := false
+	for _, uid := range uids {
+		data, err := c.fetch(uid)
+		if err != nil {
+			return alerts, err
+		}
+
+		a, err := maildir.Parse(bytes.NewReader(data))
+		if err != nil {
+			log.Error("[imap] failed to parse a mail", map[string]interface{}{
+				log.FnError: err.Error(),
+				"mailbox":   s.mailbox,
+				"uid":       uid,
+			})
+		} else {
+			alerts = append(alerts, a)
+		}
+
+		// Invalid mails are processed as well not to parse them again.
+		switch s.action {
+		case actionSeen:
+			err = c.addFlag(uid, `\Seen`)
+		case actionDelete:
+			err = c.addFlag(uid, `\Deleted`)
+			expunge = true
+		case actionMove:
+			err = c.copy(uid, s.moveTo)
+			if err == nil {
+				err = c.addFlag(uid, `\Deleted`)
+				expunge = true
+			}
+		}
+		if err != nil {
+			return alerts, err
+		}
+	}
+
+	if expunge {
+		err = c.expunge()
+		if err != nil {
+			return alerts, err
+		}
+	}
+
+	if len(alerts) > 0 {
+		log.Info("new alerts", map[string]interface{}{
+			"source":  "imap",
+			"count":   len(alerts),
+			"mailbox": s.mailbox,
+		})
+	}
+
+	c.logout()
+	return alerts, nil
+}
+
+func ctor(params map[string]interface{}) (kkok.Source, error) {
+	address, err := util.GetString("address", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "imap: address")
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "imap: address")
+	}
+
+	user, err := util.GetString("user", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "imap: user")
+	}
+	password, err := util.GetString("password", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "imap: password")
+	}
+
+	s := &source{
+		address:  address,
+		useTLS:   true,
+		tls:      &tls.Config{ServerName: host},
+		user:     user,
+		password: password,
+		mailbox:  defaultMailbox,
+		action:   actionSeen,
+		interval: defaultInterval * time.Second,
+		timeout:  defaultTimeout * time.Second,
+	}
+
+	switch b, err := util.GetBool("tls", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: tls")
+	default:
+		s.useTLS = b
+	}
+
+	switch b, err := util.GetBool("insecure_skip_verify", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: insecure_skip_verify")
+	default:
+		s.tls.InsecureSkipVerify = b
+	}
+
+	switch mailbox, err := util.GetString("mailbox", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: mailbox")
+	default:
+		s.mailbox = mailbox
+	}
+
+	switch action, err := util.GetString("action", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: action")
+	default:
+		s.action = action
+	}
+	switch s.action {
+	case actionSeen, actionDelete:
+	case actionMove:
+		moveTo, err := util.GetString("move_to", params)
+		if err != nil {
+			return nil, errors.Wrap(err, "imap: move_to")
+		}
+		s.moveTo = moveTo
+	default:
+		return nil, errors.New("imap: invalid action: " + s.action)
+	}
+
+	switch i, err := util.GetInt("interval", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: interval")
+	default:
+		if i <= 0 {
+			return nil, errors.New("imap: invalid interval")
+		}
+		s.interval = time.Duration(i) * time.Second
+	}
+
+	switch i, err := util.GetInt("timeout", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "imap: timeout")
+	default:
+		if i < 0 {
+			return nil, errors.New("imap: invalid timeout")
+		}
+		s.timeout = time.Duration(i) * time.Second
+	}
+
+	return s, nil
+}
+
+func init() {
+	kkok.RegisterSource("imap", ctor)
+}
@@ -0,0 +1,479 @@
+package imap
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/pkg/errors"
+)
+
+const (
+	testMail1 = "From: monitor <monitor@example.com>\r\n" +
+		"Subject: alert 1\r\n" +
+		"Date: Tue, 28 Feb 2017 13:56:18 +0900\r\n" +
+		"\r\n" +
+		"Host: host1\r\n" +
+		"\r\n" +
+		"body 1\r\n"
+
+	testMail2 = "From: monitor <monitor@example.com>\r\n" +
+		"Subject: alert 2\r\n" +
+		"\r\n" +
+		"body 2\r\n"
+)
+
+type fakeMessage struct {
+	uid   uint32
+	data  string
+	flags map[string]bool
+}
+
+// fakeServer is an in-process IMAP server that implements
+// commands used by the client.
+type fakeServer struct {
+	mu        sync.Mutex
+	listener  net.Listener
+	user      string
+	password  string
+	mailboxes map[string][]*fakeMessage
+	lastUID   uint32
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		listener:  l,
+		user:      "kkok",
+		password:  `pa"ss`,
+		mailboxes: map[string][]*fakeMessage{"INBOX": nil, "Done": nil},
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addMessage(mailbox, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUID++
+	s.mailboxes[mailbox] = append(s.mailboxes[mailbox], &fakeMessage{
+		uid:   s.lastUID,
+		data:  data,
+		flags: make(map[string]bool),
+	})
+}
+
+func (s *fakeServer) messages(mailbox string) []*fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*fakeMessage(nil), s.mailboxes[mailbox]...)
+}
+
+func (s *fakeServer) close() {
+	s.listener.Close()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// args splits IMAP command arguments and unquotes quoted strings.
+func args(s string) []string {
+	var l []string
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ")
+		if len(s) == 0 {
+			break
+		}
+		if s[0] != '"' {
+			idx := strings.IndexByte(s, ' ')
+			if idx == -1 {
+				idx = len(s)
+			}
+			l = append(l, s[:idx])
+			s = s[idx:]
+			continue
+		}
+		var b []byte
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+			b = append(b, s[i])
+		}
+		l = append(l, string(b))
+		s = s[i+1:]
+	}
+	return l
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	w := bufio.NewWriter(conn)
+	r := bufio.NewReader(conn)
+	w.WriteString("* OK fake server ready\r\n")
+	w.Flush()
+
+	var mailbox string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		a := args(strings.TrimRight(line, "\r\n"))
+		if len(a) < 2 {
+			return
+		}
+		tag, cmd := a[0], strings.ToUpper(a[1])
+		a = a[2:]
+		if cmd == "UID" {
+			cmd += " " + strings.ToUpper(a[0])
+			a = a[1:]
+		}
+
+		status := "OK done"
+		s.mu.Lock()
+		find := func(uid string) *fakeMessage {
+			for _, m := range s.mailboxes[mailbox] {
+				if strconv.FormatUint(uint64(m.uid), 10) == uid {
+					return m
+				}
+			}
+			return nil
+		}
+		switch cmd {
+		case "LOGIN":
+			if a[0] != s.user || a[1] != s.password {
+				status = "NO login failed"
+			}
+		case "SELECT":
+			if _, ok := s.mailboxes[a[0]]; !ok {
+				status = "NO no such mailbox"
+				break
+			}
+			mailbox = a[0]
+			w.WriteString("* " + strconv.Itoa(len(s.mailboxes[mailbox])) + " EXISTS\r\n")
+		case "UID SEARCH":
+			w.WriteString("* SEARCH")
+			for _, m := range s.mailboxes[mailbox] {
+				if !m.flags[`\Seen`] && !m.flags[`\Deleted`] {
+					w.WriteString(" " + strconv.FormatUint(uint64(m.uid), 10))
+				}
+			}
+			w.WriteString("\r\n")
+		case "UID FETCH":
+			m := find(a[0])
+			if m == nil {
+				break
+			}
+			w.WriteString("* 1 FETCH (UID " + a[0] + " BODY[] {" + strconv.Itoa(len(m.data)) + "}\r\n")
+			w.WriteString(m.data)
+			w.WriteString(")\r\n")
+		case "UID STORE":
+			if m := find(a[0]); m != nil {
+				m.flags[strings.Trim(a[2], "()")] = true
+			}
+		case "UID COPY":
+			m := find(a[0])
+			if m == nil {
+				break
+			}
+			s.lastUID++
+			s.mailboxes[a[1]] = append(s.mailboxes[a[1]], &fakeMessage{
+				uid:   s.lastUID,
+				data:  m.data,
+				flags: make(map[string]bool),
+			})
+		case "EXPUNGE":
+			var l []*fakeMessage
+			for _, m := range s.mailboxes[mailbox] {
+				if !m.flags[`\Deleted`] {
+					l = append(l, m)
+				}
+			}
+			s.mailboxes[mailbox] = l
+		case "LOGOUT":
+			w.WriteString("* BYE\r\n")
+		default:
+			status = "BAD unknown command"
+		}
+		s.mu.Unlock()
+
+		w.WriteString(tag + " " + status + "\r\n")
+		w.Flush()
+		if cmd == "LOGOUT" {
+			return
+		}
+	}
+}
+
+func testSourceParams(t *testing.T) {
+	t.Parallel()
+
+	base := func() map[string]interface{} {
+		return map[string]interface{}{
+			"address":  "imap.example.com:993",
+			"user":     "kkok",
+			"password": "secret",
+		}
+	}
+
+	for _, key := range []string{"address", "user", "password"} {
+		params := base()
+		delete(params, key)
+		_, err := ctor(params)
+		if err == nil {
+			t.Error(key + ` is not required`)
+		}
+	}
+
+	params := base()
+	params["address"] = "imap.example.com"
+	_, err := ctor(params)
+	if err == nil {
+		t.Error(`address without port is accepted`)
+	}
+
+	params = base()
+	params["action"] = "archive"
+	_, err = ctor(params)
+	if err == nil {
+		t.Error(`invalid action is accepted`)
+	}
+
+	params = base()
+	params["action"] = "move"
+	_, err = ctor(params)
+	if err == nil {
+		t.Error(`move_to is not required for move`)
+	}
+
+	src, err := ctor(base())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+	if !s.useTLS {
+		t.Error(`!s.useTLS`)
+	}
+	if s.tls.ServerName != "imap.example.com" {
+		t.Error(`s.tls.ServerName != "imap.example.com"`)
+	}
+	if s.mailbox != "INBOX" {
+		t.Error(`s.mailbox != "INBOX"`)
+	}
+	if s.action != "seen" {
+		t.Error(`s.action != "seen"`)
+	}
+}
+
+func newTestSource(t *testing.T, server *fakeServer, action string) *source {
+	params := map[string]interface{}{
+		"address":  server.listener.Addr().String(),
+		"tls":      false,
+		"user":     server.user,
+		"password": server.password,
+		"action":   action,
+		"move_to":  "Done",
+	}
+	src, err := ctor(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src.(*source)
+}
+
+func testSourcePollSeen(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+	defer server.close()
+	server.addMessage("INBOX", testMail1)
+	server.addMessage("INBOX", "broken mail")
+	server.addMessage("INBOX", testMail2)
+
+	s := newTestSource(t, server, "seen")
+	alerts, err := s.poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].Title != "alert 1" {
+		t.Error(`alerts[0].Title != "alert 1"`)
+	}
+	if alerts[0].Host != "host1" {
+		t.Error(`alerts[0].Host != "host1"`)
+	}
+	if alerts[1].Title != "alert 2" {
+		t.Error(`alerts[1].Title != "alert 2"`)
+	}
+
+	for _, m := range server.messages("INBOX") {
+		if !m.flags[`\Seen`] {
+			t.Error(`message is not marked as seen`)
+		}
+	}
+
+	alerts, err = s.poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Error(`seen messages are processed again`)
+	}
+}
+
+func testSourcePollMove(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+	defer server.close()
+	server.addMessage("INBOX", testMail1)
+
+	s := newTestSource(t, server, "move")
+	alerts, err := s.poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	if len(server.messages("INBOX")) != 0 {
+		t.Error(`len(server.messages("INBOX")) != 0`)
+	}
+	if len(server.messages("Done")) != 1 {
+		t.Error(`len(server.messages("Done")) != 1`)
+	}
+}
+
+func testSourcePollDelete(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+	defer server.close()
+	server.addMessage("INBOX", testMail1)
+	server.addMessage("INBOX", testMail2)
+
+	s := newTestSource(t, server, "delete")
+	alerts, err := s.poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if len(server.messages("INBOX")) != 0 {
+		t.Error(`len(server.messages("INBOX")) != 0`)
+	}
+}
+
+func testSourcePollLoginFailure(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+	defer server.close()
+
+	s := newTestSource(t, server, "seen")
+	s.password = "wrong"
+	_, err := s.poll(context.Background())
+	if err == nil {
+		t.Fatal(`login succeeded with wrong password`)
+	}
+	if strings.Contains(err.Error(), "wrong") {
+		t.Error(`error contains the password`)
+	}
+}
+
+func testSourcePollCancel(t *testing.T) {
+	t.Parallel()
+
+	// a stalled server that never sends the greeting.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	s := &source{
+		address: l.Addr().String(),
+		mailbox: defaultMailbox,
+		timeout: time.Minute,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	now := time.Now()
+	_, err = s.poll(ctx)
+	if errors.Cause(err) != context.Canceled {
+		t.Error(`errors.Cause(err) != context.Canceled`, err)
+	}
+	if time.Now().Sub(now) > time.Second {
+		t.Error(`poll was not cancelled`)
+	}
+}
+
+func testSourceRun(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+	defer server.close()
+	server.addMessage("INBOX", testMail1)
+
+	s := newTestSource(t, server, "seen")
+	s.interval = time.Hour
+
+	ch := make(chan *kkok.Alert, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, func(a *kkok.Alert) {
+		ch <- a
+	})
+
+	// the mailbox is polled at startup without waiting the interval.
+	select {
+	case a := <-ch:
+		if a.Title != "alert 1" {
+			t.Error(`a.Title != "alert 1"`)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error(`no alert at startup`)
+	}
+}
+
+func TestSource(t *testing.T) {
+	t.Run("Params", testSourceParams)
+	t.Run("Poll/Seen", testSourcePollSeen)
+	t.Run("Poll/Move", testSourcePollMove)
+	t.Run("Poll/Delete", testSourcePollDelete)
+	t.Run("Poll/LoginFailure", testSourcePollLoginFailure)
+	t.Run("Poll/Cancel", testSourcePollCancel)
+	t.Run("Run", testSourceRun)
+}
@@ -4,22 +4,39 @@ Package maildir reads mails in a Maildir directory to generate alerts.
 Maildir is a mail spool format first implemented in qmail:
 https://cr.yp.to/proto/maildir.html
 
-This plugin scans a Maildir directory repeatedly at regular intervals.
+This plugin scans a Maildir directory at startup, then repeatedly at
+regular intervals.
 Specifically, mails in "new" directory will be scanned, processed, then
 removed by the plugin.
 
+If "action" is "cur", processed mails are moved to "cur" directory
+with "S" (seen) flag instead of being removed.  If "quarantine" is
+specified, mails that cannot be parsed are moved to the directory
+so that they can be examined later.  The directory is created if it
+does not exist, and must be on the same file system as "dir".
+
+If "watch" is true, the plugin watches "new" directory with inotify
+and scans it as soon as a mail is delivered.  The directory is also
+scanned at "interval" in case some events are missed.
+
 Construction parameters:
 
     Name        Type               Default       Description
     dir         string                           Absolute path to a Maildir directory.
     interval    int                10            Scanning interval (seconds).
+    action      string             "delete"      "delete" or "cur".
+    quarantine  string                           Absolute path to move unparsable mails.
+    watch       bool               false         Watch "new" directory for new mails.
 
 Example snippet for TOML configuration:
 
     [[source]]
-    type     = "maildir"
-    dir      = "/var/mail/kkok"
-    interval = 60
+    type       = "maildir"
+    dir        = "/var/mail/kkok"
+    interval   = 60
+    action     = "cur"
+    quarantine = "/var/mail/kkok-error"
+    watch      = true
 
 Alerts are generated from mail headers and headers-in-mail-body.
 Specifically, "From" is taken from the mail's From header value,
@@ -45,5 +62,14 @@ Pseudo headers that do not match kkok.Alert field names become members of
 Fields specified by pseudo headers take precedence over normal headers.
 In the above example, "From" field value will be "east asian zoo operator",
 and "Info" field value will be a map {"Option1":123,"Option2":"abc def"}.
+
+MIME multipart mails are supported.  The message body is made from
+text/plain parts.  If a mail has no text/plain parts, text/html parts
+are converted into plain text and used instead.  Other parts and parts
+with file names are treated as attachments; their contents are not
+used, but their names, content types, and sizes in bytes are listed in
+"attachments" member of "Info" like this:
+
+    {"attachments": [{"name": "report.csv", "type": "text/csv", "size": 12}]}
 */
 package maildir
@@ -0,0 +1,195 @@
+package maildir
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxMIMEDepth = 10
+)
+
+var (
+	reHTMLSkip     = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>|<!--.*?-->`)
+	reHTMLBreak    = regexp.MustCompile(`(?i)<br\s*/?>|<li\b[^>]*>|</?(p|div|tr|table|ul|ol|h[1-6]|pre|blockquote|hr)\b[^>]*>`)
+	reHTMLTag      = regexp.MustCompile(`<[^>]*>`)
+	reSpaces       = regexp.MustCompile(`[ \t\r\f\v]+`)
+	reManyNewlines = regexp.MustCompile(`\n{3,}`)
+)
+
+// attachment describes a non-text or attached MIME part.
+type attachment struct {
+	Name string
+	Type string
+	Size int64
+}
+
+func (at attachment) info() map[string]interface{} {
+	return map[string]interface{}{
+		"name": at.Name,
+		"type": at.Type,
+		"size": at.Size,
+	}
+}
+
+// mimeContent accumulates contents found by walking a MIME tree.
+type mimeContent struct {
+	plain       [][]byte
+	html        [][]byte
+	attachments []attachment
+}
+
+// text returns text/plain parts joined if any.  Otherwise, text/html
+// parts converted into plain text are returned.
+func (c *mimeContent) text() []byte {
+	if len(c.plain) > 0 {
+		return bytes.Join(c.plain, []byte{'\n'})
+	}
+	if len(c.html) > 0 {
+		return htmlToText(bytes.Join(c.html, []byte{'\n'}))
+	}
+	return nil
+}
+
+func decodeTransfer(h textproto.MIMEHeader, r io.Reader) (io.Reader, error) {
+	switch t := strings.ToLower(h.Get("Content-Transfer-Encoding")); t {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r), nil
+	case "quoted-printable":
+		return quotedprintable.NewReader(r), nil
+	case "", "7bit", "8bit", "binary":
+		return r, nil
+	default:
+		return nil, errors.New("unsupported transfer encoding: " + t)
+	}
+}
+
+// partName returns the file name of a part, if any.
+func partName(h textproto.MIMEHeader, ctParams map[string]string) (string, bool) {
+	attached := false
+	name := ""
+	if cd := h.Get("Content-Disposition"); len(cd) > 0 {
+		disp, params, err := mime.ParseMediaType(cd)
+		if err == nil {
+			attached = disp == "attachment"
+			name = params["filename"]
+		}
+	}
+	if len(name) == 0 {
+		name = ctParams["name"]
+	}
+	if len(name) > 0 {
+		if decoded, err := mimeDecoder.DecodeHeader(name); err == nil {
+			name = decoded
+		}
+		attached = true
+	}
+	return name, attached
+}
+
+// walk walks a MIME tree rooted at a part with header h and body r.
+func walk(h textproto.MIMEHeader, r io.Reader, c *mimeContent, depth int) error {
+	if depth > maxMIMEDepth {
+		return errors.New("too deep MIME structure")
+	}
+
+	mt := "text/plain"
+	params := map[string]string{}
+	if ct := h.Get("Content-Type"); len(ct) > 0 {
+		var err error
+		mt, params, err = mime.ParseMediaType(ct)
+		if err != nil {
+			return errors.Wrap(err, "bad content-type")
+		}
+	}
+
+	if strings.HasPrefix(mt, "multipart/") {
+		boundary := params["boundary"]
+		if len(boundary) == 0 {
+			return errors.New("no boundary for " + mt)
+		}
+		mr := multipart.NewReader(r, boundary)
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, mt)
+			}
+			err = walk(p.Header, p, c, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	r, err := decodeTransfer(h, r)
+	if err != nil {
+		return err
+	}
+
+	name, attached := partName(h, params)
+	if attached || (mt != "text/plain" && mt != "text/html") {
+		n, err := io.Copy(ioutil.Discard, r)
+		if err != nil {
+			return err
+		}
+		c.attachments = append(c.attachments, attachment{name, mt, n})
+		return nil
+	}
+
+	if cs, ok := params["charset"]; ok {
+		r, err = charsetReader(cs, r)
+		if err != nil {
+			return errors.New("unsupported character set: " + cs)
+		}
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	data = bytes.Replace(data, []byte{'\r'}, nil, -1)
+
+	if mt == "text/html" {
+		c.html = append(c.html, data)
+	} else {
+		c.plain = append(c.plain, data)
+	}
+	return nil
+}
+
+// htmlToText converts HTML into plain text roughly.
+// Block-level elements are converted into newlines.
+func htmlToText(data []byte) []byte {
+	s := string(data)
+	s = reHTMLSkip.ReplaceAllString(s, "")
+	s = strings.Replace(s, "\n", " ", -1)
+	s = reHTMLBreak.ReplaceAllString(s, "\n")
+	s = reHTMLTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.Replace(s, "\u00a0", " ", -1)
+
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(reSpaces.ReplaceAllString(l, " "))
+	}
+	s = strings.Join(lines, "\n")
+	s = reManyNewlines.ReplaceAllString(s, "\n\n")
+	s = strings.TrimSpace(s)
+	if len(s) > 0 {
+		s += "\n"
+	}
+	return []byte(s)
+}
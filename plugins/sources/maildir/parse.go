@@ -2,14 +2,11 @@ package maildir
 
 import (
 	"bytes"
-	"encoding/base64"
 	"io"
-	"io/ioutil"
 	"mime"
-	"mime/quotedprintable"
 	"net/mail"
+	"net/textproto"
 	"regexp"
-	"strings"
 	"time"
 
 	"golang.org/x/text/encoding/htmlindex"
@@ -100,41 +97,15 @@ func parseDate(s string) (time.Time, error) {
 	return time.Time{}, errors.New("mail: header could not be parsed")
 }
 
-func decodeBody(m *mail.Message) ([]byte, error) {
-	r := m.Body
-	switch t := strings.ToLower(m.Header.Get("Content-Transfer-Encoding")); t {
-	case "base64":
-		r = base64.NewDecoder(base64.StdEncoding, r)
-	case "quoted-printable":
-		r = quotedprintable.NewReader(r)
-	case "", "7bit", "8bit":
-	default:
-		return nil, errors.New("unsupported transfer encoding: " + t)
-	}
-
-	ct := m.Header.Get("Content-Type")
-	if len(ct) > 0 {
-		mt, params, err := mime.ParseMediaType(ct)
-		if err != nil {
-			return nil, errors.Wrap(err, "bad content-type")
-		}
-		if !strings.HasPrefix(mt, "text/") {
-			return nil, errors.New("non text content: " + mt)
-		}
-		if cs, ok := params["charset"]; ok {
-			r, err = charsetReader(cs, r)
-			if err != nil {
-				return nil, errors.New("unsupported character set: " + cs)
-			}
-		}
-	}
-
-	data, err := ioutil.ReadAll(r)
+// decodeBody walks the MIME tree of m to return the text content
+// and attachments.
+func decodeBody(m *mail.Message) ([]byte, []attachment, error) {
+	c := new(mimeContent)
+	err := walk(textproto.MIMEHeader(m.Header), m.Body, c, 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	return bytes.Replace(data, []byte{'\r'}, nil, -1), nil
+	return c.text(), c.attachments, nil
 }
 
 func parseBody(data []byte, a *kkok.Alert) {
@@ -201,7 +172,13 @@ func parseBody(data []byte, a *kkok.Alert) {
 	a.Message = string(buf)
 }
 
-// parse read a mail source from r and generate an alert.
+// Parse reads a mail from r and generates an alert as described in
+// the package document.  This is used by other mail based sources.
+func Parse(r io.Reader) (*kkok.Alert, error) {
+	return parse(r)
+}
+
+// parse reads a mail source from r and generates an alert.
 func parse(r io.Reader) (*kkok.Alert, error) {
 	r = &io.LimitedReader{
 		R: r,
@@ -229,7 +206,7 @@ func parse(r io.Reader) (*kkok.Alert, error) {
 		Title: title,
 	}
 
-	body, err := decodeBody(m)
+	body, attachments, err := decodeBody(m)
 	if err != nil {
 		log.Error("failed to decode mail body", map[string]interface{}{
 			log.FnError:    err.Error(),
@@ -241,6 +218,14 @@ func parse(r io.Reader) (*kkok.Alert, error) {
 
 	parseBody(body, a)
 
+	if len(attachments) > 0 {
+		l := make([]interface{}, len(attachments))
+		for i, at := range attachments {
+			l[i] = at.info()
+		}
+		a.SetInfo("attachments", l)
+	}
+
 	err = a.Validate()
 	if err != nil {
 		return nil, err
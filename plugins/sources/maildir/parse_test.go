@@ -104,6 +104,43 @@ var (
 			Host:    "test-host",
 			Message: "$ € 円\n",
 		},
+
+		"multipart_alternative": {
+			From:    "monitor@example.com",
+			Date:    time.Date(2017, 2, 28, 6, 10, 15, 0, time.UTC),
+			Title:   "multipart alternative",
+			Host:    "host-1",
+			Message: "Café is down.",
+		},
+
+		"html_only": {
+			From:    "monitor@example.com",
+			Date:    time.Date(2017, 2, 28, 6, 10, 15, 0, time.UTC),
+			Title:   "html only",
+			Host:    "host-2",
+			Message: "Disk usage is 95% & rising.\n\nsda1\nsdb1\n",
+		},
+
+		"multipart_mixed": {
+			From:    "monitor@example.com",
+			Date:    time.Date(2017, 2, 28, 6, 10, 15, 0, time.UTC),
+			Title:   "multipart mixed",
+			Message: "report attached\n",
+			Info: map[string]interface{}{
+				"attachments": []interface{}{
+					map[string]interface{}{
+						"name": "report.csv",
+						"type": "text/csv",
+						"size": int64(12),
+					},
+					map[string]interface{}{
+						"name": "グラフ.png",
+						"type": "image/png",
+						"size": int64(8),
+					},
+				},
+			},
+		},
 	}
 )
 
@@ -159,4 +196,7 @@ func TestParse(t *testing.T) {
 	t.Run("Pseudo/Base64", func(t *testing.T) { testParse(t, "pseudo_base64") })
 	t.Run("QuotedPrintable", func(t *testing.T) { testParse(t, "qprintable") })
 	t.Run("Base64", func(t *testing.T) { testParse(t, "base64") })
+	t.Run("Multipart/Alternative", func(t *testing.T) { testParse(t, "multipart_alternative") })
+	t.Run("Multipart/Mixed", func(t *testing.T) { testParse(t, "multipart_mixed") })
+	t.Run("HTML", func(t *testing.T) { testParse(t, "html_only") })
 }
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/cybozu-go/kkok"
 	"github.com/cybozu-go/log"
@@ -19,9 +20,36 @@ func parseFile(p string) (*kkok.Alert, error) {
 	return parse(f)
 }
 
-// scan scans a Maildir directory dir and generates alerts.
-func scan(dir string) []*kkok.Alert {
-	files, err := ioutil.ReadDir(filepath.Join(dir, "new"))
+// curName returns the file name of a mail moved to "cur" directory
+// with "S" (seen) flag as described in https://cr.yp.to/proto/maildir.html
+func curName(fname string) string {
+	if idx := strings.IndexByte(fname, ':'); idx != -1 {
+		fname = fname[:idx]
+	}
+	return fname + ":2,S"
+}
+
+// dispose removes or moves a processed mail.
+// If parseErr is not nil and quarantine is configured, the mail is
+// moved to the quarantine directory, which is created if not exist.
+func (s *source) dispose(fname string, parseErr error) error {
+	p := filepath.Join(s.dir, "new", fname)
+	switch {
+	case parseErr != nil && len(s.quarantine) > 0:
+		err := os.MkdirAll(s.quarantine, 0700)
+		if err != nil {
+			return err
+		}
+		return os.Rename(p, filepath.Join(s.quarantine, fname))
+	case s.action == actionCur:
+		return os.Rename(p, filepath.Join(s.dir, "cur", curName(fname)))
+	}
+	return os.Remove(p)
+}
+
+// scan scans "new" sub directory of the Maildir and generates alerts.
+func (s *source) scan() []*kkok.Alert {
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, "new"))
 	if err != nil {
 		return nil
 	}
@@ -33,24 +61,23 @@ func scan(dir string) []*kkok.Alert {
 		}
 
 		fname := f.Name()
-		p := filepath.Join(dir, "new", fname)
-		a, err := parseFile(p)
+		a, err := parseFile(filepath.Join(s.dir, "new", fname))
 		if err != nil {
 			log.Error("failed to parse a mail", map[string]interface{}{
 				"source":    "maildir",
 				log.FnError: err.Error(),
-				"dir":       dir,
+				"dir":       s.dir,
 				"filename":  fname,
 			})
 		} else {
 			alerts = append(alerts, a)
 		}
-		err = os.Remove(p)
+		err = s.dispose(fname, err)
 		if err != nil {
-			log.Critical("failed to remove a mail", map[string]interface{}{
+			log.Critical("failed to dispose a mail", map[string]interface{}{
 				"source":    "maildir",
 				log.FnError: err.Error(),
-				"dir":       dir,
+				"dir":       s.dir,
 				"filename":  fname,
 			})
 			return nil
@@ -61,7 +88,7 @@ func scan(dir string) []*kkok.Alert {
 		log.Info("new alerts", map[string]interface{}{
 			"source": "maildir",
 			"count":  len(alerts),
-			"dir":    dir,
+			"dir":    s.dir,
 		})
 	}
 
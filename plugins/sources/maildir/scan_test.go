@@ -52,8 +52,114 @@ func TestScan(t *testing.T) {
 		}
 	}
 
-	alerts := scan(dir)
+	s := &source{dir: dir, action: actionDelete}
+	alerts := s.scan()
 	if len(alerts) != len(files) {
 		t.Errorf(`len(alerts) != len(files) (%d, %d)`, len(alerts), len(files))
 	}
+
+	remaining, err := ioutil.ReadDir(ndir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Error(`len(remaining) != 0`, len(remaining))
+	}
+}
+
+const badMail = `From: foo@example.com
+Subject: bad
+Content-Transfer-Encoding: x-bogus
+
+body
+`
+
+func makeMaildir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = copyFile("testdata/new/simple.txt", filepath.Join(dir, "new", "good"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "new", "bad"), []byte(badMail), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func testScanCur(t *testing.T) {
+	t.Parallel()
+
+	dir := makeMaildir(t)
+	defer os.RemoveAll(dir)
+
+	s := &source{dir: dir, action: actionCur}
+	alerts := s.scan()
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`, len(alerts))
+	}
+
+	if exists(filepath.Join(dir, "new", "good")) {
+		t.Error(`new/good still exists`)
+	}
+	if !exists(filepath.Join(dir, "cur", "good:2,S")) {
+		t.Error(`cur/good:2,S does not exist`)
+	}
+	if !exists(filepath.Join(dir, "cur", "bad:2,S")) {
+		t.Error(`cur/bad:2,S does not exist`)
+	}
+}
+
+func testScanQuarantine(t *testing.T) {
+	t.Parallel()
+
+	dir := makeMaildir(t)
+	defer os.RemoveAll(dir)
+
+	qdir := filepath.Join(dir, "quarantine")
+	s := &source{dir: dir, action: actionDelete, quarantine: qdir}
+	alerts := s.scan()
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`, len(alerts))
+	}
+
+	if exists(filepath.Join(dir, "new", "good")) {
+		t.Error(`new/good still exists`)
+	}
+	if exists(filepath.Join(dir, "new", "bad")) {
+		t.Error(`new/bad still exists`)
+	}
+	if !exists(filepath.Join(qdir, "bad")) {
+		t.Error(`quarantine/bad does not exist`)
+	}
+}
+
+func TestScanDispose(t *testing.T) {
+	t.Run("Cur", testScanCur)
+	t.Run("Quarantine", testScanQuarantine)
+}
+
+func TestCurName(t *testing.T) {
+	t.Parallel()
+
+	if n := curName("123.abc.host"); n != "123.abc.host:2,S" {
+		t.Error(`n != "123.abc.host:2,S"`, n)
+	}
+	if n := curName("123.abc.host:2,"); n != "123.abc.host:2,S" {
+		t.Error(`n != "123.abc.host:2,S"`, n)
+	}
 }
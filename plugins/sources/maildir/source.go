@@ -9,30 +9,87 @@ import (
 	"github.com/cybozu-go/kkok"
 	"github.com/cybozu-go/kkok/util"
 	"github.com/cybozu-go/log"
+	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
 )
 
 const (
 	defaultInterval = 10 // seconds
+
+	actionDelete = "delete"
+	actionCur    = "cur"
 )
 
 // source implements kkok.Source.
 type source struct {
-	dir      string
-	interval time.Duration
+	dir        string
+	interval   time.Duration
+	action     string
+	quarantine string
+	watch      bool
 }
 
+// Run scans the directory at startup, then at every interval.
 func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	if s.watch {
+		return s.runWatch(ctx, post)
+	}
+
 	for {
+		for _, a := range s.scan() {
+			post(a)
+		}
+
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-time.After(s.interval):
 		}
+	}
+}
+
+// runWatch scans the directory whenever a mail is delivered to "new".
+// The directory is also scanned at regular intervals in case that
+// some events are lost.
+func (s *source) runWatch(ctx context.Context, post func(*kkok.Alert)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "maildir")
+	}
+	defer w.Close()
+
+	ndir := filepath.Join(s.dir, "new")
+	err = w.Add(ndir)
+	if err != nil {
+		// fall back to polling.
+		log.Warn("failed to watch directory", map[string]interface{}{
+			"source":    "maildir",
+			log.FnError: err.Error(),
+			"dir":       ndir,
+		})
+	}
 
-		for _, a := range scan(s.dir) {
+	for {
+		for _, a := range s.scan() {
 			post(a)
 		}
+
+	WAIT:
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-w.Events:
+			if ev.Op&fsnotify.Create == 0 {
+				goto WAIT
+			}
+		case err := <-w.Errors:
+			log.Error("failed to watch directory", map[string]interface{}{
+				"source":    "maildir",
+				log.FnError: err.Error(),
+				"dir":       ndir,
+			})
+		case <-time.After(s.interval):
+		}
 	}
 }
 
@@ -72,7 +129,48 @@ func ctor(params map[string]interface{}) (kkok.Source, error) {
 		return nil, errors.New(`maildir: invalid interval value`)
 	}
 
-	return &source{dir, interval}, nil
+	action := actionDelete
+	switch a, err := util.GetString("action", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "maildir: action")
+	default:
+		action = a
+	}
+	switch action {
+	case actionDelete, actionCur:
+	default:
+		return nil, errors.New("maildir: invalid action: " + action)
+	}
+
+	var quarantine string
+	switch q, err := util.GetString("quarantine", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "maildir: quarantine")
+	default:
+		if !filepath.IsAbs(q) {
+			return nil, errors.New(`maildir: quarantine is not an absolute path`)
+		}
+		quarantine = q
+	}
+
+	var watch bool
+	switch b, err := util.GetBool("watch", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "maildir: watch")
+	default:
+		watch = b
+	}
+
+	return &source{
+		dir:        dir,
+		interval:   interval,
+		action:     action,
+		quarantine: quarantine,
+		watch:      watch,
+	}, nil
 }
 
 func init() {
@@ -1,10 +1,14 @@
 package maildir
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/cybozu-go/kkok"
 )
 
 func testSourceDirMissing(t *testing.T) {
@@ -144,6 +148,157 @@ func testSourceIntervalCustom(t *testing.T) {
 	}
 }
 
+func testSourceActionDefault(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"dir": "/not/existing",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.(*source).action != actionDelete {
+		t.Error(`src.(*source).action != actionDelete`)
+	}
+}
+
+func testSourceActionCur(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"dir":    "/not/existing",
+		"action": "cur",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.(*source).action != actionCur {
+		t.Error(`src.(*source).action != actionCur`)
+	}
+}
+
+func testSourceActionInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{
+		"dir":    "/not/existing",
+		"action": "move",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSourceQuarantineRelative(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{
+		"dir":        "/not/existing",
+		"quarantine": "error",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSourceWatchWrongType(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{
+		"dir":   "/not/existing",
+		"watch": "yes",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSourceRunWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := makeMaildir(t)
+	defer os.RemoveAll(dir)
+
+	src, err := ctor(map[string]interface{}{
+		"dir":      dir,
+		"interval": 3600,
+		"watch":    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *kkok.Alert, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(ctx, func(a *kkok.Alert) { ch <- a })
+	}()
+
+	// existing mails are processed at startup.
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal(`timed out`)
+	}
+
+	// deliver a new mail as MTAs do.
+	tmp := filepath.Join(dir, "tmp", "watched")
+	err = copyFile("testdata/new/simple.txt", tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(tmp, filepath.Join(dir, "new", "watched"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal(`timed out`)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func testSourceRunPoll(t *testing.T) {
+	t.Parallel()
+
+	dir := makeMaildir(t)
+	defer os.RemoveAll(dir)
+
+	src, err := ctor(map[string]interface{}{
+		"dir":      dir,
+		"interval": 3600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *kkok.Alert, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(ctx, func(a *kkok.Alert) { ch <- a })
+	}()
+
+	// existing mails are processed at startup without waiting the interval.
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal(`timed out`)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
 func TestSource(t *testing.T) {
 	t.Run("dir/missing", testSourceDirMissing)
 	t.Run("dir/wrongtype", testSourceDirWrongType)
@@ -154,4 +309,11 @@ func TestSource(t *testing.T) {
 	t.Run("interval/wrongtype", testSourceIntervalWrongType)
 	t.Run("interval/wrongvalue", testSourceIntervalWrongValue)
 	t.Run("interval/custom", testSourceIntervalCustom)
+	t.Run("action/default", testSourceActionDefault)
+	t.Run("action/cur", testSourceActionCur)
+	t.Run("action/invalid", testSourceActionInvalid)
+	t.Run("quarantine/relative", testSourceQuarantineRelative)
+	t.Run("watch/wrongtype", testSourceWatchWrongType)
+	t.Run("run/poll", testSourceRunPoll)
+	t.Run("run/watch", testSourceRunWatch)
 }
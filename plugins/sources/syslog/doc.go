@@ -0,0 +1,54 @@
+/*
+Package syslog receives syslog messages to generate alerts.
+
+This plugin listens on a UDP, TCP, or UNIX domain socket and parses
+messages in RFC5424 or RFC3164 (BSD syslog) format.  Messages over
+stream sockets can be framed by octet counting or by newlines (RFC6587).
+
+Construction parameters:
+
+    Name        Type               Default       Description
+    network     string             udp           "udp", "tcp", "unix", or "unixgram".
+    address     string                           Listening address or socket path.
+    severity    string             warning       Least severe level to generate alerts.
+    if          string                           JavaScript expression to select alerts.
+
+Messages less severe than "severity" are ignored.  Severity names are
+"emerg", "alert", "crit", "err", "warning", "notice", "info", and "debug".
+
+If "if" is given, it is evaluated with the generated alert as "alert"
+variable.  Only alerts for which the expression is true are posted.
+
+Example snippet for TOML configuration:
+
+    [[source]]
+    type     = "syslog"
+    network  = "udp"
+    address  = ":10514"
+    severity = "err"
+    if       = "alert.Info.facility != 'local7'"
+
+Alerts are generated as follows:
+
+    Field       Value
+    From        APP-NAME (TAG for RFC3164), or "syslog" if missing.
+    Date        TIMESTAMP, or the current time if missing.
+    Host        HOSTNAME, or the sender's address if missing.
+    Title       The first line of MSG.
+    Message     MSG.
+    Info        See below.
+
+Info has these members:
+
+    Name        Type               Description
+    facility    string             Facility name such as "daemon" or "local0".
+    severity    string             Severity name.
+    procid      string             PROCID (PID for RFC3164) if given.
+    msgid       string             MSGID if given.
+    sd          object             STRUCTURED-DATA if given.
+
+"sd" maps SD-IDs to objects of SD-PARAMs.  For example, a message with
+[exampleSDID@32473 iut="3" eventSource="Application"] will have
+Info.sd["exampleSDID@32473"].iut == "3".
+*/
+package syslog
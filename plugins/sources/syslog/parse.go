@@ -0,0 +1,255 @@
+package syslog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nilValue = "-"
+	bom      = "\xEF\xBB\xBF"
+)
+
+var (
+	severityNames = []string{
+		"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+	}
+
+	facilityNames = []string{
+		"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+		"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+	}
+)
+
+// message is a parsed syslog message.
+type message struct {
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+
+	// SD is structured data in RFC5424 messages.
+	// Keys are SD-IDs, and values are maps of SD-PARAMs.
+	SD map[string]map[string]string
+
+	Msg string
+}
+
+// parse parses an RFC5424 or RFC3164 syslog message.
+// now is used to complement the year of RFC3164 timestamps.
+func parse(b []byte, now time.Time) (*message, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+	s := string(b)
+
+	if len(s) < 3 || s[0] != '<' {
+		return nil, errors.New("no PRI")
+	}
+	idx := strings.IndexByte(s, '>')
+	if idx < 2 || idx > 4 {
+		return nil, errors.New("invalid PRI")
+	}
+	pri, err := strconv.Atoi(s[1:idx])
+	if err != nil || pri > 191 {
+		return nil, errors.New("invalid PRI: " + s[1:idx])
+	}
+	m := &message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	s = s[idx+1:]
+
+	if strings.HasPrefix(s, "1 ") {
+		err = parse5424(m, s[2:])
+	} else {
+		parse3164(m, s, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// nextField splits s at the first space.
+func nextField(s string) (field, rest string) {
+	idx := strings.IndexByte(s, ' ')
+	if idx == -1 {
+		return s, ""
+	}
+	return s[:idx], s[idx+1:]
+}
+
+func nilable(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
+
+func parse5424(m *message, s string) error {
+	var ts string
+	ts, s = nextField(s)
+	if ts != nilValue {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return errors.Wrap(err, "invalid TIMESTAMP")
+		}
+		m.Timestamp = t
+	}
+
+	var f string
+	f, s = nextField(s)
+	m.Hostname = nilable(f)
+	f, s = nextField(s)
+	m.AppName = nilable(f)
+	f, s = nextField(s)
+	m.ProcID = nilable(f)
+	f, s = nextField(s)
+	m.MsgID = nilable(f)
+
+	if len(s) == 0 {
+		return errors.New("no STRUCTURED-DATA")
+	}
+	if s[0] == '-' {
+		s = s[1:]
+	} else {
+		sd, rest, err := parseSD(s)
+		if err != nil {
+			return err
+		}
+		m.SD = sd
+		s = rest
+	}
+
+	s = strings.TrimPrefix(s, " ")
+	m.Msg = strings.TrimPrefix(s, bom)
+	return nil
+}
+
+// parseSD parses one or more SD-ELEMENTs at the beginning of s.
+func parseSD(s string) (map[string]map[string]string, string, error) {
+	sd := make(map[string]map[string]string)
+
+	for len(s) > 0 && s[0] == '[' {
+		s = s[1:]
+		end := strings.IndexAny(s, " ]")
+		if end < 1 {
+			return nil, "", errors.New("invalid SD-ID")
+		}
+		params := make(map[string]string)
+		sd[s[:end]] = params
+		s = s[end:]
+
+		for {
+			if len(s) == 0 {
+				return nil, "", errors.New("unterminated SD-ELEMENT")
+			}
+			if s[0] == ']' {
+				s = s[1:]
+				break
+			}
+			s = strings.TrimLeft(s, " ")
+
+			eq := strings.Index(s, `="`)
+			if eq < 1 {
+				return nil, "", errors.New("invalid SD-PARAM")
+			}
+			name := s[:eq]
+			s = s[eq+2:]
+
+			var value []byte
+			closed := false
+			for i := 0; i < len(s); i++ {
+				c := s[i]
+				if c == '\\' && i+1 < len(s) {
+					switch s[i+1] {
+					case '"', '\\', ']':
+						value = append(value, s[i+1])
+						i++
+						continue
+					}
+				}
+				if c == '"' {
+					s = s[i+1:]
+					closed = true
+					break
+				}
+				value = append(value, c)
+			}
+			if !closed {
+				return nil, "", errors.New("unterminated PARAM-VALUE")
+			}
+			params[name] = string(value)
+		}
+	}
+
+	return sd, s, nil
+}
+
+// parseStamp parses a timestamp at the beginning of s.
+func parseStamp(s string, now time.Time) (time.Time, string, bool) {
+	// Some implementations send RFC3339 timestamps instead.
+	if f, rest := nextField(s); len(f) > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, f); err == nil {
+			return t, rest, true
+		}
+	}
+
+	if len(s) < len(time.Stamp) {
+		return time.Time{}, s, false
+	}
+	t, err := time.Parse(time.Stamp, s[:len(time.Stamp)])
+	if err != nil {
+		return time.Time{}, s, false
+	}
+	t = time.Date(now.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+	// for messages sent just before a new year.
+	if t.Sub(now) > 24*time.Hour {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, strings.TrimPrefix(s[len(time.Stamp):], " "), true
+}
+
+func parse3164(m *message, s string, now time.Time) {
+	t, s, ok := parseStamp(s, now)
+	if !ok {
+		m.Msg = s
+		return
+	}
+	m.Timestamp = t
+
+	// Messages sent to local sockets often lack HOSTNAME.
+	// TAG is terminated by ":" or "[".
+	if f, rest := nextField(s); !strings.ContainsAny(f, ":[") {
+		m.Hostname = f
+		s = rest
+	}
+
+	idx := strings.IndexAny(s, ":[ ")
+	if idx < 1 || s[idx] == ' ' {
+		m.Msg = s
+		return
+	}
+	m.AppName = s[:idx]
+	s = s[idx:]
+
+	if s[0] == '[' {
+		end := strings.IndexByte(s, ']')
+		if end == -1 {
+			m.Msg = s
+			return
+		}
+		m.ProcID = s[1:end]
+		s = s[end+1:]
+	}
+	s = strings.TrimPrefix(s, ":")
+	m.Msg = strings.TrimPrefix(s, " ")
+}
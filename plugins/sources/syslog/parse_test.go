@@ -0,0 +1,137 @@
+package syslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	testNow = time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	parseExpects = map[string]*message{
+		// RFC5424 examples
+		`<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - BOM'su root' failed for lonvick on /dev/pts/8`: {
+			Facility:  4,
+			Severity:  2,
+			Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+			Hostname:  "mymachine.example.com",
+			AppName:   "su",
+			MsgID:     "ID47",
+			Msg:       "BOM'su root' failed for lonvick on /dev/pts/8",
+		},
+		`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"]` + "\xEF\xBB\xBF" + `An application event`: {
+			Facility:  20,
+			Severity:  5,
+			Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+			Hostname:  "mymachine.example.com",
+			AppName:   "evntslog",
+			MsgID:     "ID47",
+			SD: map[string]map[string]string{
+				"exampleSDID@32473": {
+					"iut":         "3",
+					"eventSource": "Application",
+					"eventID":     "1011",
+				},
+				"examplePriority@32473": {
+					"class": "high",
+				},
+			},
+			Msg: "An application event",
+		},
+		`<165>1 - - - - - [id a="x\"y\]z"]`: {
+			Facility: 20,
+			Severity: 5,
+			SD: map[string]map[string]string{
+				"id": {"a": `x"y]z`},
+			},
+		},
+
+		// RFC3164
+		`<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`: {
+			Facility:  4,
+			Severity:  2,
+			Timestamp: time.Date(2016, 10, 11, 22, 14, 15, 0, time.UTC),
+			Hostname:  "mymachine",
+			AppName:   "su",
+			Msg:       "'su root' failed for lonvick on /dev/pts/8",
+		},
+		`<13>Feb  3 04:05:06 sshd[1234]: hello world` + "\n": {
+			Facility:  1,
+			Severity:  5,
+			Timestamp: time.Date(2017, 2, 3, 4, 5, 6, 0, time.UTC),
+			AppName:   "sshd",
+			ProcID:    "1234",
+			Msg:       "hello world",
+		},
+		`<11>2017-02-03T04:05:06+09:00 host1 app: rsyslog format`: {
+			Facility:  1,
+			Severity:  3,
+			Timestamp: time.Date(2017, 2, 3, 4, 5, 6, 0, time.FixedZone("", 9*3600)),
+			Hostname:  "host1",
+			AppName:   "app",
+			Msg:       "rsyslog format",
+		},
+		`<0>no timestamp`: {
+			Msg: "no timestamp",
+		},
+	}
+)
+
+func testParseValid(t *testing.T) {
+	t.Parallel()
+
+	for s, expected := range parseExpects {
+		m, err := parse([]byte(s), testNow)
+		if err != nil {
+			t.Error(s, err)
+			continue
+		}
+		if !m.Timestamp.Equal(expected.Timestamp) {
+			t.Error(`!m.Timestamp.Equal(expected.Timestamp)`, s)
+		}
+		m.Timestamp = expected.Timestamp
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("unexpected result for %s: %#v", s, m)
+		}
+	}
+}
+
+func testParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"",
+		"no pri",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<34>1 yesterday - - - - -",
+		"<34>1 - - - - -",
+		`<34>1 - - - - - [id a="b"`,
+		`<34>1 - - - - - [id a=b]`,
+	} {
+		_, err := parse([]byte(s), testNow)
+		if err == nil {
+			t.Error(`invalid message is accepted:`, s)
+		}
+	}
+}
+
+func testParseNewYear(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2017, 1, 1, 0, 0, 1, 0, time.UTC)
+	m, err := parse([]byte("<13>Dec 31 23:59:59 host1 app: hello"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Timestamp.Year() != 2016 {
+		t.Error(`m.Timestamp.Year() != 2016`)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Valid", testParseValid)
+	t.Run("Invalid", testParseInvalid)
+	t.Run("NewYear", testParseNewYear)
+}
@@ -0,0 +1,348 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	defaultNetwork  = "udp"
+	defaultSeverity = "warning"
+	defaultFrom     = "syslog"
+
+	maxMessageSize = 64 * 1024
+	maxTitleLength = 250
+)
+
+// source implements kkok.Source.
+type source struct {
+	network  string
+	address  string
+	severity int
+
+	origIf   string
+	ifScript *otto.Script
+}
+
+func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	switch s.network {
+	case "udp", "unixgram":
+		return s.runPacket(ctx, post)
+	}
+	return s.runStream(ctx, post)
+}
+
+func (s *source) removeSocket() {
+	if !strings.HasPrefix(s.network, "unix") {
+		return
+	}
+	fi, err := os.Lstat(s.address)
+	if err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(s.address)
+	}
+}
+
+func (s *source) runPacket(ctx context.Context, post func(*kkok.Alert)) error {
+	s.removeSocket()
+	conn, err := net.ListenPacket(s.network, s.address)
+	if err != nil {
+		return errors.Wrap(err, "syslog")
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "syslog")
+		}
+		s.handle(buf[:n], addr, post)
+	}
+}
+
+func (s *source) runStream(ctx context.Context, post func(*kkok.Alert)) error {
+	s.removeSocket()
+	l, err := net.Listen(s.network, s.address)
+	if err != nil {
+		return errors.Wrap(err, "syslog")
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		mu.Lock()
+		for c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+	}()
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "syslog")
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(conn, post)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// serveConn reads messages from a stream connection.
+// Messages are framed by octet counting or by LF (RFC6587).
+func (s *source) serveConn(conn net.Conn, post func(*kkok.Alert)) {
+	r := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		b, err := readFrame(r)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Warn("[syslog] failed to read a message", map[string]interface{}{
+				log.FnError: err.Error(),
+				"remote":    conn.RemoteAddr().String(),
+			})
+			return
+		}
+		s.handle(b, conn.RemoteAddr(), post)
+	}
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	c, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if c[0] < '0' || c[0] > '9' {
+		b, err := r.ReadSlice('\n')
+		if err == io.EOF && len(b) > 0 {
+			return b, nil
+		}
+		return b, err
+	}
+
+	l, err := r.ReadString(' ')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(l[:len(l)-1])
+	if err != nil || n > maxMessageSize {
+		return nil, errors.New("invalid message length: " + l)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *source) handle(b []byte, addr net.Addr, post func(*kkok.Alert)) {
+	m, err := parse(b, time.Now())
+	if err != nil {
+		log.Warn("[syslog] failed to parse a message", map[string]interface{}{
+			log.FnError: err.Error(),
+		})
+		return
+	}
+
+	if m.Severity > s.severity {
+		return
+	}
+
+	a := toAlert(m, addr)
+
+	if s.ifScript != nil {
+		v, err := kkok.NewVM().EvalAlert(a, s.ifScript)
+		if err != nil {
+			log.Error("[syslog] failed to evaluate if", map[string]interface{}{
+				log.FnError: err.Error(),
+				"if":        s.origIf,
+			})
+			return
+		}
+		if ok, _ := v.ToBoolean(); !ok {
+			return
+		}
+	}
+
+	err = a.Validate()
+	if err != nil {
+		log.Warn("[syslog] invalid alert", map[string]interface{}{
+			log.FnError: err.Error(),
+			"from":      a.From,
+			"host":      a.Host,
+		})
+		return
+	}
+
+	post(a)
+}
+
+// toAlert converts a syslog message into an alert.
+func toAlert(m *message, addr net.Addr) *kkok.Alert {
+	a := &kkok.Alert{
+		From:    m.AppName,
+		Date:    m.Timestamp,
+		Host:    m.Hostname,
+		Title:   title(m.Msg),
+		Message: m.Msg,
+		Info: map[string]interface{}{
+			"facility": facilityNames[m.Facility],
+			"severity": severityNames[m.Severity],
+		},
+	}
+
+	if len(a.From) == 0 {
+		a.From = defaultFrom
+	}
+	if a.Date.IsZero() {
+		a.Date = time.Now()
+	}
+	a.Date = a.Date.UTC()
+	if len(a.Host) == 0 && addr != nil {
+		if h, _, err := net.SplitHostPort(addr.String()); err == nil {
+			a.Host = h
+		}
+	}
+	if len(m.ProcID) > 0 {
+		a.Info["procid"] = m.ProcID
+	}
+	if len(m.MsgID) > 0 {
+		a.Info["msgid"] = m.MsgID
+	}
+	if len(m.SD) > 0 {
+		sd := make(map[string]interface{}, len(m.SD))
+		for id, params := range m.SD {
+			p := make(map[string]interface{}, len(params))
+			for k, v := range params {
+				p[k] = v
+			}
+			sd[id] = p
+		}
+		a.Info["sd"] = sd
+	}
+	return a
+}
+
+// title returns the first line of msg as a title.
+func title(msg string) string {
+	t := strings.TrimSpace(msg)
+	if idx := strings.IndexByte(t, '\n'); idx != -1 {
+		t = strings.TrimSpace(t[:idx])
+	}
+	if len(t) == 0 {
+		return "(empty message)"
+	}
+	if len(t) <= maxTitleLength {
+		return t
+	}
+	t = t[:maxTitleLength]
+	for !utf8.ValidString(t) {
+		t = t[:len(t)-1]
+	}
+	return t
+}
+
+func severityValue(name string) (int, error) {
+	for i, n := range severityNames {
+		if n == name {
+			return i, nil
+		}
+	}
+	return 0, errors.New("no such severity: " + name)
+}
+
+func ctor(params map[string]interface{}) (kkok.Source, error) {
+	s := &source{network: defaultNetwork}
+
+	switch network, err := util.GetString("network", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "syslog: network")
+	default:
+		s.network = network
+	}
+	switch s.network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, errors.New("syslog: unsupported network: " + s.network)
+	}
+
+	address, err := util.GetString("address", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "syslog: address")
+	}
+	s.address = address
+
+	severity := defaultSeverity
+	switch sv, err := util.GetString("severity", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "syslog: severity")
+	default:
+		severity = sv
+	}
+	s.severity, err = severityValue(severity)
+	if err != nil {
+		return nil, errors.Wrap(err, "syslog: severity")
+	}
+
+	switch expr, err := util.GetString("if", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "syslog: if")
+	default:
+		script, err := kkok.CompileJS(expr)
+		if err != nil {
+			return nil, errors.Wrap(err, "syslog: if")
+		}
+		s.origIf = expr
+		s.ifScript = script
+	}
+
+	return s, nil
+}
+
+func init() {
+	kkok.RegisterSource("syslog", ctor)
+}
@@ -0,0 +1,214 @@
+package syslog
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+func testSourceParams(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{})
+	if err == nil {
+		t.Error(`address is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"network": "sctp",
+		"address": ":10514",
+	})
+	if err == nil {
+		t.Error(`unsupported network is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"address":  ":10514",
+		"severity": "fatal",
+	})
+	if err == nil {
+		t.Error(`unknown severity is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"address": ":10514",
+		"if":      "alert.From ==",
+	})
+	if err == nil {
+		t.Error(`invalid if is accepted`)
+	}
+
+	src, err := ctor(map[string]interface{}{
+		"address": ":10514",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+	if s.network != "udp" {
+		t.Error(`s.network != "udp"`)
+	}
+	if s.severity != 4 {
+		t.Error(`s.severity != 4`)
+	}
+}
+
+type alertCollector struct {
+	mu     sync.Mutex
+	alerts []*kkok.Alert
+}
+
+func (c *alertCollector) post(a *kkok.Alert) {
+	c.mu.Lock()
+	c.alerts = append(c.alerts, a)
+	c.mu.Unlock()
+}
+
+func (c *alertCollector) wait(t *testing.T, n int) []*kkok.Alert {
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		if len(c.alerts) >= n {
+			l := c.alerts
+			c.mu.Unlock()
+			return l
+		}
+		c.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal(`timed out`)
+	return nil
+}
+
+func testSourceHandle(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"address":  ":10514",
+		"severity": "err",
+		"if":       "alert.Host != 'ignored'",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+
+	c := new(alertCollector)
+	addr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 514}
+	s.handle([]byte("<11>Feb  3 04:05:06 app[1]: error\nsecond line"), addr, c.post)
+	s.handle([]byte("<12>Feb  3 04:05:06 host1 app: warning"), addr, c.post)
+	s.handle([]byte("<11>Feb  3 04:05:06 ignored app: error"), addr, c.post)
+	s.handle([]byte("garbage"), addr, c.post)
+
+	if len(c.alerts) != 1 {
+		t.Fatal(`len(c.alerts) != 1`)
+	}
+	a := c.alerts[0]
+	if a.From != "app" {
+		t.Error(`a.From != "app"`)
+	}
+	if a.Host != "192.0.2.1" {
+		t.Error(`a.Host != "192.0.2.1"`)
+	}
+	if a.Title != "error" {
+		t.Error(`a.Title != "error"`)
+	}
+	if a.Message != "error\nsecond line" {
+		t.Error(`a.Message != "error\nsecond line"`)
+	}
+	if a.Info["severity"] != "err" {
+		t.Error(`a.Info["severity"] != "err"`)
+	}
+	if a.Info["procid"] != "1" {
+		t.Error(`a.Info["procid"] != "1"`)
+	}
+}
+
+func testSourceRunUDP(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"address": "127.0.0.1:15140",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := new(alertCollector)
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(ctx, c.post)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("udp", "127.0.0.1:15140")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("<11>1 - host1 app - - - udp message"))
+	conn.Close()
+
+	alerts := c.wait(t, 1)
+	if alerts[0].Title != "udp message" {
+		t.Error(`alerts[0].Title != "udp message"`)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func testSourceRunTCP(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"network": "tcp",
+		"address": "127.0.0.1:15141",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := new(alertCollector)
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(ctx, c.post)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:15141")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg1 := "<11>1 - host1 app - - - octet\ncounting"
+	conn.Write([]byte(strconv.Itoa(len(msg1)) + " " + msg1))
+	conn.Write([]byte("<11>1 - host1 app - - - non-transparent\n"))
+
+	alerts := c.wait(t, 2)
+	if alerts[0].Message != "octet\ncounting" {
+		t.Error(`alerts[0].Message != "octet\ncounting"`)
+	}
+	if alerts[1].Title != "non-transparent" {
+		t.Error(`alerts[1].Title != "non-transparent"`)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+	conn.Close()
+}
+
+func TestSource(t *testing.T) {
+	t.Run("Params", testSourceParams)
+	t.Run("Handle", testSourceHandle)
+	t.Run("Run/UDP", testSourceRunUDP)
+	t.Run("Run/TCP", testSourceRunTCP)
+}
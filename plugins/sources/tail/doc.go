@@ -0,0 +1,59 @@
+/*
+Package tail follows log files to generate alerts from matching lines.
+
+This plugin polls files at regular intervals and reads appended lines.
+When started, existing contents of the files are skipped.
+
+Rotated files are detected by comparing the file at the path with the
+file being read.  The rest of the rotated file is read, then the new
+file is read from the beginning.  If a file becomes smaller than the
+read offset, it is regarded as truncated and read from the beginning.
+
+Construction parameters:
+
+    Name        Type               Default       Description
+    files       []string                         Absolute paths of files to follow.
+    from        string             tail          "From" field of alerts.
+    interval    int                1             Polling interval (seconds).
+    rule        []table                          Rules to match lines.
+
+Each rule is a table with these parameters:
+
+    Name        Type               Default       Description
+    pattern     string                           Regular expression to match lines.
+    title       string                           Template of "Title" field.
+
+Rules are examined in order, and the first rule that matches a line
+generates an alert.  Lines that match none of the rules are ignored.
+The syntax of regular expressions is described in:
+https://golang.org/pkg/regexp/syntax/
+
+Example snippet for TOML configuration:
+
+    [[source]]
+    type     = "tail"
+    files    = ["/var/log/app/app.log"]
+    from     = "app"
+
+    [[source.rule]]
+    pattern  = 'ERROR \[(?P<component>\w+)\] (?P<detail>.*)'
+    title    = "error in ${component}"
+
+    [[source.rule]]
+    pattern  = 'panic:'
+
+Alerts are generated as follows:
+
+    Field       Value
+    From        "from" parameter.
+    Date        The current time.
+    Host        The host name of kkok.
+    Title       "title" expanded by regexp.Expand, or the line.
+    Message     The line.
+    Info        See below.
+
+Info has "file" member for the file path.  In addition, values of
+named capture groups become members of Info by their names.
+In the above example, Info.component and Info.detail will be set.
+*/
+package tail
@@ -0,0 +1,161 @@
+package tail
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+const (
+	maxLineLength = 64 * 1024
+	readBufSize   = 32 * 1024
+)
+
+// follower follows a file to read appended lines.
+//
+// Rotation is detected by comparing the file at the path with the
+// opened file.  The rest of a rotated file is read before the new
+// file is opened.  If the file gets smaller than the read offset,
+// it is regarded as truncated and read from the beginning.
+type follower struct {
+	path   string
+	file   *os.File
+	offset int64
+	buf    []byte
+}
+
+func newFollower(path string) *follower {
+	return &follower{path: path}
+}
+
+// open opens the file.  If end is true, existing contents are skipped.
+func (f *follower) open(end bool) error {
+	g, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if end {
+		offset, err = g.Seek(0, io.SeekEnd)
+		if err != nil {
+			g.Close()
+			return err
+		}
+	}
+	f.file = g
+	f.offset = offset
+	f.buf = nil
+	return nil
+}
+
+func (f *follower) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// read reads appended data and returns complete lines.
+func (f *follower) read() ([]string, error) {
+	var lines []string
+	data := make([]byte, readBufSize)
+	for {
+		n, err := f.file.Read(data)
+		if n > 0 {
+			f.offset += int64(n)
+			f.buf = append(f.buf, data[:n]...)
+			lines = f.split(lines)
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+func (f *follower) split(lines []string) []string {
+	for {
+		idx := bytes.IndexByte(f.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		line := bytes.TrimRight(f.buf[:idx], "\r")
+		lines = append(lines, string(line))
+		f.buf = f.buf[idx+1:]
+	}
+
+	// Too long lines are split forcibly.
+	for len(f.buf) >= maxLineLength {
+		lines = append(lines, string(f.buf[:maxLineLength]))
+		f.buf = f.buf[maxLineLength:]
+	}
+	if len(f.buf) == 0 {
+		f.buf = nil
+	}
+	return lines
+}
+
+// poll returns lines appended since the last poll.
+//
+// When the file is opened for the first time, existing contents
+// are skipped.
+func (f *follower) poll(first bool) ([]string, error) {
+	if f.file == nil {
+		err := f.open(first)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lines, err := f.read()
+	if err != nil {
+		f.close()
+		return lines, err
+	}
+
+	fi, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		// rotated, but a new file is not yet created.
+		return lines, nil
+	}
+	if err != nil {
+		return lines, err
+	}
+
+	cur, err := f.file.Stat()
+	if err != nil {
+		f.close()
+		return lines, err
+	}
+
+	switch {
+	case !os.SameFile(fi, cur):
+		// rotated.  Read the new file from the beginning.
+		f.close()
+		err = f.open(false)
+		if err != nil {
+			return lines, err
+		}
+		l, err := f.read()
+		return append(lines, l...), err
+
+	case fi.Size() < f.offset:
+		// truncated.
+		_, err = f.file.Seek(0, io.SeekStart)
+		if err != nil {
+			f.close()
+			return lines, err
+		}
+		f.offset = 0
+		f.buf = nil
+		l, err := f.read()
+		return append(lines, l...), err
+	}
+
+	return lines, nil
+}
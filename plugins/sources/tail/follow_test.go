@@ -0,0 +1,117 @@
+package tail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func appendFile(t *testing.T, fn, data string) {
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testPoll(t *testing.T, f *follower, first bool, expected []string) {
+	lines, err := f.poll(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected lines: %#v, expected: %#v", lines, expected)
+	}
+}
+
+func testFollowerAppend(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "test.log")
+	appendFile(t, fn, "existing\n")
+
+	f := newFollower(fn)
+	defer f.close()
+	testPoll(t, f, true, nil)
+
+	appendFile(t, fn, "line1\r\nline2\nparti")
+	testPoll(t, f, false, []string{"line1", "line2"})
+
+	appendFile(t, fn, "al\n")
+	testPoll(t, f, false, []string{"partial"})
+	testPoll(t, f, false, nil)
+}
+
+func testFollowerRotate(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "test.log")
+
+	f := newFollower(fn)
+	defer f.close()
+
+	// not exist yet
+	testPoll(t, f, true, nil)
+
+	appendFile(t, fn, "line1\n")
+	testPoll(t, f, false, []string{"line1"})
+
+	appendFile(t, fn, "line2\n")
+	err = os.Rename(fn, fn+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPoll(t, f, false, []string{"line2"})
+
+	appendFile(t, fn+".1", "line3\n")
+	appendFile(t, fn, "new1\n")
+	testPoll(t, f, false, []string{"line3", "new1"})
+
+	appendFile(t, fn, "new2\n")
+	testPoll(t, f, false, []string{"new2"})
+}
+
+func testFollowerTruncate(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "test.log")
+	appendFile(t, fn, "existing line\n")
+
+	f := newFollower(fn)
+	defer f.close()
+	testPoll(t, f, true, nil)
+
+	err = os.Truncate(fn, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, fn, "new\n")
+	testPoll(t, f, false, []string{"new"})
+}
+
+func TestFollower(t *testing.T) {
+	t.Run("Append", testFollowerAppend)
+	t.Run("Rotate", testFollowerRotate)
+	t.Run("Truncate", testFollowerTruncate)
+}
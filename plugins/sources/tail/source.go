@@ -0,0 +1,232 @@
+package tail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultInterval = 1 // seconds
+	defaultFrom     = "tail"
+
+	maxTitleLength = 250
+)
+
+// rule generates an alert from a line matching the pattern.
+type rule struct {
+	pattern *regexp.Regexp
+	title   string
+}
+
+// source implements kkok.Source.
+type source struct {
+	files    []string
+	from     string
+	host     string
+	interval time.Duration
+	rules    []rule
+}
+
+func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	followers := make([]*follower, len(s.files))
+	for i, fn := range s.files {
+		followers[i] = newFollower(fn)
+	}
+	defer func() {
+		for _, f := range followers {
+			f.close()
+		}
+	}()
+
+	first := true
+	for {
+		for _, f := range followers {
+			lines, err := f.poll(first)
+			if err != nil {
+				log.Error("[tail] failed to read", map[string]interface{}{
+					log.FnError: err.Error(),
+					"file":      f.path,
+				})
+			}
+			for _, line := range lines {
+				if a := s.match(f.path, line); a != nil {
+					post(a)
+				}
+			}
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// match returns an alert if line matches one of the rules.
+// Rules are examined in order, and the first matching rule is used.
+func (s *source) match(file, line string) *kkok.Alert {
+	for _, r := range s.rules {
+		m := r.pattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+
+		info := map[string]interface{}{
+			"file": file,
+		}
+		for i, name := range r.pattern.SubexpNames() {
+			if len(name) == 0 || m[2*i] < 0 {
+				continue
+			}
+			info[name] = line[m[2*i]:m[2*i+1]]
+		}
+
+		title := line
+		if len(r.title) > 0 {
+			title = string(r.pattern.ExpandString(nil, r.title, line, m))
+		}
+
+		return &kkok.Alert{
+			From:    s.from,
+			Date:    time.Now().UTC(),
+			Host:    s.host,
+			Title:   truncateTitle(title),
+			Message: line,
+			Info:    info,
+		}
+	}
+	return nil
+}
+
+func truncateTitle(t string) string {
+	t = strings.TrimSpace(t)
+	if len(t) == 0 {
+		return "(empty line)"
+	}
+	if len(t) <= maxTitleLength {
+		return t
+	}
+	t = t[:maxTitleLength]
+	for !utf8.ValidString(t) {
+		t = t[:len(t)-1]
+	}
+	return t
+}
+
+// getRules reads "rule" parameter, a list of tables.
+func getRules(params map[string]interface{}) ([]rule, error) {
+	var tables []map[string]interface{}
+	switch l := params["rule"].(type) {
+	case nil:
+		return nil, errors.New("no rules")
+	case []map[string]interface{}:
+		tables = l
+	case []interface{}:
+		for _, i := range l {
+			t, ok := i.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("not a table")
+			}
+			tables = append(tables, t)
+		}
+	default:
+		return nil, errors.New("not a list of tables")
+	}
+
+	if len(tables) == 0 {
+		return nil, errors.New("no rules")
+	}
+
+	rules := make([]rule, 0, len(tables))
+	for _, t := range tables {
+		pat, err := util.GetString("pattern", t)
+		if err != nil {
+			return nil, errors.Wrap(err, "pattern")
+		}
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, errors.Wrap(err, "pattern")
+		}
+
+		r := rule{pattern: re}
+		switch title, err := util.GetString("title", t); {
+		case util.IsNotFound(err):
+		case err != nil:
+			return nil, errors.Wrap(err, "title")
+		default:
+			r.title = title
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func ctor(params map[string]interface{}) (kkok.Source, error) {
+	files, err := util.GetStringSlice("files", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "tail: files")
+	}
+	if len(files) == 0 {
+		return nil, errors.New("tail: no files")
+	}
+	for _, fn := range files {
+		if !filepath.IsAbs(fn) {
+			return nil, errors.New("tail: not an absolute path: " + fn)
+		}
+	}
+
+	from := defaultFrom
+	switch f, err := util.GetString("from", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "tail: from")
+	default:
+		from = f
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "tail: hostname")
+	}
+
+	interval := time.Second * defaultInterval
+	switch i, err := util.GetInt("interval", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "tail: interval")
+	default:
+		interval = time.Duration(i) * time.Second
+	}
+	if interval <= 0 {
+		return nil, errors.New(`tail: invalid interval value`)
+	}
+
+	rules, err := getRules(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "tail: rule")
+	}
+
+	return &source{
+		files:    files,
+		from:     from,
+		host:     host,
+		interval: interval,
+		rules:    rules,
+	}, nil
+}
+
+func init() {
+	kkok.RegisterSource("tail", ctor)
+}
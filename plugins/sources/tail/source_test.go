@@ -0,0 +1,134 @@
+package tail
+
+import (
+	"testing"
+)
+
+func testSourceParams(t *testing.T) {
+	t.Parallel()
+
+	rules := []map[string]interface{}{
+		{"pattern": "ERROR"},
+	}
+
+	_, err := ctor(map[string]interface{}{
+		"rule": rules,
+	})
+	if err == nil {
+		t.Error(`files is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"files": []interface{}{"relative/path"},
+		"rule":  rules,
+	})
+	if err == nil {
+		t.Error(`relative path is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"files": []interface{}{"/var/log/test.log"},
+	})
+	if err == nil {
+		t.Error(`rule is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"files": []interface{}{"/var/log/test.log"},
+		"rule": []map[string]interface{}{
+			{"pattern": "(unclosed"},
+		},
+	})
+	if err == nil {
+		t.Error(`invalid pattern is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"files":    []interface{}{"/var/log/test.log"},
+		"rule":     rules,
+		"interval": 0,
+	})
+	if err == nil {
+		t.Error(`invalid interval is accepted`)
+	}
+
+	src, err := ctor(map[string]interface{}{
+		"files": []interface{}{"/var/log/test.log"},
+		"rule": []interface{}{
+			map[string]interface{}{"pattern": "ERROR"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+	if s.from != "tail" {
+		t.Error(`s.from != "tail"`)
+	}
+	if len(s.rules) != 1 {
+		t.Error(`len(s.rules) != 1`)
+	}
+}
+
+func testSourceMatch(t *testing.T) {
+	t.Parallel()
+
+	src, err := ctor(map[string]interface{}{
+		"files": []interface{}{"/var/log/test.log"},
+		"from":  "app",
+		"rule": []map[string]interface{}{
+			{
+				"pattern": `ERROR \[(?P<component>\w+)\] (?P<detail>.*)`,
+				"title":   "error in ${component}",
+			},
+			{"pattern": "panic:"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*source)
+
+	if s.match("/var/log/test.log", "INFO ok") != nil {
+		t.Error(`unmatched line generated an alert`)
+	}
+
+	a := s.match("/var/log/test.log", "2017-02-03 ERROR [db] connection lost")
+	if a == nil {
+		t.Fatal(`a == nil`)
+	}
+	if a.From != "app" {
+		t.Error(`a.From != "app"`)
+	}
+	if a.Title != "error in db" {
+		t.Error(`a.Title != "error in db"`)
+	}
+	if a.Message != "2017-02-03 ERROR [db] connection lost" {
+		t.Error(`a.Message != "2017-02-03 ERROR [db] connection lost"`)
+	}
+	if a.Info["component"] != "db" {
+		t.Error(`a.Info["component"] != "db"`)
+	}
+	if a.Info["detail"] != "connection lost" {
+		t.Error(`a.Info["detail"] != "connection lost"`)
+	}
+	if a.Info["file"] != "/var/log/test.log" {
+		t.Error(`a.Info["file"] != "/var/log/test.log"`)
+	}
+	if err := a.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	a = s.match("/var/log/test.log", "panic: runtime error")
+	if a == nil {
+		t.Fatal(`a == nil`)
+	}
+	if a.Title != "panic: runtime error" {
+		t.Error(`a.Title != "panic: runtime error"`)
+	}
+}
+
+func TestSource(t *testing.T) {
+	t.Run("Params", testSourceParams)
+	t.Run("Match", testSourceMatch)
+}
@@ -0,0 +1,65 @@
+/*
+Package webhook receives webhooks of arbitrary JSON to generate alerts.
+
+Each webhook source provides an end point /webhooks/PATH on kkok's
+API server.  The request body must be JSON.  It is converted into
+alerts by a JavaScript expression given as "mapping" parameter.
+
+Requests to webhooks do not need the API token.  Instead, requests
+are verified with "secret" by either of these methods:
+
+  * If "signature_header" is given, the header value must be the hex
+    encoded HMAC-SHA256 of the request body keyed by the secret.
+    A prefix like "sha256=" is ignored.
+  * Otherwise, the secret must be given as "X-Kkok-Webhook-Token"
+    header or "token" query parameter.
+
+"secret" is required.  To accept unauthenticated requests, omit it and
+set "insecure" to true explicitly.  A warning is logged at startup then.
+
+Construction parameters:
+
+    Name              Type      Default   Description
+    path              string              PATH of the end point.  Required.
+    mapping           string              JavaScript expression.  Required.
+    secret            string              Shared secret to verify requests.
+    signature_header  string              Header name of HMAC signatures.
+    insecure          bool      false     Accept requests without "secret".
+
+PATH must consist of alphanumeric characters, "-", and "_".
+"alertmanager" is reserved.
+
+The mapping expression can refer these variables:
+
+    Name        Description
+    payload     The parsed request body.
+    headers     An object of request headers.  Keys are canonicalized
+                like "Content-Type".
+
+The expression should evaluate to an object or an array of objects.
+Each object is converted into an alert just like the body of
+"POST /alerts" API.  If "From" is missing, PATH is used.
+null or undefined generates no alerts.
+
+The response is the same as "POST /alerts" with an array.
+
+Example snippet for TOML configuration:
+
+    [[source]]
+    type             = "webhook"
+    path             = "github"
+    secret           = "some secret"
+    signature_header = "X-Hub-Signature-256"
+    mapping          = '''
+    (function() {
+        if (headers["X-Github-Event"] !== "check_run") return null;
+        return {
+            From:    "github",
+            Host:    payload.repository.full_name,
+            Title:   payload.check_run.name + " " + payload.check_run.conclusion,
+            Message: payload.check_run.html_url,
+        };
+    })()
+    '''
+*/
+package webhook
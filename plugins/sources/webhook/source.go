@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	tokenHeader = "X-Kkok-Webhook-Token"
+	tokenQuery  = "token"
+)
+
+// source implements kkok.Source and kkok.Webhook.
+type source struct {
+	path            string
+	secret          string
+	signatureHeader string
+	insecure        bool
+	mapping         *otto.Script
+}
+
+func (s *source) Run(ctx context.Context, post func(*kkok.Alert)) error {
+	if len(s.secret) == 0 {
+		log.Warn("[webhook] requests are not authenticated", map[string]interface{}{
+			"path": s.path,
+		})
+	}
+
+	// Alerts are posted by the API server.
+	<-ctx.Done()
+	return nil
+}
+
+func (s *source) Path() string {
+	return s.path
+}
+
+func (s *source) Verify(r *http.Request, body []byte) error {
+	if len(s.secret) == 0 {
+		return nil
+	}
+
+	if len(s.signatureHeader) > 0 {
+		sig := r.Header.Get(s.signatureHeader)
+		if len(sig) == 0 {
+			return errors.New("no signature")
+		}
+		if idx := strings.IndexByte(sig, '='); idx != -1 {
+			sig = sig[idx+1:]
+		}
+		given, err := hex.DecodeString(sig)
+		if err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write(body)
+		if !hmac.Equal(given, mac.Sum(nil)) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+
+	token := r.Header.Get(tokenHeader)
+	if len(token) == 0 {
+		token = r.URL.Query().Get(tokenQuery)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.secret)) != 1 {
+		return errors.New("token mismatch")
+	}
+	return nil
+}
+
+func (s *source) Alerts(r *http.Request, body []byte) ([]*kkok.Alert, error) {
+	var payload interface{}
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: payload")
+	}
+
+	headers := make(map[string]interface{}, len(r.Header))
+	for k := range r.Header {
+		headers[k] = r.Header.Get(k)
+	}
+
+	vm := kkok.NewVM()
+	err = vm.Set("payload", payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook")
+	}
+	err = vm.Set("headers", headers)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook")
+	}
+	v, err := vm.Run(s.mapping)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+
+	return s.toAlerts(v)
+}
+
+// toAlerts converts the value of the mapping into alerts.
+// The value should be an object, an array of objects, or null.
+func (s *source) toAlerts(v otto.Value) ([]*kkok.Alert, error) {
+	if v.IsNull() || v.IsUndefined() {
+		return nil, nil
+	}
+
+	e, err := v.Export()
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+
+	var alerts []*kkok.Alert
+	if v.Class() == "Array" {
+		err = json.Unmarshal(data, &alerts)
+	} else {
+		a := new(kkok.Alert)
+		err = json.Unmarshal(data, a)
+		alerts = []*kkok.Alert{a}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+
+	for _, a := range alerts {
+		if a != nil && len(a.From) == 0 {
+			a.From = s.path
+		}
+	}
+	return alerts, nil
+}
+
+func ctor(params map[string]interface{}) (kkok.Source, error) {
+	path, err := util.GetString("path", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: path")
+	}
+
+	mapping, err := util.GetString("mapping", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+	script, err := kkok.CompileJS(mapping)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: mapping")
+	}
+
+	s := &source{
+		path:    path,
+		mapping: script,
+	}
+
+	switch secret, err := util.GetString("secret", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "webhook: secret")
+	default:
+		s.secret = secret
+	}
+
+	insecure, err := util.GetBool("insecure", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: insecure")
+	}
+	s.insecure = insecure
+	if len(s.secret) == 0 && !insecure {
+		return nil, errors.New("webhook: secret is required unless insecure is true")
+	}
+
+	switch h, err := util.GetString("signature_header", params); {
+	case util.IsNotFound(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "webhook: signature_header")
+	default:
+		if len(s.secret) == 0 {
+			return nil, errors.New("webhook: signature_header requires secret")
+		}
+		s.signatureHeader = h
+	}
+
+	return s, nil
+}
+
+func init() {
+	kkok.RegisterSource("webhook", ctor)
+}
@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newSource(t *testing.T, params map[string]interface{}) *source {
+	src, err := ctor(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src.(*source)
+}
+
+func testSourceParams(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(map[string]interface{}{
+		"mapping": "payload",
+	})
+	if err == nil {
+		t.Error(`path is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"path": "test",
+	})
+	if err == nil {
+		t.Error(`mapping is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"path":    "test",
+		"mapping": "payload.",
+	})
+	if err == nil {
+		t.Error(`invalid mapping is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"path":             "test",
+		"mapping":          "payload",
+		"signature_header": "X-Signature",
+	})
+	if err == nil {
+		t.Error(`signature_header without secret is accepted`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"path":    "test",
+		"mapping": "payload",
+	})
+	if err == nil {
+		t.Error(`secret is not required`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"path":     "test",
+		"mapping":  "payload",
+		"insecure": "true",
+	})
+	if err == nil {
+		t.Error(`invalid insecure is accepted`)
+	}
+
+	s := newSource(t, map[string]interface{}{
+		"path":    "test",
+		"mapping": "payload",
+		"secret":  "secret1",
+	})
+	if s.Path() != "test" {
+		t.Error(`s.Path() != "test"`)
+	}
+}
+
+func testSourceVerifyToken(t *testing.T) {
+	t.Parallel()
+
+	s := newSource(t, map[string]interface{}{
+		"path":    "test",
+		"mapping": "payload",
+		"secret":  "secret1",
+	})
+
+	r := httptest.NewRequest("POST", "http://localhost/webhooks/test", nil)
+	if s.Verify(r, nil) == nil {
+		t.Error(`request without token is accepted`)
+	}
+
+	r.Header.Set("X-Kkok-Webhook-Token", "wrong")
+	if s.Verify(r, nil) == nil {
+		t.Error(`wrong token is accepted`)
+	}
+
+	r.Header.Set("X-Kkok-Webhook-Token", "secret1")
+	if err := s.Verify(r, nil); err != nil {
+		t.Error(err)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/webhooks/test?token=secret1", nil)
+	if err := s.Verify(r, nil); err != nil {
+		t.Error(err)
+	}
+
+	s = newSource(t, map[string]interface{}{
+		"path":     "test",
+		"mapping":  "payload",
+		"insecure": true,
+	})
+	r = httptest.NewRequest("POST", "http://localhost/webhooks/test", nil)
+	if err := s.Verify(r, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func testSourceVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	s := newSource(t, map[string]interface{}{
+		"path":             "test",
+		"mapping":          "payload",
+		"secret":           "secret1",
+		"signature_header": "X-Hub-Signature-256",
+	})
+
+	body := []byte(`{"a": 1}`)
+	mac := hmac.New(sha256.New, []byte("secret1"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	r := httptest.NewRequest("POST", "http://localhost/webhooks/test", nil)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+sig)
+	if err := s.Verify(r, body); err != nil {
+		t.Error(err)
+	}
+
+	r.Header.Set("X-Hub-Signature-256", sig)
+	if err := s.Verify(r, body); err != nil {
+		t.Error(err)
+	}
+
+	if s.Verify(r, []byte(`{"a": 2}`)) == nil {
+		t.Error(`signature for another body is accepted`)
+	}
+
+	r.Header.Del("X-Hub-Signature-256")
+	r.Header.Set("X-Kkok-Webhook-Token", "secret1")
+	if s.Verify(r, body) == nil {
+		t.Error(`request without signature is accepted`)
+	}
+}
+
+func testSourceAlerts(t *testing.T) {
+	t.Parallel()
+
+	s := newSource(t, map[string]interface{}{
+		"path":     "saas",
+		"insecure": true,
+		"mapping": `payload.events.map(function(e) {
+    return {Title: e.name, Host: headers["X-Source"], Info: {level: e.level}};
+})`,
+	})
+
+	r := httptest.NewRequest("POST", "http://localhost/webhooks/saas", nil)
+	r.Header.Set("X-Source", "host1")
+	body := []byte(`{"events": [{"name": "e1", "level": 3}, {"name": "e2", "level": 1}]}`)
+	alerts, err := s.Alerts(r, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	a := alerts[0]
+	if a.From != "saas" {
+		t.Error(`a.From != "saas"`)
+	}
+	if a.Title != "e1" {
+		t.Error(`a.Title != "e1"`)
+	}
+	if a.Host != "host1" {
+		t.Error(`a.Host != "host1"`)
+	}
+	if a.Info["level"] != 3.0 {
+		t.Error(`a.Info["level"] != 3.0`)
+	}
+	if alerts[1].Title != "e2" {
+		t.Error(`alerts[1].Title != "e2"`)
+	}
+
+	s = newSource(t, map[string]interface{}{
+		"path":     "saas",
+		"insecure": true,
+		"mapping":  `payload.ok ? null : {From: "f", Title: payload.title}`,
+	})
+	alerts, err = s.Alerts(r, []byte(`{"ok": false, "title": "down"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].From != "f" || alerts[0].Title != "down" {
+		t.Error(`len(alerts) != 1 || alerts[0].From != "f" || alerts[0].Title != "down"`)
+	}
+
+	alerts, err = s.Alerts(r, []byte(`{"ok": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Error(`len(alerts) != 0`)
+	}
+
+	_, err = s.Alerts(r, []byte(`not json`))
+	if err == nil {
+		t.Error(`invalid JSON is accepted`)
+	}
+
+	s = newSource(t, map[string]interface{}{
+		"path":     "saas",
+		"insecure": true,
+		"mapping":  `payload.no.such.field`,
+	})
+	_, err = s.Alerts(r, []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "mapping") {
+		t.Error(`mapping error is not reported`)
+	}
+}
+
+func TestSource(t *testing.T) {
+	t.Run("Params", testSourceParams)
+	t.Run("Verify/Token", testSourceVerifyToken)
+	t.Run("Verify/Signature", testSourceVerifySignature)
+	t.Run("Alerts", testSourceAlerts)
+}
@@ -7,4 +7,5 @@ import (
 	_ "github.com/cybozu-go/kkok/plugins/transports/exec"
 	_ "github.com/cybozu-go/kkok/plugins/transports/slack"
 	_ "github.com/cybozu-go/kkok/plugins/transports/twilio"
+	_ "github.com/cybozu-go/kkok/plugins/transports/webhook"
 )
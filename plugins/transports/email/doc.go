@@ -6,8 +6,12 @@ plain text using text/template package.  The default template is
 built-in as DefaultTemplate.
 
 "Subject" header value will be the alert's Title field value.
+For resolved alerts, it is prefixed with "[RESOLVED] ".
 "Date" header value will be the alert's Date field value.
 
+Delivery is aborted when the context given to DeliverContext is done,
+including while connecting to the SMTP server.
+
 The plugin takes these construction parameters:
 
     Name      Type        Default     Description
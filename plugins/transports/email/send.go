@@ -0,0 +1,120 @@
+package email
+
+import (
+	"context"
+	"sync"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/log"
+)
+
+// delivery is the progress of sending mails for alerts.
+//
+// gomail.Dialer and SendCloser do not take a context.  To return
+// soon after a context is done, DeliverContext sends mails in another
+// goroutine and reads the progress of the delivery.
+type delivery struct {
+	mu      sync.Mutex
+	rest    []*kkok.Alert
+	failed  []*kkok.Alert
+	lastErr error
+}
+
+// next returns the alert to be sent next, or nil if no alerts remain.
+func (d *delivery) next() *kkok.Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.rest) == 0 {
+		return nil
+	}
+	return d.rest[0]
+}
+
+// done records the result of sending the next alert.
+func (d *delivery) done(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		d.failed = append(d.failed, d.rest[0])
+		d.lastErr = err
+	}
+	d.rest = d.rest[1:]
+}
+
+// fail records err that prevents the rest of alerts from being sent.
+func (d *delivery) fail(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastErr = err
+}
+
+// undelivered returns alerts that have failed or not been sent yet,
+// and the last error.
+func (d *delivery) undelivered() ([]*kkok.Alert, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	alerts := make([]*kkok.Alert, 0, len(d.failed)+len(d.rest))
+	alerts = append(alerts, d.failed...)
+	alerts = append(alerts, d.rest...)
+	return alerts, d.lastErr
+}
+
+// send sends mails for alerts in d through an SMTP server dialed by dialer.
+// The connection is closed when ctx is done so that no more mails are sent.
+func (t *transport) send(ctx context.Context, d *delivery, dialer *gomail.Dialer, to, cc, bcc []string) {
+	s, err := dialer.Dial()
+	if err != nil {
+		d.fail(err)
+		return
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		s.Close()
+	}()
+
+	for {
+		a := d.next()
+		if a == nil || ctx.Err() != nil {
+			return
+		}
+
+		m, err := t.compose(a, to, cc, bcc)
+		if err == nil {
+			err = gomail.Send(s, m)
+		}
+		fields := map[string]interface{}{
+			"transport": transportType,
+			"from":      a.From,
+			"title":     a.Title,
+			"host":      a.Host,
+		}
+		if len(to) > 0 {
+			fields["to"] = to
+		}
+		if len(cc) > 0 {
+			fields["cc"] = cc
+		}
+		if len(bcc) > 0 {
+			fields["bcc"] = bcc
+		}
+		if err == nil {
+			log.Info("sent a mail", fields)
+		} else {
+			fields[log.FnError] = err.Error()
+			log.Error("failed to send a mail", fields)
+		}
+		d.done(err)
+	}
+}
@@ -0,0 +1,167 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/pkg/errors"
+)
+
+// testSMTPServer is a minimal SMTP server.
+// If hang is true, the server never responds.
+type testSMTPServer struct {
+	l    net.Listener
+	hang bool
+
+	mu    sync.Mutex
+	rcpts []string
+	mails int
+}
+
+func newTestSMTPServer(t *testing.T, hang bool) *testSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSMTPServer{l: l, hang: hang}
+	go s.serve()
+	return s
+}
+
+func (s *testSMTPServer) port() int {
+	return s.l.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSMTPServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	if s.hang {
+		r.ReadString('\n')
+		return
+	}
+
+	reply := func(l string) {
+		conn.Write([]byte(l + "\r\n"))
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(l))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.TrimSpace(l[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+			}
+			s.mu.Lock()
+			s.mails++
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Run("Success", testSendSuccess)
+	t.Run("Cancel", testSendCancel)
+}
+
+func testSendSuccess(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSMTPServer(t, false)
+	defer s.l.Close()
+
+	tr := &transport{
+		from: "foo@example.com",
+		to:   []string{"kkok@example.org"},
+		bcc:  []string{"bar@example.org"},
+		host: "127.0.0.1",
+		port: s.port(),
+	}
+	alerts := []*kkok.Alert{
+		{From: "from1", Title: "title1", Host: "host1"},
+		{From: "from2", Title: "title2", Host: "host2"},
+	}
+	err := tr.DeliverContext(context.Background(), alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mails != 2 {
+		t.Error(`s.mails != 2: ` + strconv.Itoa(s.mails))
+	}
+	if len(s.rcpts) != 4 {
+		t.Error(`len(s.rcpts) != 4`, s.rcpts)
+	}
+}
+
+func testSendCancel(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSMTPServer(t, true)
+	defer s.l.Close()
+
+	tr := &transport{
+		from: "foo@example.com",
+		to:   []string{"kkok@example.org"},
+		host: "127.0.0.1",
+		port: s.port(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	now := time.Now()
+	err := tr.DeliverContext(ctx, []*kkok.Alert{{Title: "title"}})
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Error(`errors.Cause(err) != context.DeadlineExceeded`, err)
+	}
+	if time.Now().Sub(now) > time.Second {
+		t.Error(`delivery was not cancelled`)
+	}
+}
@@ -12,6 +12,8 @@ From: {{.From}}
 Date: {{.Date.UTC.Format "2006-01-02T15:04:05.999999999Z07:00"}}
 Host: {{.Host}}
 Title: {{.Title}}
+{{if .Resolved}}Status: RESOLVED
+{{end -}}
 {{if .Message}}
 {{.Message -}}
 {{end -}}
@@ -3,13 +3,13 @@ package email
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"os"
 	"text/template"
 
 	gomail "gopkg.in/gomail.v2"
 
 	"github.com/cybozu-go/kkok"
-	"github.com/cybozu-go/log"
 	"github.com/pkg/errors"
 )
 
@@ -20,6 +20,8 @@ const (
 	defaultPort = 25
 
 	mailer = "kkok " + kkok.Version
+
+	resolvedPrefix = "[RESOLVED] "
 )
 
 type transport struct {
@@ -145,7 +147,11 @@ func (t *transport) compose(alert *kkok.Alert, to, cc, bcc []string) (*gomail.Me
 	if len(bcc) > 0 {
 		m.SetHeader("Bcc", bcc...)
 	}
-	m.SetHeader("Subject", alert.Title)
+	subject := alert.Title
+	if alert.Resolved() {
+		subject = resolvedPrefix + subject
+	}
+	m.SetHeader("Subject", subject)
 	m.SetDateHeader("Date", alert.Date)
 	m.SetHeader("X-Mailer", mailer)
 	m.SetBody("text/plain", buf.String())
@@ -153,6 +159,12 @@ func (t *transport) compose(alert *kkok.Alert, to, cc, bcc []string) (*gomail.Me
 }
 
 func (t *transport) Deliver(alerts []*kkok.Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+// DeliverContext sends a mail for each alert.  If ctx is done,
+// this returns soon and alerts not sent yet are undelivered.
+func (t *transport) DeliverContext(ctx context.Context, alerts []*kkok.Alert) error {
 	to, err := getAddressList(t.to, t.toFile)
 	if err != nil {
 		return errors.Wrap(err, transportType)
@@ -178,41 +190,32 @@ func (t *transport) Deliver(alerts []*kkok.Alert) error {
 	if port == 0 {
 		port = defaultPort
 	}
-	s, err := gomail.NewDialer(host, port, t.username, t.password).Dial()
-	if err != nil {
-		return errors.Wrap(err, transportType)
+
+	d := &delivery{rest: alerts}
+	done := make(chan struct{})
+	go func() {
+		t.send(ctx, d, gomail.NewDialer(host, port, t.username, t.password), to, cc, bcc)
+		close(done)
+	}()
+
+	var ctxErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
 	}
-	defer s.Close()
 
-	for _, a := range alerts {
-		m, err := t.compose(a, to, cc, bcc)
-		if err != nil {
+	failed, lastErr := d.undelivered()
+	if ctxErr != nil {
+		lastErr = ctxErr
+	}
+	if len(failed) > 0 {
+		err = errors.Wrapf(lastErr, "%s: failed to send %d of %d mails",
+			transportType, len(failed), len(alerts))
+		if len(failed) == len(alerts) {
 			return err
 		}
-
-		err = gomail.Send(s, m)
-		fields := map[string]interface{}{
-			"transport": transportType,
-			"from":      a.From,
-			"title":     a.Title,
-			"host":      a.Host,
-		}
-		if len(to) > 0 {
-			fields["to"] = to
-		}
-		if len(cc) > 0 {
-			fields["cc"] = cc
-		}
-		if len(bcc) > 0 {
-			fields["bcc"] = bcc
-		}
-		if err == nil {
-			log.Info("sent a mail", fields)
-		} else {
-			fields[log.FnError] = err.Error()
-			log.Error("failed to send a mail", fields)
-		}
+		return &kkok.PartialError{Err: err, Undelivered: failed}
 	}
-
 	return nil
 }
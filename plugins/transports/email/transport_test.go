@@ -261,6 +261,34 @@ func testCompose(t *testing.T) {
 	t.Run("Bcc", func(t *testing.T) {
 		testComposeOne(t, nil, nil, []string{"foo", "bar", "zot"})
 	})
+	t.Run("Resolved", testComposeResolved)
+}
+
+func testComposeResolved(t *testing.T) {
+	t.Parallel()
+
+	tr := &transport{
+		from: "foo@example.com",
+	}
+	a := &kkok.Alert{
+		From:   "test monitor",
+		Date:   time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC),
+		Host:   "host1",
+		Title:  "test test",
+		Status: kkok.StatusResolved,
+	}
+
+	m, err := tr.compose(a, []string{"foo"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hSubject := m.GetHeader("Subject")
+	if len(hSubject) != 1 {
+		t.Fatal(`len(hSubject) != 1`)
+	}
+	if hSubject[0] != "[RESOLVED] test test" {
+		t.Error(`hSubject[0] != "[RESOLVED] test test"`)
+	}
 }
 
 func testDeliver(t *testing.T) {
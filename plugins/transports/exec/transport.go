@@ -63,8 +63,7 @@ func (t *transport) Params() kkok.PluginParams {
 	}
 }
 
-func (t *transport) exec(j []byte) error {
-	ctx := context.Background()
+func (t *transport) exec(ctx context.Context, j []byte) error {
 	if t.timeout != 0 {
 		ctx2, cancel := context.WithTimeout(ctx, t.timeout)
 		ctx = ctx2
@@ -77,26 +76,33 @@ func (t *transport) exec(j []byte) error {
 }
 
 func (t *transport) Deliver(alerts []*kkok.Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+func (t *transport) DeliverContext(ctx context.Context, alerts []*kkok.Alert) error {
 	if t.all {
 		data, err := json.Marshal(alerts)
 		if err != nil {
 			return errors.Wrap(err, transportType)
 		}
-		err = t.exec(data)
+		err = t.exec(ctx, data)
 		if err != nil {
 			return errors.Wrap(err, transportType)
 		}
 		return nil
 	}
 
-	for _, a := range alerts {
+	for i, a := range alerts {
 		data, err := json.Marshal(a)
-		if err != nil {
-			return errors.Wrap(err, transportType)
+		if err == nil {
+			err = t.exec(ctx, data)
 		}
-		err = t.exec(data)
 		if err != nil {
-			return errors.Wrap(err, transportType)
+			err = errors.Wrap(err, transportType)
+			if i == 0 {
+				return err
+			}
+			return &kkok.PartialError{Err: err, Undelivered: alerts[i:]}
 		}
 	}
 
@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -75,7 +76,9 @@ func testDeliver(t *testing.T) {
 	t.Run("One", testDeliverOne)
 	t.Run("All", testDeliverAll)
 	t.Run("Timeout", testDeliverTimeout)
+	t.Run("Context", testDeliverContext)
 	t.Run("Error", testDeliverError)
+	t.Run("Partial", testDeliverPartial)
 }
 
 type testHandler struct {
@@ -224,6 +227,34 @@ func testDeliverTimeout(t *testing.T) {
 	}
 }
 
+func testDeliverContext(t *testing.T) {
+	t.Parallel()
+
+	a := &kkok.Alert{
+		From:    "from",
+		Title:   "title",
+		Date:    time.Date(2011, 2, 3, 4, 5, 6, 123456000, time.UTC),
+		Host:    "localhost",
+		Message: "msg",
+		Info: map[string]interface{}{
+			"info1": true,
+		},
+	}
+	h := &testHandler{
+		expectAlert: a,
+		wait:        200 * time.Millisecond,
+	}
+	tr, serv := testSetup(h)
+	defer serv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := tr.DeliverContext(ctx, []*kkok.Alert{a, a})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
 func testDeliverError(t *testing.T) {
 	t.Parallel()
 
@@ -249,3 +280,37 @@ func testDeliverError(t *testing.T) {
 		t.Error(`err == nil`)
 	}
 }
+
+func testDeliverPartial(t *testing.T) {
+	t.Parallel()
+
+	a := &kkok.Alert{
+		From:  "from",
+		Title: "title",
+		Date:  time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC),
+		Host:  "localhost",
+	}
+	b := &kkok.Alert{
+		From:  "from",
+		Title: "another",
+		Date:  time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC),
+		Host:  "localhost",
+	}
+	h := &testHandler{
+		expectAlert: a,
+	}
+	tr, serv := testSetup(h)
+	defer serv.Close()
+
+	err := tr.Deliver([]*kkok.Alert{a, b, a})
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+	pe, ok := err.(*kkok.PartialError)
+	if !ok {
+		t.Fatal(`!ok`, err)
+	}
+	if !reflect.DeepEqual(pe.Undelivered, []*kkok.Alert{b, a}) {
+		t.Error(`!reflect.DeepEqual(pe.Undelivered, []*kkok.Alert{b, a})`)
+	}
+}
@@ -5,7 +5,7 @@ import (
 
 	"github.com/cybozu-go/kkok"
 	"github.com/cybozu-go/kkok/util"
-	"github.com/cybozu-go/well"
+	"github.com/cybozu-go/log"
 	"github.com/pkg/errors"
 )
 
@@ -21,8 +21,7 @@ func ctor(params map[string]interface{}) (kkok.Transport, error) {
 	}
 
 	tr := &transport{
-		url:      u,
-		maxRetry: defaultRetry,
+		url: u,
 	}
 
 	label, err := util.GetString("label", params)
@@ -31,15 +30,6 @@ func ctor(params map[string]interface{}) (kkok.Transport, error) {
 	}
 	tr.label = label
 
-	maxRetry, err := util.GetInt("max_retry", params)
-	switch {
-	case err == nil:
-		tr.maxRetry = maxRetry
-	case util.IsNotFound(err):
-	default:
-		return nil, errors.Wrap(err, "slack: max_retry")
-	}
-
 	name, err := util.GetString("name", params)
 	if err != nil && !util.IsNotFound(err) {
 		return nil, errors.Wrap(err, "slack: name")
@@ -86,10 +76,16 @@ func ctor(params map[string]interface{}) (kkok.Transport, error) {
 		return nil, errors.Wrap(err, "slack: template")
 	}
 
+	if _, ok := params["max_retry"]; ok {
+		// max_retry was removed as kkok retries failed deliveries.
+		log.Warn("[slack] max_retry is no longer supported and ignored", map[string]interface{}{
+			"transport": tr.String(),
+		})
+	}
+
 	return tr, nil
 }
 
 func init() {
 	kkok.RegisterTransport(transportType, ctor)
-	well.Go(dequeueAndSend)
 }
@@ -14,75 +14,51 @@ var ctorTestData = map[string]ctorTest{
 	"tr1": {nil, nil},
 	"tr2": {map[string]interface{}{
 		"url": "https://slack.com/foo/bar",
-	}, &transport{
-		maxRetry: defaultRetry,
-	}},
+	}, &transport{}},
 	"tr3": {map[string]interface{}{"url": 3.14}, nil},
 	"tr4": {map[string]interface{}{"url": "hoge"}, nil},
 	"tr5": {map[string]interface{}{
 		"url":   "https://slack.com/foo/bar",
 		"label": "label1",
 	}, &transport{
-		label:    "label1",
-		maxRetry: defaultRetry,
+		label: "label1",
 	}},
 	"tr6": {map[string]interface{}{
-		"url":       "https://slack.com/foo/bar",
-		"max_retry": 0,
-	}, &transport{
-		maxRetry: 0,
-	}},
-	"tr7": {map[string]interface{}{
-		"url":       "https://slack.com/foo/bar",
-		"max_retry": 100,
-	}, &transport{
-		maxRetry: 100,
-	}},
-	"tr8": {map[string]interface{}{
-		"url":       "https://slack.com/foo/bar",
-		"max_retry": "100",
-	}, nil},
-	"tr9": {map[string]interface{}{
 		"url":  "https://slack.com/foo/bar",
 		"name": "test",
 	}, &transport{
-		maxRetry: defaultRetry,
-		name:     "test",
+		name: "test",
 	}},
-	"tr10": {map[string]interface{}{
+	"tr7": {map[string]interface{}{
 		"url":  "https://slack.com/foo/bar",
 		"icon": ":sushi:",
 	}, &transport{
-		maxRetry: defaultRetry,
-		icon:     ":sushi:",
+		icon: ":sushi:",
 	}},
-	"tr11": {map[string]interface{}{
+	"tr8": {map[string]interface{}{
 		"url":     "https://slack.com/foo/bar",
 		"channel": "#random",
 	}, &transport{
-		maxRetry: defaultRetry,
-		channel:  "#random",
+		channel: "#random",
 	}},
-	"tr12": {map[string]interface{}{
+	"tr9": {map[string]interface{}{
 		"url":   "https://slack.com/foo/bar",
 		"color": "'",
 	}, nil},
-	"tr13": {map[string]interface{}{
+	"tr10": {map[string]interface{}{
 		"url":   "https://slack.com/foo/bar",
 		"color": "alert.Info.severity",
 	}, &transport{
-		maxRetry:  defaultRetry,
 		origColor: "alert.Info.severity",
 	}},
-	"tr14": {map[string]interface{}{
+	"tr11": {map[string]interface{}{
 		"url":      "https://slack.com/foo/bar",
 		"template": "/template/not/exist",
 	}, nil},
-	"tr15": {map[string]interface{}{
+	"tr12": {map[string]interface{}{
 		"url":      "https://slack.com/foo/bar",
 		"template": "testdata/1.txt",
 	}, &transport{
-		maxRetry: defaultRetry,
 		tmplPath: "testdata/1.txt",
 	}},
 }
@@ -12,7 +12,6 @@ The plugin takes these construction parameters:
     Name       Type        Default     Description
     label      string      ""          Arbitrary string label.
     url        string                  Incoming webhook URL.  Required.
-    max_retry  int         3           Max retry count when server returns 500.
     name       string      ""          Customize the user name.
     icon       string      ""          Customize the user icon.  Emoji only.
     channel    string      ""          Override the default channel.
@@ -33,6 +32,15 @@ The template must be written for text/template package.  To escape
 special characters, the template provides a non-standard function "slack"
 to escape strings for Slack.
 
+Messages are sent one by one at intervals of slightly more than a second
+to comply with Slack's rate limits.  Deliver returns an error if Slack
+does not accept a message; failed deliveries are retried by kkok's retry
+policy and fallback transports, not by this plugin.  The time to wait
+for the rate limits is added to kkok's delivery_timeout.
+
+Titles of resolved alerts are prefixed with "[RESOLVED] ".
+Templates can check it by {{if .Resolved}}...{{end}}.
+
 Example snippet for TOML configuration:
 
     [[route.notify]]
@@ -6,71 +6,76 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 	"time"
 
 	"github.com/cybozu-go/log"
 	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
 )
 
 const (
-	queueSize = 100
-
 	// See https://api.slack.com/docs/rate-limits
 	slackAPIDuration = 1100 * time.Millisecond
 
 	slackAPITimeout = 5 * time.Second
+
+	// maxErrorBody limits the response body to be logged.
+	maxErrorBody = 1024
 )
 
 var (
-	sendCh     = make(chan *slackMessage, queueSize)
 	httpClient = &well.HTTPClient{
 		Client:   &http.Client{},
 		Severity: log.LvDebug,
 	}
+
+	defaultSender = newSender(slackAPIDuration)
 )
 
-func enqueue(m *slackMessage) bool {
-	select {
-	case sendCh <- m:
-		return true
-	default:
-		return false
-	}
+// sender posts messages to Slack one by one complying its rate limits.
+type sender struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	// protected by sem
+	last time.Time
 }
 
-type slackMessage struct {
-	url      *url.URL
-	maxRetry int
-	payload  []byte
+func newSender(interval time.Duration) *sender {
+	return &sender{
+		sem:      make(chan struct{}, 1),
+		interval: interval,
+	}
 }
 
-func (m *slackMessage) wait(ctx context.Context, duration time.Duration) bool {
+func wait(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
 	select {
 	case <-ctx.Done():
-		return false
+		return ctx.Err()
 	case <-time.After(duration):
-		return true
+		return nil
 	}
 }
 
-func (m *slackMessage) do(ctx context.Context) (*http.Response, []byte, error) {
+func do(ctx context.Context, u *url.URL, payload []byte) (*http.Response, []byte, error) {
 	header := make(http.Header)
 	header.Set("Content-Type", "application/json")
 	req := &http.Request{
 		Method:        "POST",
-		URL:           m.url,
+		URL:           u,
 		Proto:         "HTTP/1.1",
 		ProtoMajor:    1,
 		ProtoMinor:    1,
 		Header:        header,
-		Body:          ioutil.NopCloser(bytes.NewReader(m.payload)),
-		ContentLength: int64(len(m.payload)),
-		Host:          m.url.Host,
+		Body:          ioutil.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Host:          u.Host,
 	}
 
-	// use context.Background to send alerts gracefully.
-	ctx, cancel := context.WithTimeout(context.Background(), slackAPITimeout)
+	ctx, cancel := context.WithTimeout(ctx, slackAPITimeout)
 	defer cancel()
 
 	resp, err := httpClient.Do(req.WithContext(ctx))
@@ -85,83 +90,51 @@ func (m *slackMessage) do(ctx context.Context) (*http.Response, []byte, error) {
 	return resp, data, nil
 }
 
-func (m *slackMessage) send(ctx context.Context) bool {
-	var retries int
+// post sends payload to u.  An error is returned unless Slack accepts it.
+// Failed requests are not retried here; kkok retries deliveries.
+func (s *sender) post(ctx context.Context, u *url.URL, payload []byte) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		<-s.sem
+	}()
 
-RETRY:
-	resp, body, err := m.do(ctx)
+	err := wait(ctx, s.last.Add(s.interval).Sub(time.Now()))
 	if err != nil {
-		log.Error("[slack] do", map[string]interface{}{
-			log.FnError: err.Error(),
-			log.FnURL:   m.url.String(),
-		})
-		if retries < m.maxRetry {
-			retries++
-			if m.wait(ctx, slackAPIDuration) {
-				goto RETRY
-			}
-		}
-		log.Error("[slack] gave up", nil)
-		return false
+		return err
 	}
 
-	sleepDuration := slackAPIDuration
+	resp, body, err := do(ctx, u, payload)
+	s.last = time.Now()
+	if err != nil {
+		return err
+	}
 
 	switch {
 	case (200 <= resp.StatusCode) && (resp.StatusCode < 300):
 		log.Info("[slack] sent alerts", nil)
-		return true
-
-	case resp.StatusCode == 429:
-		// rate limit exceeded
-		log.Warn("[slack] rate limit exceeds", nil)
-		ssec := resp.Header.Get("Retry-After")
-		if len(ssec) > 0 {
-			sec, err := strconv.Atoi(ssec)
-			if err == nil {
-				sleepDuration = time.Duration(sec) * time.Second
-			}
-		}
+		return nil
 
-	case resp.StatusCode >= 500:
-		// temporary server failure, hopefully.
-		log.Error("[slack] failed to send", map[string]interface{}{
-			log.FnURL:            m.url.String(),
-			log.FnHTTPStatusCode: resp.StatusCode,
+	case resp.StatusCode == http.StatusTooManyRequests:
+		log.Warn("[slack] rate limit exceeds", map[string]interface{}{
+			"retry_after": resp.Header.Get("Retry-After"),
 		})
 
 	default:
-		// mainly because the request was bad.
 		fields := map[string]interface{}{
-			log.FnURL:            m.url,
+			log.FnURL:            u.String(),
 			log.FnHTTPStatusCode: resp.StatusCode,
 		}
+		if len(body) > maxErrorBody {
+			body = body[:maxErrorBody]
+		}
 		if len(body) > 0 {
 			fields[log.FnError] = string(body)
 		}
 		log.Error("[slack] request failed", fields)
-		return false
-	}
-
-	if retries < m.maxRetry {
-		retries++
-		if m.wait(ctx, sleepDuration) {
-			goto RETRY
-		}
-	}
-	log.Error("[slack] gave up", nil)
-	return false
-}
-
-// A goroutine to send messages to slack complying its rate limits.
-func dequeueAndSend(ctx context.Context) error {
-	for {
-		select {
-		case m := <-sendCh:
-			m.send(ctx)
-			m.wait(ctx, slackAPIDuration)
-		case <-ctx.Done():
-			return nil
-		}
 	}
+	return errors.New("slack: " + resp.Status)
 }
@@ -54,11 +54,12 @@ func (h *testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func TestSend(t *testing.T) {
-	t.Run("Connect", testSendConnect)
-	t.Run("Retry", testSendRetry)
-	t.Run("Cancel", testSendCancel)
+	t.Run("Success", testSendSuccess)
+	t.Run("Error", testSendError)
 	t.Run("Rate", testSendRate)
 	t.Run("Bad", testSendBad)
+	t.Run("Cancel", testSendCancel)
+	t.Run("Interval", testSendInterval)
 }
 
 func makeServ(errors, rateLimit int, badRequest bool) (*httptest.Server, *url.URL) {
@@ -67,110 +68,114 @@ func makeServ(errors, rateLimit int, badRequest bool) (*httptest.Server, *url.UR
 	return serv, u
 }
 
-func testSendConnect(t *testing.T) {
-	t.Skip("This test is not enough good to run always.")
+func testSendSuccess(t *testing.T) {
 	t.Parallel()
 
-	serv := httptest.NewUnstartedServer(&testHandler{0, 0, false})
-	m := &slackMessage{&url.URL{}, 2, validData}
-	go func() {
-		time.Sleep(1500 * time.Millisecond)
-		serv.Start()
-		u, _ := url.Parse(serv.URL)
-		m.url = u
-	}()
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	serv, u := makeServ(0, 0, false)
+	defer serv.Close()
+
+	s := newSender(0)
+	err := s.post(context.Background(), u, validData)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// invalid data
+	err = s.post(context.Background(), u, nil)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 }
 
-func testSendRetry(t *testing.T) {
+func testSendError(t *testing.T) {
 	t.Parallel()
 
-	serv, u := makeServ(0, 0, false)
-	// invalid data
-	m := &slackMessage{u, 0, nil}
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	serv, u := makeServ(1, 0, false)
+	defer serv.Close()
+
+	s := newSender(0)
+	err := s.post(context.Background(), u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 
-	serv, u = makeServ(0, 0, false)
-	m = &slackMessage{u, 0, validData}
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	err = s.post(context.Background(), u, validData)
+	if err != nil {
+		t.Error(err)
 	}
+
 	serv.Close()
+	err = s.post(context.Background(), u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
 
-	serv, u = makeServ(1, 0, false)
-	m = &slackMessage{u, 0, validData}
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+func testSendRate(t *testing.T) {
+	t.Parallel()
+
+	serv, u := makeServ(0, 2, false)
+	defer serv.Close()
+
+	s := newSender(0)
+	err := s.post(context.Background(), u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
+}
 
-	serv, u = makeServ(1, 0, false)
-	m = &slackMessage{u, 1, validData}
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+func testSendBad(t *testing.T) {
+	t.Parallel()
+
+	serv, u := makeServ(0, 0, true)
+	defer serv.Close()
+
+	s := newSender(0)
+	err := s.post(context.Background(), u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 }
 
 func testSendCancel(t *testing.T) {
 	t.Parallel()
 
+	serv, u := makeServ(0, 0, false)
+	defer serv.Close()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	serv, u := makeServ(1, 0, false)
-	m := &slackMessage{u, 2, validData}
-	// should give up
-	if m.send(ctx) {
-		t.Error(`m.send(context.Background())`)
-	}
-	serv.Close()
-
-	serv, u = makeServ(0, 0, false)
-	m = &slackMessage{u, 0, validData}
-	// should succeed
-	if !m.send(ctx) {
-		t.Error(`!m.send(context.Background())`)
+	s := newSender(0)
+	err := s.post(ctx, u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 }
 
-func testSendRate(t *testing.T) {
+func testSendInterval(t *testing.T) {
 	t.Parallel()
 
-	serv, u := makeServ(0, 2, false)
-	m := &slackMessage{u, 0, validData}
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
-	}
-	serv.Close()
+	serv, u := makeServ(0, 0, false)
+	defer serv.Close()
 
+	s := newSender(200 * time.Millisecond)
 	now := time.Now()
-	serv, u = makeServ(0, 2, false)
-	m = &slackMessage{u, 1, validData}
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	for i := 0; i < 3; i++ {
+		err := s.post(context.Background(), u, validData)
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
-	serv.Close()
-
-	if time.Now().Sub(now) < (2 * time.Second) {
-		t.Error(`time.Now().Sub(now) < (2 * time.Second)`)
+	if time.Now().Sub(now) < 400*time.Millisecond {
+		t.Error(`time.Now().Sub(now) < 400*time.Millisecond`)
 	}
-}
-
-func testSendBad(t *testing.T) {
-	t.Parallel()
 
-	serv, u := makeServ(0, 0, true)
-	m := &slackMessage{u, 3, validData}
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	// the next post waits for the interval and ctx expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.post(ctx, u, validData)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 }
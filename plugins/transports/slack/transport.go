@@ -2,9 +2,11 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/url"
 	"text/template"
+	"time"
 
 	"github.com/cybozu-go/kkok"
 	"github.com/cybozu-go/log"
@@ -18,15 +20,14 @@ const (
 	// Slack's recommendation is 20.  Hard maximum is 100.
 	maxAttachments = 20
 
-	defaultRetry = 3
-
 	rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+	resolvedPrefix = "[RESOLVED] "
 )
 
 type transport struct {
 	url       *url.URL
 	label     string
-	maxRetry  int
 	name      string
 	icon      string
 	channel   string
@@ -34,7 +35,7 @@ type transport struct {
 	origColor string
 	tmplPath  string
 	tmpl      *template.Template
-	enqueue   func(*slackMessage) bool
+	post      func(context.Context, *url.URL, []byte) error
 }
 
 func (t *transport) String() string {
@@ -47,8 +48,7 @@ func (t *transport) String() string {
 
 func (t *transport) Params() kkok.PluginParams {
 	m := map[string]interface{}{
-		"url":       t.url.String(),
-		"max_retry": t.maxRetry,
+		"url": t.url.String(),
 	}
 
 	if len(t.label) > 0 {
@@ -77,9 +77,13 @@ func (t *transport) Params() kkok.PluginParams {
 }
 
 func (t *transport) format(a *kkok.Alert) (*attachment, error) {
+	title := a.Title
+	if a.Resolved() {
+		title = resolvedPrefix + title
+	}
 	at := &attachment{
-		Fallback: a.Title,
-		Title:    EscapeSlack(a.Title),
+		Fallback: title,
+		Title:    EscapeSlack(title),
 	}
 
 	if t.color != nil {
@@ -121,7 +125,7 @@ func (t *transport) format(a *kkok.Alert) (*attachment, error) {
 	return at, nil
 }
 
-func (t *transport) send(alerts []*kkok.Alert) error {
+func (t *transport) send(ctx context.Context, alerts []*kkok.Alert) error {
 	m := &message{
 		Name:    t.name,
 		Icon:    t.icon,
@@ -141,31 +145,34 @@ func (t *transport) send(alerts []*kkok.Alert) error {
 		return err
 	}
 
-	eq := t.enqueue
-	if eq == nil {
-		eq = enqueue
-	}
-
-	ok := eq(&slackMessage{
-		url:      t.url,
-		maxRetry: t.maxRetry,
-		payload:  data,
-	})
-	if !ok {
-		return errors.New("slack queue is full")
+	post := t.post
+	if post == nil {
+		post = defaultSender.post
 	}
+	return post(ctx, t.url, data)
+}
 
-	return nil
+// DeliveryWait implements kkok.RateLimitedTransport.
+func (t *transport) DeliveryWait(alerts []*kkok.Alert) time.Duration {
+	n := (len(alerts) + maxAttachments - 1) / maxAttachments
+	return time.Duration(n) * slackAPIDuration
 }
 
 func (t *transport) Deliver(alerts []*kkok.Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+// DeliverContext sends alerts in messages of up to maxAttachments.
+// If a message fails, kkok.PartialError is returned with the alerts
+// of the message and the rest.
+func (t *transport) DeliverContext(ctx context.Context, alerts []*kkok.Alert) error {
 	for i := 0; i < len(alerts); i += maxAttachments {
 		pos := i + maxAttachments
 		if pos > len(alerts) {
 			pos = len(alerts)
 		}
 
-		err := t.send(alerts[i:pos])
+		err := t.send(ctx, alerts[i:pos])
 		if err == nil {
 			continue
 		}
@@ -176,8 +183,16 @@ func (t *transport) Deliver(alerts []*kkok.Alert) error {
 		if len(t.label) > 0 {
 			fields["label"] = t.label
 		}
-		log.Error("[slack] failed to enqueue", fields)
-	}
+		log.Error("[slack] failed to send", fields)
 
+		err = errors.Wrap(err, t.String())
+		if i == 0 {
+			return err
+		}
+		return &kkok.PartialError{
+			Err:         err,
+			Undelivered: alerts[i:],
+		}
+	}
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/cybozu-go/kkok"
+	"github.com/pkg/errors"
 )
 
 func TestTransport(t *testing.T) {
@@ -17,6 +18,7 @@ func TestTransport(t *testing.T) {
 	t.Run("Params", testParams)
 	t.Run("Format", testFormat)
 	t.Run("Deliver", testDeliver)
+	t.Run("DeliveryWait", testDeliveryWait)
 }
 
 func testString(t *testing.T) {
@@ -42,7 +44,6 @@ func testParams(t *testing.T) {
 	tr := &transport{
 		url:       u,
 		label:     "label",
-		maxRetry:  0,
 		name:      "name",
 		icon:      ":sushi:",
 		channel:   "#channel",
@@ -61,9 +62,6 @@ func testParams(t *testing.T) {
 	if pp.Params["label"] != "label" {
 		t.Error(`pp.Params["label"] != "label"`)
 	}
-	if pp.Params["max_retry"] != 0 {
-		t.Error(`pp.Params["max_retry"] != 0`)
-	}
 	if pp.Params["name"] != "name" {
 		t.Error(`pp.Params["name"] != "name"`)
 	}
@@ -84,11 +82,8 @@ func testParams(t *testing.T) {
 		url: u,
 	}
 	pp = tr.Params()
-	if len(pp.Params) != 2 {
-		t.Error(`len(pp.Params) != 2`)
-	}
-	if _, ok := pp.Params["max_retry"]; !ok {
-		t.Error(`_, ok := pp.Params["max_retry"]; !ok`)
+	if len(pp.Params) != 1 {
+		t.Error(`len(pp.Params) != 1`)
 	}
 }
 
@@ -289,10 +284,10 @@ func testFormatCustom(t *testing.T) {
 func testDeliver(t *testing.T) {
 	t.Parallel()
 
-	ch := make(chan *slackMessage, 10)
-	eq := func(m *slackMessage) bool {
-		ch <- m
-		return true
+	ch := make(chan []byte, 10)
+	post := func(ctx context.Context, u *url.URL, payload []byte) error {
+		ch <- payload
+		return nil
 	}
 
 	alerts1 := make([]*kkok.Alert, 1)
@@ -319,8 +314,8 @@ func testDeliver(t *testing.T) {
 
 	u, _ := url.Parse("https://slack.com/hoge/fuga")
 	tr := &transport{
-		url:     u,
-		enqueue: eq,
+		url:  u,
+		post: post,
 	}
 
 	dequeue := func() int {
@@ -358,6 +353,34 @@ func testDeliver(t *testing.T) {
 	if dequeue() != 3 {
 		t.Error(`dequeue() != 3`)
 	}
+
+	// the second message fails.
+	var n int
+	tr.post = func(ctx context.Context, u *url.URL, payload []byte) error {
+		n++
+		if n == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}
+	err = tr.Deliver(alerts3)
+	pe, ok := errors.Cause(err).(*kkok.PartialError)
+	if !ok {
+		t.Fatal(`not a PartialError`, err)
+	}
+	if len(pe.Undelivered) != len(alerts3)-maxAttachments {
+		t.Error(`len(pe.Undelivered) != len(alerts3)-maxAttachments`, len(pe.Undelivered))
+	}
+
+	// the first message fails.
+	n = 1
+	err = tr.Deliver(alerts1)
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+	if _, ok := errors.Cause(err).(*kkok.PartialError); ok {
+		t.Error(`unexpected PartialError`)
+	}
 }
 
 func TestPost(t *testing.T) {
@@ -371,12 +394,6 @@ func TestPost(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ch := make(chan *slackMessage, 10)
-	eq := func(m *slackMessage) bool {
-		ch <- m
-		return true
-	}
-
 	color, err := kkok.CompileJS(`"danger"`)
 	if err != nil {
 		t.Fatal(err)
@@ -388,7 +405,6 @@ func TestPost(t *testing.T) {
 		icon:    ":sushi:",
 		channel: "#random",
 		color:   color,
-		enqueue: eq,
 	}
 
 	err = tr.Deliver([]*kkok.Alert{
@@ -406,9 +422,16 @@ func TestPost(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+}
+
+func testDeliveryWait(t *testing.T) {
+	t.Parallel()
 
-	m := <-ch
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	tr := &transport{}
+	if tr.DeliveryWait(make([]*kkok.Alert, 1)) != slackAPIDuration {
+		t.Error(`one message should be sent for 1 alert`)
+	}
+	if tr.DeliveryWait(make([]*kkok.Alert, maxAttachments+1)) != 2*slackAPIDuration {
+		t.Error(`two messages should be sent for maxAttachments+1 alerts`)
 	}
 }
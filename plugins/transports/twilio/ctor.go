@@ -6,7 +6,7 @@ import (
 
 	"github.com/cybozu-go/kkok"
 	"github.com/cybozu-go/kkok/util"
-	"github.com/cybozu-go/well"
+	"github.com/cybozu-go/log"
 	"github.com/pkg/errors"
 )
 
@@ -80,15 +80,6 @@ func ctor(params map[string]interface{}) (kkok.Transport, error) {
 		return nil, errors.Wrap(err, "twilio: max_length")
 	}
 
-	maxRetry, err := util.GetInt("max_retry", params)
-	switch {
-	case err == nil:
-		tr.maxRetry = maxRetry
-	case util.IsNotFound(err):
-	default:
-		return nil, errors.Wrap(err, "twilio: max_retry")
-	}
-
 	tmplPath, err := util.GetString("template", params)
 	switch {
 	case err == nil:
@@ -109,10 +100,16 @@ func ctor(params map[string]interface{}) (kkok.Transport, error) {
 	}
 	tr.countOnly = countOnly
 
+	if _, ok := params["max_retry"]; ok {
+		// max_retry was removed as kkok retries failed deliveries.
+		log.Warn("[twilio] max_retry is no longer supported and ignored", map[string]interface{}{
+			"transport": tr.String(),
+		})
+	}
+
 	return tr, nil
 }
 
 func init() {
 	kkok.RegisterTransport(transportType, ctor)
-	well.Go(dequeueAndSend)
 }
@@ -34,7 +34,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr6": {map[string]interface{}{
 		"account": "account",
@@ -46,7 +45,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "12345",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr7": {map[string]interface{}{
 		"account": "account",
@@ -58,7 +56,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "123456",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr8": {map[string]interface{}{
 		"account": "account",
@@ -91,7 +88,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr13": {map[string]interface{}{
 		"account": "account",
@@ -111,7 +107,6 @@ var ctorTestData = map[string]ctorTest{
 		from:      "+123456789",
 		to:        []string{"+123456789", "123456"},
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr15": {map[string]interface{}{
 		"account": "account",
@@ -131,7 +126,6 @@ var ctorTestData = map[string]ctorTest{
 		from:      "+123456789",
 		toFile:    "/path/to/file",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 	"tr17": {map[string]interface{}{
 		"account":    "account",
@@ -144,7 +138,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: 20,
-		maxRetry:  defaultRetry,
 	}},
 	"tr18": {map[string]interface{}{
 		"account":    "account",
@@ -165,19 +158,6 @@ var ctorTestData = map[string]ctorTest{
 		"max_length": 2000,
 	}, nil},
 	"tr21": {map[string]interface{}{
-		"account":   "account",
-		"token":     "token",
-		"from":      "+123456789",
-		"max_retry": 0,
-	}, &transport{
-		account:   "account",
-		sid:       "account",
-		token:     "token",
-		from:      "+123456789",
-		maxLength: defaultLength,
-		maxRetry:  0,
-	}},
-	"tr22": {map[string]interface{}{
 		"account":  "account",
 		"token":    "token",
 		"from":     "+123456789",
@@ -188,16 +168,15 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 		tmplPath:  "testdata/test.tmpl",
 	}},
-	"tr23": {map[string]interface{}{
+	"tr22": {map[string]interface{}{
 		"account":  "account",
 		"token":    "token",
 		"from":     "+123456789",
 		"template": "testdata/invalid.tmpl",
 	}, nil},
-	"tr24": {map[string]interface{}{
+	"tr23": {map[string]interface{}{
 		"account":    "account",
 		"token":      "token",
 		"from":       "+123456789",
@@ -208,10 +187,9 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 		countOnly: true,
 	}},
-	"tr25": {map[string]interface{}{
+	"tr24": {map[string]interface{}{
 		"label":   "abc",
 		"account": "account",
 		"token":   "token",
@@ -223,7 +201,6 @@ var ctorTestData = map[string]ctorTest{
 		token:     "token",
 		from:      "+123456789",
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 	}},
 }
 
@@ -249,7 +226,8 @@ func testCtorOne(t *testing.T, data ctorTest) {
 		tr2.url = nil
 	}
 	tr2.tmpl = nil
-	tr2.enqueue = nil
+	tr2.post = nil
+	tr2.sent = nil
 	if !reflect.DeepEqual(tr2, data.tr) {
 		t.Error(`!reflect.DeepEqual(tr2, data.tr)`)
 		t.Logf("%#v, %#v", tr2, data.tr)
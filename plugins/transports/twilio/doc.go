@@ -6,7 +6,14 @@ SMS API, read https://www.twilio.com/docs/api/rest/sending-messages
 
 Message segment per second (MPS) is limited to 1 for US/Canada or 10
 for other countries.  The plugin automatically adjust sending rates to
-comply these rate limits.
+comply these rate limits.  The time to wait for the rate limits is
+added to kkok's delivery_timeout.
+
+Deliver returns an error if Twilio does not accept a message; failed
+deliveries are retried by kkok's retry policy and fallback transports,
+not by this plugin.  If only some recipients fail, the plugin remembers
+recipients who have received the message and skips them when the same
+alert is retried.
 
 The plugin takes these construction parameters:
 
@@ -19,7 +26,6 @@ The plugin takes these construction parameters:
     to          []string    nil         Destination phone numbers.
     to_file     string      ""          Filename.  See below.
     max_length  int         160         The maximum body length in characters.
-    max_retry   int         3           Max retry count when server returns 500.
     template    string      ""          Filesystem path of the template file.
     count_only  bool        false       See below.
 
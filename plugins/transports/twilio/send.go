@@ -11,11 +11,10 @@ import (
 
 	"github.com/cybozu-go/log"
 	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
 )
 
 const (
-	queueSize = 100
-
 	// see https://www.twilio.com/docs/api/rest/sending-messages#rate-limiting
 	twilioSMSInterval = 1100 * time.Millisecond
 
@@ -23,36 +22,46 @@ const (
 )
 
 var (
-	sendCh     = make(chan *twilioSMS, queueSize)
 	httpClient = &well.HTTPClient{
 		Client:   &http.Client{},
 		Severity: log.LvDebug,
 	}
-)
 
-func enqueue(m *twilioSMS) bool {
-	select {
-	case sendCh <- m:
-		return true
-	default:
-		return false
-	}
-}
+	defaultSender = newSender(twilioSMSInterval)
+)
 
 type twilioSMS struct {
 	url      *url.URL
 	username string
 	password string
-	maxRetry int
 	payload  string
 }
 
-func (m *twilioSMS) wait(ctx context.Context, duration time.Duration) bool {
+// sender sends SMS one by one complying Twilio's rate limits.
+type sender struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	// protected by sem
+	last time.Time
+}
+
+func newSender(interval time.Duration) *sender {
+	return &sender{
+		sem:      make(chan struct{}, 1),
+		interval: interval,
+	}
+}
+
+func wait(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
 	select {
 	case <-ctx.Done():
-		return false
+		return ctx.Err()
 	case <-time.After(duration):
-		return true
+		return nil
 	}
 }
 
@@ -72,8 +81,7 @@ func (m *twilioSMS) do(ctx context.Context) (*http.Response, []byte, error) {
 	}
 	req.SetBasicAuth(m.username, m.password)
 
-	// use context.Background to send SMS gracefully.
-	ctx, cancel := context.WithTimeout(context.Background(), twilioTimeout)
+	ctx, cancel := context.WithTimeout(ctx, twilioTimeout)
 	defer cancel()
 
 	resp, err := httpClient.Do(req.WithContext(ctx))
@@ -88,29 +96,33 @@ func (m *twilioSMS) do(ctx context.Context) (*http.Response, []byte, error) {
 	return resp, data, nil
 }
 
-func (m *twilioSMS) send(ctx context.Context) bool {
-	var retries int
+// post sends m.  An error is returned unless Twilio accepts it.
+// Failed requests are not retried here; kkok retries deliveries.
+func (s *sender) post(ctx context.Context, m *twilioSMS) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		<-s.sem
+	}()
+
+	err := wait(ctx, s.last.Add(s.interval).Sub(time.Now()))
+	if err != nil {
+		return err
+	}
 
-RETRY:
 	resp, body, err := m.do(ctx)
+	s.last = time.Now()
 	if err != nil {
-		log.Error("[twilio] do", map[string]interface{}{
-			log.FnError: err.Error(),
-		})
-		if retries < m.maxRetry {
-			retries++
-			if m.wait(ctx, twilioSMSInterval) {
-				goto RETRY
-			}
-		}
-		log.Error("[twilio] gave up", nil)
-		return false
+		return err
 	}
 
 	switch {
 	case (200 <= resp.StatusCode) && (resp.StatusCode < 300):
 		log.Info("[twilio] sent SMS", nil)
-		return true
+		return nil
 
 	case resp.StatusCode == 429, resp.StatusCode >= 500:
 		// temporary server failure, hopefully.
@@ -131,28 +143,6 @@ RETRY:
 			}
 		}
 		log.Error("[twilio] request failed", fields)
-		return false
-	}
-
-	if retries < m.maxRetry {
-		retries++
-		if m.wait(ctx, twilioSMSInterval) {
-			goto RETRY
-		}
-	}
-	log.Error("[twilio] gave up", nil)
-	return false
-}
-
-// A goroutine to send SMS complying its rate limits.
-func dequeueAndSend(ctx context.Context) error {
-	for {
-		select {
-		case m := <-sendCh:
-			m.send(ctx)
-			m.wait(ctx, twilioSMSInterval)
-		case <-ctx.Done():
-			return nil
-		}
 	}
+	return errors.New("twilio: " + resp.Status)
 }
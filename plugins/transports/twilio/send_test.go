@@ -81,119 +81,90 @@ func (h *testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func TestSend(t *testing.T) {
-	t.Run("Connect", testSendConnect)
-	t.Run("Retry", testSendRetry)
+	t.Run("Success", testSendSuccess)
+	t.Run("Error", testSendError)
 	t.Run("Cancel", testSendCancel)
 	t.Run("Rate", testSendRate)
 	t.Run("Bad", testSendBad)
+	t.Run("Interval", testSendInterval)
 }
 
-func testSendConnect(t *testing.T) {
-	t.Skip("This test is not enough good to run always.")
+func testSendSuccess(t *testing.T) {
 	t.Parallel()
 
-	serv := httptest.NewUnstartedServer(newTestHandler())
-	m := newTestSMS(serv.URL)
-	m.maxRetry = 2
-	go func() {
-		time.Sleep(1500 * time.Millisecond)
-		serv.Start()
-		u, _ := url.Parse(serv.URL)
-		m.url = u
-	}()
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
-	}
-	serv.Close()
-}
+	serv := httptest.NewServer(newTestHandler())
+	defer serv.Close()
 
-func testSendRetry(t *testing.T) {
-	t.Parallel()
+	s := newSender(0)
+	err := s.post(context.Background(), newTestSMS(serv.URL))
+	if err != nil {
+		t.Error(err)
+	}
 
 	// invalid data
-	serv := httptest.NewServer(newTestHandler())
 	m := newTestSMS(serv.URL)
 	m.payload = "invalid"
-	m.maxRetry = 3
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	err = s.post(context.Background(), m)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
+}
 
-	serv = httptest.NewServer(newTestHandler())
-	m = newTestSMS(serv.URL)
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
-	}
-	serv.Close()
+func testSendError(t *testing.T) {
+	t.Parallel()
 
 	h := newTestHandler()
 	h.errors = 1
-	serv = httptest.NewServer(h)
-	m = newTestSMS(serv.URL)
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	s := newSender(0)
+	m := newTestSMS(serv.URL)
+	err := s.post(context.Background(), m)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 
-	h = newTestHandler()
-	h.errors = 1
-	serv = httptest.NewServer(h)
-	m = newTestSMS(serv.URL)
-	m.maxRetry = 1
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	err = s.post(context.Background(), m)
+	if err != nil {
+		t.Error(err)
 	}
+
 	serv.Close()
+	err = s.post(context.Background(), m)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
 }
 
 func testSendCancel(t *testing.T) {
 	t.Parallel()
 
+	serv := httptest.NewServer(newTestHandler())
+	defer serv.Close()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	h := newTestHandler()
-	h.errors = 1
-	serv := httptest.NewServer(h)
-	m := newTestSMS(serv.URL)
-	m.maxRetry = 2
-	// should give up
-	if m.send(ctx) {
-		t.Error(`m.send(context.Background())`)
-	}
-	serv.Close()
-
-	h = newTestHandler()
-	serv = httptest.NewServer(h)
-	m = newTestSMS(serv.URL)
-	// should succeed
-	if !m.send(ctx) {
-		t.Error(`!m.send(context.Background())`)
+	s := newSender(0)
+	err := s.post(ctx, newTestSMS(serv.URL))
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
 }
 
 func testSendRate(t *testing.T) {
 	t.Parallel()
 
-	now := time.Now()
 	h := newTestHandler()
 	h.statusCode = http.StatusTooManyRequests
 	serv := httptest.NewServer(h)
-	m := newTestSMS(serv.URL)
-	m.maxRetry = 2
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
-	}
-	serv.Close()
+	defer serv.Close()
 
-	if time.Now().Sub(now) < (2 * twilioSMSInterval) {
-		t.Error(`time.Now().Sub(now) < (2 * twilioSMSInterval)`)
-	}
-
-	if time.Now().Sub(now) > (3 * twilioSMSInterval) {
-		t.Error(`time.Now().Sub(now) > (3 * twilioSMSInterval)`)
+	s := newSender(0)
+	err := s.post(context.Background(), newTestSMS(serv.URL))
+	if err == nil {
+		t.Error(`err == nil`)
 	}
 }
 
@@ -203,42 +174,54 @@ func testSendBad(t *testing.T) {
 	h := newTestHandler()
 	h.statusCode = http.StatusBadRequest
 	serv := httptest.NewServer(h)
-	m := newTestSMS(serv.URL)
-	m.maxRetry = 3
-	now := time.Now()
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	s := newSender(0)
+	err := s.post(context.Background(), newTestSMS(serv.URL))
+	if err == nil {
+		t.Error(`err == nil`)
 	}
 	serv.Close()
-	if time.Now().Sub(now) > twilioSMSInterval {
-		t.Error(`time.Now().Sub(now) > twilioSMSInterval`)
-	}
 
-	h = newTestHandler()
-	serv = httptest.NewServer(h)
-	m = newTestSMS(serv.URL)
+	serv = httptest.NewServer(newTestHandler())
+	defer serv.Close()
+
+	m := newTestSMS(serv.URL)
 	m.username = "baduser"
-	m.maxRetry = 3
-	now = time.Now()
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
-	}
-	serv.Close()
-	if time.Now().Sub(now) > twilioSMSInterval {
-		t.Error(`time.Now().Sub(now) > twilioSMSInterval`)
+	err = s.post(context.Background(), m)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
 
-	h = newTestHandler()
-	serv = httptest.NewServer(h)
 	m = newTestSMS(serv.URL)
 	m.password = "badpass"
-	m.maxRetry = 3
-	now = time.Now()
-	if m.send(context.Background()) {
-		t.Error(`m.send(context.Background())`)
+	err = s.post(context.Background(), m)
+	if err == nil {
+		t.Error(`err == nil`)
 	}
-	serv.Close()
-	if time.Now().Sub(now) > twilioSMSInterval {
-		t.Error(`time.Now().Sub(now) > twilioSMSInterval`)
+}
+
+func testSendInterval(t *testing.T) {
+	t.Parallel()
+
+	serv := httptest.NewServer(newTestHandler())
+	defer serv.Close()
+
+	s := newSender(200 * time.Millisecond)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		err := s.post(context.Background(), newTestSMS(serv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Now().Sub(now) < 400*time.Millisecond {
+		t.Error(`time.Now().Sub(now) < 400*time.Millisecond`)
+	}
+
+	// the next post waits for the interval and ctx expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.post(ctx, newTestSMS(serv.URL))
+	if err == nil {
+		t.Error(`err == nil`)
 	}
 }
@@ -0,0 +1,68 @@
+package twilio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+// sentExpire is the duration to remember recipients of an alert.
+// This should be longer than kkok retries deliveries.
+const sentExpire = 24 * time.Hour
+
+// sentTable remembers recipients who have received SMS for alerts
+// so that retries of partially delivered alerts do not send SMS to
+// them again.
+type sentTable struct {
+	mu sync.Mutex
+	m  map[*kkok.Alert]*sentEntry
+}
+
+type sentEntry struct {
+	date time.Time
+	to   map[string]bool
+}
+
+func newSentTable() *sentTable {
+	return &sentTable{
+		m: make(map[*kkok.Alert]*sentEntry),
+	}
+}
+
+// has returns true if SMS for a has been sent to "to".
+func (s *sentTable) has(a *kkok.Alert, to string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.m[a]
+	return ok && e.to[to]
+}
+
+// add records that SMS for a has been sent to "to".
+func (s *sentTable) add(a *kkok.Alert, to string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range s.m {
+		if now.Sub(e.date) > sentExpire {
+			delete(s.m, k)
+		}
+	}
+
+	e, ok := s.m[a]
+	if !ok {
+		e = &sentEntry{date: now, to: make(map[string]bool)}
+		s.m[a] = e
+	}
+	e.to[to] = true
+}
+
+// remove forgets recipients of a.
+func (s *sentTable) remove(a *kkok.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.m, a)
+}
@@ -4,7 +4,7 @@ import "text/template"
 
 // DefaultTemplate is the default text/template to render alert message body.
 // "slack" is a template function to escape special characters in Slack.
-const DefaultTemplate = `Title: {{.Title}}
+const DefaultTemplate = `{{if .Resolved}}RESOLVED {{end}}Title: {{.Title}}
 From: {{.From}}
 Host: {{.Host}}
 Message: {{.Message}}`
@@ -3,10 +3,12 @@ package twilio
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"net/url"
 	"os"
 	"text/template"
+	"time"
 
 	"github.com/cybozu-go/kkok"
 	"github.com/pkg/errors"
@@ -16,7 +18,6 @@ const (
 	transportType = "twilio"
 
 	defaultLength = 160
-	defaultRetry  = 3
 
 	countOnlyMessageOne = "There is an alert."
 	countOnlyMessage    = "There are %d alerts."
@@ -34,11 +35,11 @@ type transport struct {
 	to        []string
 	toFile    string
 	maxLength int
-	maxRetry  int
 	countOnly bool
 	tmplPath  string
 	tmpl      *template.Template
-	enqueue   func(*twilioSMS) bool
+	post      func(context.Context, *twilioSMS) error
+	sent      *sentTable
 }
 
 func newTransport(account string) (*transport, error) {
@@ -52,9 +53,9 @@ func newTransport(account string) (*transport, error) {
 		account:   account,
 		sid:       account,
 		maxLength: defaultLength,
-		maxRetry:  defaultRetry,
 		tmpl:      defaultTemplate,
-		enqueue:   enqueue,
+		post:      defaultSender.post,
+		sent:      newSentTable(),
 	}
 	return tr, nil
 }
@@ -73,7 +74,6 @@ func (t *transport) Params() kkok.PluginParams {
 		"token":      t.token,
 		"from":       t.from,
 		"max_length": t.maxLength,
-		"max_retry":  t.maxRetry,
 	}
 
 	if len(t.label) > 0 {
@@ -131,7 +131,10 @@ func (t *transport) recipients() ([]string, error) {
 	return to, nil
 }
 
-func (t *transport) send(to []string, msg string) error {
+// send sends msg for alert a to recipients.  Recipients who have
+// received the message are skipped when a is delivered again.
+// If some recipients fail, the others are still tried.
+func (t *transport) send(ctx context.Context, a *kkok.Alert, to []string, msg string) error {
 	if len(msg) > t.maxLength {
 		umsg := []rune(msg)
 		if len(umsg) > t.maxLength {
@@ -142,24 +145,59 @@ func (t *transport) send(to []string, msg string) error {
 	v := url.Values{}
 	v.Set("From", t.from)
 	v.Set("Body", msg)
+
+	var nfailed int
+	var lastErr error
 	for _, r := range to {
+		if t.sent.has(a, r) {
+			continue
+		}
+
 		v.Set("To", r)
 		m := &twilioSMS{
 			url:      t.url,
 			username: t.sid,
 			password: t.token,
-			maxRetry: t.maxRetry,
 			payload:  v.Encode(),
 		}
 
-		if !t.enqueue(m) {
-			return errors.New("twilio: queue is full")
+		err := t.post(ctx, m)
+		if err != nil {
+			nfailed++
+			lastErr = err
+			continue
 		}
+		t.sent.add(a, r)
 	}
+
+	if nfailed > 0 {
+		return errors.Wrapf(lastErr, "failed to send SMS to %d of %d recipients", nfailed, len(to))
+	}
+	t.sent.remove(a)
 	return nil
 }
 
+// DeliveryWait implements kkok.RateLimitedTransport.
+func (t *transport) DeliveryWait(alerts []*kkok.Alert) time.Duration {
+	to, err := t.recipients()
+	if err != nil {
+		return 0
+	}
+
+	n := len(to)
+	if !t.countOnly {
+		n *= len(alerts)
+	}
+	return time.Duration(n) * twilioSMSInterval
+}
+
 func (t *transport) Deliver(alerts []*kkok.Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+// DeliverContext sends SMS for alerts one by one.  If an alert fails,
+// kkok.PartialError is returned with the alert and the rest.
+func (t *transport) DeliverContext(ctx context.Context, alerts []*kkok.Alert) error {
 	to, err := t.recipients()
 	if err != nil {
 		return errors.Wrap(err, transportType)
@@ -170,21 +208,33 @@ func (t *transport) Deliver(alerts []*kkok.Alert) error {
 	}
 
 	if t.countOnly {
-		if len(alerts) <= 1 {
-			return t.send(to, countOnlyMessageOne)
+		msg := countOnlyMessageOne
+		if len(alerts) > 1 {
+			msg = fmt.Sprintf(countOnlyMessage, len(alerts))
+		}
+		// the first alert represents the batch.
+		err := t.send(ctx, alerts[0], to, msg)
+		if err != nil {
+			return errors.Wrap(err, transportType)
 		}
-		return t.send(to, fmt.Sprintf(countOnlyMessage, len(alerts)))
+		return nil
 	}
 
 	buf := new(bytes.Buffer)
-	for _, a := range alerts {
+	for i, a := range alerts {
 		err := t.tmpl.Execute(buf, a)
-		if err != nil {
-			return errors.Wrap(err, transportType)
+		if err == nil {
+			err = t.send(ctx, a, to, buf.String())
 		}
-		err = t.send(to, buf.String())
 		if err != nil {
-			return errors.Wrap(err, transportType)
+			err = errors.Wrap(err, transportType)
+			if i == 0 {
+				return err
+			}
+			return &kkok.PartialError{
+				Err:         err,
+				Undelivered: alerts[i:],
+			}
 		}
 		buf.Reset()
 	}
@@ -10,12 +10,14 @@ import (
 	"time"
 
 	"github.com/cybozu-go/kkok"
+	"github.com/pkg/errors"
 )
 
 func TestTransport(t *testing.T) {
 	t.Run("String", testString)
 	t.Run("Params", testParams)
 	t.Run("Deliver", testDeliver)
+	t.Run("DeliveryWait", testDeliveryWait)
 }
 
 func testString(t *testing.T) {
@@ -54,7 +56,6 @@ func testParams(t *testing.T) {
 		"token":      "token",
 		"from":       "+123456789",
 		"max_length": defaultLength,
-		"max_retry":  defaultRetry,
 	}) {
 		t.Error(`pp.Params is not expected`, pp.Params)
 	}
@@ -64,7 +65,6 @@ func testParams(t *testing.T) {
 	tr.to = []string{"+987654321"}
 	tr.toFile = "/path/to/file"
 	tr.maxLength = 1
-	tr.maxRetry = 0
 	tr.countOnly = true
 	tr.tmplPath = "/path/to/template"
 	pp = tr.Params()
@@ -80,7 +80,6 @@ func testParams(t *testing.T) {
 		"to":         []string{"+987654321"},
 		"to_file":    "/path/to/file",
 		"max_length": 1,
-		"max_retry":  0,
 		"template":   "/path/to/template",
 		"count_only": true,
 	}) {
@@ -91,7 +90,7 @@ func testParams(t *testing.T) {
 
 func testDeliver(t *testing.T) {
 	t.Run("SMS", testDeliverSMS)
-	t.Run("Queue", testDeliverQueue)
+	t.Run("Failure", testDeliverFailure)
 	t.Run("Template", testDeliverTemplate)
 	t.Run("CountOnly", testDeliverCountOnly)
 	t.Run("ToFile", testDeliverToFile)
@@ -121,9 +120,9 @@ func testDeliverSMS(t *testing.T) {
 	tr.from = "+123456789"
 
 	ch := make(chan *twilioSMS, 10)
-	tr.enqueue = func(m *twilioSMS) bool {
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
 		ch <- m
-		return true
+		return nil
 	}
 
 	a := &kkok.Alert{
@@ -161,9 +160,6 @@ func testDeliverSMS(t *testing.T) {
 		if m.password != tr.token {
 			t.Error(`m.password != tr.token`)
 		}
-		if m.maxRetry != tr.maxRetry {
-			t.Error(`m.maxRetry != tr.maxRetry`)
-		}
 
 		v, err := url.ParseQuery(m.payload)
 		if err != nil {
@@ -179,7 +175,7 @@ func testDeliverSMS(t *testing.T) {
 	}
 }
 
-func testDeliverQueue(t *testing.T) {
+func testDeliverFailure(t *testing.T) {
 	t.Parallel()
 
 	tr, err := newTransport("abc")
@@ -190,13 +186,71 @@ func testDeliverQueue(t *testing.T) {
 	tr.from = "+123456789"
 	tr.to = []string{"+100000000"}
 
-	tr.enqueue = func(m *twilioSMS) bool {
-		return false
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
+		return errors.New("failed")
 	}
 
 	err = tr.Deliver([]*kkok.Alert{{}})
 	if err == nil {
-		t.Error(`err == nil`)
+		t.Fatal(`err == nil`)
+	}
+	if _, ok := errors.Cause(err).(*kkok.PartialError); ok {
+		t.Error(`unexpected PartialError`)
+	}
+
+	// the second alert fails.
+	var n int
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
+		n++
+		if n == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}
+	alerts := []*kkok.Alert{{Message: "1"}, {Message: "2"}, {Message: "3"}}
+	err = tr.Deliver(alerts)
+	pe, ok := errors.Cause(err).(*kkok.PartialError)
+	if !ok {
+		t.Fatal(`not a PartialError`, err)
+	}
+	if len(pe.Undelivered) != 2 || pe.Undelivered[0] != alerts[1] {
+		t.Error(`unexpected undelivered alerts`, pe.Undelivered)
+	}
+
+	// retries skip recipients who have received SMS.
+	tr.to = []string{"+100000000", "+200000000"}
+	var sent []string
+	fail := true
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
+		v, _ := url.ParseQuery(m.payload)
+		sent = append(sent, v.Get("To"))
+		if v.Get("To") == "+200000000" && fail {
+			fail = false
+			return errors.New("failed")
+		}
+		return nil
+	}
+	a := &kkok.Alert{Message: "4"}
+	err = tr.Deliver([]*kkok.Alert{a})
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+	err = tr.Deliver([]*kkok.Alert{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, []string{"+100000000", "+200000000", "+200000000"}) {
+		t.Error(`unexpected recipients`, sent)
+	}
+
+	// sent SMS are forgotten after successful delivery.
+	sent = nil
+	err = tr.Deliver([]*kkok.Alert{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 {
+		t.Error(`len(sent) != 2`, sent)
 	}
 }
 
@@ -218,9 +272,9 @@ func testDeliverTemplate(t *testing.T) {
 	tr.tmpl = tmpl
 
 	ch := make(chan *twilioSMS, 10)
-	tr.enqueue = func(m *twilioSMS) bool {
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
 		ch <- m
-		return true
+		return nil
 	}
 
 	msgs := []string{"msg1", "msg2"}
@@ -257,9 +311,9 @@ func testDeliverCountOnly(t *testing.T) {
 	tr.countOnly = true
 
 	ch := make(chan *twilioSMS, 10)
-	tr.enqueue = func(m *twilioSMS) bool {
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
 		ch <- m
-		return true
+		return nil
 	}
 
 	err = tr.Deliver([]*kkok.Alert{{Message: "aaa"}, {Message: "bbb"}})
@@ -287,9 +341,9 @@ func testDeliverToFile(t *testing.T) {
 	tr.toFile = "testdata/to.txt"
 
 	ch := make(chan *twilioSMS, 10)
-	tr.enqueue = func(m *twilioSMS) bool {
+	tr.post = func(ctx context.Context, m *twilioSMS) error {
 		ch <- m
-		return true
+		return nil
 	}
 
 	err = tr.Deliver([]*kkok.Alert{{Message: "aaa"}})
@@ -332,9 +386,9 @@ func testDeliverMaxLength(t *testing.T) {
 		tr.maxLength = max
 
 		ch := make(chan *twilioSMS, 1)
-		tr.enqueue = func(m *twilioSMS) bool {
+		tr.post = func(ctx context.Context, m *twilioSMS) error {
 			ch <- m
-			return true
+			return nil
 		}
 
 		err = tr.Deliver([]*kkok.Alert{{Message: msg}})
@@ -389,12 +443,6 @@ func TestPost(t *testing.T) {
 	tr.from = from
 	tr.to = []string{to}
 
-	ch := make(chan *twilioSMS, 1)
-	tr.enqueue = func(m *twilioSMS) bool {
-		ch <- m
-		return true
-	}
-
 	err = tr.Deliver([]*kkok.Alert{
 		{
 			From:    "from1",
@@ -411,9 +459,18 @@ func TestPost(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+}
+
+func testDeliveryWait(t *testing.T) {
+	t.Parallel()
 
-	m := <-ch
-	if !m.send(context.Background()) {
-		t.Error(`!m.send(context.Background())`)
+	tr := &transport{to: []string{"+100000000", "+200000000"}}
+	if tr.DeliveryWait(make([]*kkok.Alert, 3)) != 6*twilioSMSInterval {
+		t.Error(`6 SMS should be sent for 3 alerts`)
+	}
+
+	tr.countOnly = true
+	if tr.DeliveryWait(make([]*kkok.Alert, 3)) != 2*twilioSMSInterval {
+		t.Error(`2 SMS should be sent in count_only mode`)
 	}
 }
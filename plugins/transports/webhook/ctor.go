@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/kkok/util"
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
+)
+
+// getHeaders reads "headers" parameter, a table of strings.
+func getHeaders(params map[string]interface{}) (map[string]string, error) {
+	switch m := params["headers"].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return m, nil
+	case map[string]interface{}:
+		headers := make(map[string]string, len(m))
+		for k, v := range m {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("not a string: " + k)
+			}
+			headers[k] = s
+		}
+		return headers, nil
+	}
+	return nil, errors.New("not a table")
+}
+
+// tlsConfig returns *tls.Config built from p.
+// nil is returned if p is the zero value.
+func (p tlsParams) tlsConfig() (*tls.Config, error) {
+	if p == (tlsParams{}) {
+		return nil, nil
+	}
+
+	c := &tls.Config{
+		InsecureSkipVerify: p.insecureSkipVerify,
+	}
+
+	if len(p.caFile) > 0 {
+		data, err := ioutil.ReadFile(p.caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "ca_file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("ca_file: no certificates")
+		}
+		c.RootCAs = pool
+	}
+
+	if len(p.certFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cert_file")
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+func ctor(params map[string]interface{}) (kkok.Transport, error) {
+	us, err := util.GetString("url", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: url")
+	}
+
+	u, err := url.ParseRequestURI(us)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("webhook: url: unsupported scheme: " + u.Scheme)
+	}
+
+	tr := &transport{
+		url:           u,
+		method:        defaultMethod,
+		contentType:   defaultContentType,
+		timeout:       defaultTimeout,
+		maxRetry:      defaultRetry,
+		retryInterval: defaultRetryInterval,
+	}
+
+	label, err := util.GetString("label", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: label")
+	}
+	tr.label = label
+
+	method, err := util.GetString("method", params)
+	switch {
+	case err == nil:
+		tr.method = strings.ToUpper(method)
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: method")
+	}
+
+	headers, err := getHeaders(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: headers")
+	}
+	tr.headers = headers
+
+	contentType, err := util.GetString("content_type", params)
+	switch {
+	case err == nil:
+		tr.contentType = contentType
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: content_type")
+	}
+
+	all, err := util.GetBool("all", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: all")
+	}
+	tr.all = all
+
+	tmplPath, err := util.GetString("template", params)
+	switch {
+	case err == nil:
+		tmpl, err := parseTemplate(tmplPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "webhook: template")
+		}
+		tr.tmpl = tmpl
+		tr.tmplPath = tmplPath
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: template")
+	}
+
+	body, err := util.GetString("body", params)
+	switch {
+	case err == nil:
+		if tr.tmpl != nil {
+			return nil, errors.New("webhook: template and body are exclusive")
+		}
+		bs, err := kkok.CompileJS(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "webhook: body")
+		}
+		tr.body = bs
+		tr.origBody = body
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: body")
+	}
+
+	secret, err := util.GetString("secret", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: secret")
+	}
+	tr.secret = secret
+
+	sigHeader, err := util.GetString("signature_header", params)
+	switch {
+	case err == nil:
+		if len(secret) == 0 {
+			return nil, errors.New("webhook: signature_header requires secret")
+		}
+		tr.signatureHeader = sigHeader
+	case util.IsNotFound(err):
+		if len(secret) > 0 {
+			tr.signatureHeader = defaultSignatureHeader
+		}
+	default:
+		return nil, errors.Wrap(err, "webhook: signature_header")
+	}
+
+	insecure, err := util.GetBool("insecure_skip_verify", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: insecure_skip_verify")
+	}
+	tr.tls.insecureSkipVerify = insecure
+
+	caFile, err := util.GetString("ca_file", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: ca_file")
+	}
+	tr.tls.caFile = caFile
+
+	certFile, err := util.GetString("cert_file", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: cert_file")
+	}
+	keyFile, err := util.GetString("key_file", params)
+	if err != nil && !util.IsNotFound(err) {
+		return nil, errors.Wrap(err, "webhook: key_file")
+	}
+	if (len(certFile) > 0) != (len(keyFile) > 0) {
+		return nil, errors.New("webhook: cert_file and key_file must be specified together")
+	}
+	tr.tls.certFile = certFile
+	tr.tls.keyFile = keyFile
+
+	ts, err := util.GetInt("timeout", params)
+	switch {
+	case err == nil:
+		if ts < 0 {
+			return nil, errors.New("webhook: invalid timeout")
+		}
+		tr.timeout = time.Duration(ts) * time.Second
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: timeout")
+	}
+
+	maxRetry, err := util.GetInt("max_retry", params)
+	switch {
+	case err == nil:
+		if maxRetry < 0 {
+			return nil, errors.New("webhook: invalid max_retry")
+		}
+		tr.maxRetry = maxRetry
+	case util.IsNotFound(err):
+	default:
+		return nil, errors.Wrap(err, "webhook: max_retry")
+	}
+
+	tc, err := tr.tls.tlsConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook")
+	}
+	client := &http.Client{}
+	if tc != nil {
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tc,
+		}
+	}
+	tr.client = &well.HTTPClient{
+		Client:   client,
+		Severity: log.LvDebug,
+	}
+
+	return tr, nil
+}
+
+func init() {
+	kkok.RegisterTransport(transportType, ctor)
+}
@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCtor(t *testing.T) {
+	t.Run("URL", testCtorURL)
+	t.Run("Defaults", testCtorDefaults)
+	t.Run("Headers", testCtorHeaders)
+	t.Run("Body", testCtorBody)
+	t.Run("Signature", testCtorSignature)
+	t.Run("TLS", testCtorTLS)
+	t.Run("Invalid", testCtorInvalid)
+	t.Run("Params", testCtorParams)
+}
+
+func testCtorURL(t *testing.T) {
+	t.Parallel()
+
+	_, err := ctor(nil)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	for _, u := range []interface{}{3.14, "hoge", "ftp://example.com/"} {
+		_, err := ctor(map[string]interface{}{"url": u})
+		if err == nil {
+			t.Error(`err == nil`, u)
+		}
+	}
+}
+
+func testCtorDefaults(t *testing.T) {
+	t.Parallel()
+
+	tr, err := ctor(map[string]interface{}{
+		"url": "https://example.com/hook",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := tr.(*transport)
+	if w.method != defaultMethod {
+		t.Error(`w.method != defaultMethod`)
+	}
+	if w.contentType != defaultContentType {
+		t.Error(`w.contentType != defaultContentType`)
+	}
+	if w.timeout != defaultTimeout {
+		t.Error(`w.timeout != defaultTimeout`)
+	}
+	if w.maxRetry != defaultRetry {
+		t.Error(`w.maxRetry != defaultRetry`)
+	}
+	if w.all {
+		t.Error(`w.all`)
+	}
+	if w.client == nil {
+		t.Error(`w.client == nil`)
+	}
+}
+
+func testCtorHeaders(t *testing.T) {
+	t.Parallel()
+
+	tr, err := ctor(map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"method": "put",
+		"headers": map[string]interface{}{
+			"Authorization": "Bearer xxx",
+		},
+		"timeout": 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := tr.(*transport)
+	if w.method != "PUT" {
+		t.Error(`w.method != "PUT"`)
+	}
+	if !reflect.DeepEqual(w.headers, map[string]string{"Authorization": "Bearer xxx"}) {
+		t.Error(`unexpected headers`, w.headers)
+	}
+	if w.timeout != 0 {
+		t.Error(`w.timeout != 0`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"url": "https://example.com/hook",
+		"headers": map[string]interface{}{
+			"X-Number": 1,
+		},
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testCtorBody(t *testing.T) {
+	t.Parallel()
+
+	tr, err := ctor(map[string]interface{}{
+		"url":      "https://example.com/hook",
+		"template": "testdata/body.tmpl",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.(*transport).tmpl == nil {
+		t.Error(`tmpl == nil`)
+	}
+
+	tr, err = ctor(map[string]interface{}{
+		"url":  "https://example.com/hook",
+		"body": "({text: alert.Title})",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.(*transport).body == nil {
+		t.Error(`body == nil`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"url":      "https://example.com/hook",
+		"template": "testdata/body.tmpl",
+		"body":     "({text: alert.Title})",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testCtorSignature(t *testing.T) {
+	t.Parallel()
+
+	tr, err := ctor(map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"secret": "himitsu",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.(*transport).signatureHeader != defaultSignatureHeader {
+		t.Error(`signatureHeader != defaultSignatureHeader`)
+	}
+
+	tr, err = ctor(map[string]interface{}{
+		"url":              "https://example.com/hook",
+		"secret":           "himitsu",
+		"signature_header": "X-Hub-Signature-256",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.(*transport).signatureHeader != "X-Hub-Signature-256" {
+		t.Error(`signatureHeader != "X-Hub-Signature-256"`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"url":              "https://example.com/hook",
+		"signature_header": "X-Hub-Signature-256",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testCtorTLS(t *testing.T) {
+	t.Parallel()
+
+	tr, err := ctor(map[string]interface{}{
+		"url":                  "https://example.com/hook",
+		"insecure_skip_verify": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.(*transport).tls.insecureSkipVerify {
+		t.Error(`!insecureSkipVerify`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"url":     "https://example.com/hook",
+		"ca_file": "testdata/not-exist.pem",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	_, err = ctor(map[string]interface{}{
+		"url":       "https://example.com/hook",
+		"cert_file": "testdata/cert.pem",
+	})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testCtorInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []map[string]interface{}{
+		{"method": 1},
+		{"headers": "abc"},
+		{"content_type": true},
+		{"all": "true"},
+		{"template": "testdata/not-exist.tmpl"},
+		{"body": "("},
+		{"secret": 1},
+		{"insecure_skip_verify": "yes"},
+		{"timeout": -1},
+		{"timeout": "1"},
+		{"max_retry": -1},
+		{"max_retry": "100"},
+	}
+
+	for _, c := range cases {
+		c["url"] = "https://example.com/hook"
+		_, err := ctor(c)
+		if err == nil {
+			t.Error(`err == nil`, c)
+		}
+	}
+}
+
+func testCtorParams(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"label":  "label",
+		"method": "PUT",
+		"headers": map[string]interface{}{
+			"Authorization": "Bearer xxx",
+		},
+		"content_type":         "text/plain",
+		"all":                  true,
+		"body":                 "alerts.length.toString()",
+		"secret":               "himitsu",
+		"signature_header":     "X-Signature",
+		"insecure_skip_verify": true,
+		"timeout":              10,
+		"max_retry":            5,
+	}
+
+	tr, err := ctor(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp := tr.Params()
+	if pp.Type != transportType {
+		t.Error(`pp.Type != transportType`)
+	}
+	if !reflect.DeepEqual(pp.Params, params) {
+		t.Error(`!reflect.DeepEqual(pp.Params, params)`, pp.Params)
+	}
+
+	tr2, err := ctor(pp.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr2.(*transport).timeout != 10*time.Second {
+		t.Error(`tr2.timeout != 10*time.Second`)
+	}
+}
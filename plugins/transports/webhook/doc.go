@@ -0,0 +1,69 @@
+/*
+Package webhook provides a transport to send alerts to HTTP services.
+
+By default, alerts are serialized as JSON and sent as request bodies.
+If "all" construction parameter is true, all alerts are sent in one
+request as a list of kkok.Alert objects.  Otherwise, each alert is
+sent in a separate request.
+
+The request body can be customized in either of two ways:
+
+"template" is a filesystem path of a template file for text/template
+package.  The template is executed with a kkok.Alert, or a list of
+kkok.Alert if "all" is true.  The template provides a non-standard
+function "json" to encode values into JSON, e.g. {{json .Title}}.
+
+"body" is a JavaScript expression.  It can refer to "alert" variable,
+or "alerts" variable if "all" is true.  If the expression evaluates to
+a string, the string is used as is.  Otherwise, the value is encoded
+into JSON.
+
+If "secret" is specified, the request has a signature header whose
+value is "sha256=" followed by the hex-encoded HMAC-SHA256 of the body.
+
+Failed deliveries are retried by kkok's retry policy.  In addition,
+requests can be retried up to "max_retry" times by this plugin when
+they fail to connect or the server returns 5xx or 429 status.  For 429,
+"Retry-After" response header is respected.  Since the two retries
+multiply the attempts, "max_retry" is 0 by default.
+
+If "all" is false and a request fails, alerts sent before the failure
+are not delivered again by kkok's retry policy or fallback transports.
+
+The plugin takes these construction parameters:
+
+    Name                  Type     Default             Description
+    label                 string   ""                  Arbitrary string label.
+    url                   string                       URL to send requests.  Required.
+    method                string   "POST"              HTTP method.
+    headers               table                        Additional request headers.
+    content_type          string   "application/json"  Content-Type header value.
+    all                   bool     false               See above description.
+    template              string   ""                  Filesystem path of the template file.
+    body                  string   ""                  JavaScript expression to make the body.
+    secret                string   ""                  Secret key to sign the body.
+    signature_header      string   "X-Kkok-Signature"  Header name for the signature.
+    insecure_skip_verify  bool     false               Skip TLS certificate verification.
+    ca_file               string   ""                  PEM file of CA certificates.
+    cert_file             string   ""                  PEM file of the client certificate.
+    key_file              string   ""                  PEM file of the client private key.
+    timeout               int      5                   Seconds to wait for a response.
+                                                       If 0, requests will not time out.
+    max_retry             int      0                   Max retry count.
+
+"template" and "body" are exclusive.
+
+Example snippet for TOML configuration:
+
+    [[route.notify]]
+    type        = "webhook"
+    url         = "https://some.service.com/alerts"
+    headers     = { Authorization = "Bearer xxxx" }
+    body        = "({text: alerts.map(function(a) { return a.Title }).join('\\n')})"
+    all         = true
+    secret      = "zzzz"
+
+This example sends a JSON object with "text" field listing titles of
+all alerts in one request.
+*/
+package webhook
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+// maxErrorBody limits the response body to be logged.
+const maxErrorBody = 1024
+
+func (t *transport) wait(ctx context.Context, duration time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
+
+func (t *transport) do(ctx context.Context, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(t.method, t.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", t.contentType)
+	if len(t.secret) > 0 {
+		req.Header.Set(t.signatureHeader, t.sign(body))
+	}
+
+	if t.timeout != 0 {
+		ctx2, cancel := context.WithTimeout(ctx, t.timeout)
+		ctx = ctx2
+		defer cancel()
+	}
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// send sends body to the URL.
+// Connection errors, 5xx and 429 responses are retried up to maxRetry times.
+func (t *transport) send(ctx context.Context, body []byte) error {
+	var retries int
+
+RETRY:
+	resp, data, err := t.do(ctx, body)
+	if err != nil {
+		log.Error("[webhook] do", map[string]interface{}{
+			log.FnError: err.Error(),
+			log.FnURL:   t.url.String(),
+		})
+		if retries < t.maxRetry {
+			retries++
+			if t.wait(ctx, t.retryInterval) {
+				goto RETRY
+			}
+		}
+		return err
+	}
+
+	sleepDuration := t.retryInterval
+
+	switch {
+	case (200 <= resp.StatusCode) && (resp.StatusCode < 300):
+		log.Info("[webhook] sent alerts", map[string]interface{}{
+			log.FnURL: t.url.String(),
+		})
+		return nil
+
+	case resp.StatusCode == http.StatusTooManyRequests:
+		log.Warn("[webhook] rate limit exceeds", map[string]interface{}{
+			log.FnURL: t.url.String(),
+		})
+		ssec := resp.Header.Get("Retry-After")
+		if len(ssec) > 0 {
+			sec, err := strconv.Atoi(ssec)
+			if err == nil {
+				sleepDuration = time.Duration(sec) * time.Second
+			}
+		}
+
+	case resp.StatusCode >= 500:
+		// temporary server failure, hopefully.
+		log.Error("[webhook] failed to send", map[string]interface{}{
+			log.FnURL:            t.url.String(),
+			log.FnHTTPStatusCode: resp.StatusCode,
+		})
+
+	default:
+		// mainly because the request was bad.
+		fields := map[string]interface{}{
+			log.FnURL:            t.url.String(),
+			log.FnHTTPStatusCode: resp.StatusCode,
+		}
+		if len(data) > maxErrorBody {
+			data = data[:maxErrorBody]
+		}
+		if len(data) > 0 {
+			fields[log.FnError] = string(data)
+		}
+		log.Error("[webhook] request failed", fields)
+		return errors.New("request failed: " + resp.Status)
+	}
+
+	if retries < t.maxRetry {
+		retries++
+		if t.wait(ctx, sleepDuration) {
+			goto RETRY
+		}
+	}
+	return errors.New("gave up: " + resp.Status)
+}
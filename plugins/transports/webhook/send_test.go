@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+type testHandler struct {
+	mu        sync.Mutex
+	errors    int
+	rateLimit bool
+	status    int
+	maxBodies int
+	bodies    [][]byte
+	headers   []http.Header
+}
+
+func (h *testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.errors > 0 {
+		h.errors--
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if h.rateLimit {
+		h.rateLimit = false
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "rate limit exceeds", http.StatusTooManyRequests)
+		return
+	}
+
+	if h.status != 0 {
+		http.Error(w, "error", h.status)
+		return
+	}
+
+	if h.maxBodies > 0 && len(h.bodies) >= h.maxBodies {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.bodies = append(h.bodies, data)
+	h.headers = append(h.headers, r.Header)
+}
+
+func (h *testHandler) received() ([][]byte, []http.Header) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.bodies, h.headers
+}
+
+func newTestTransport(t *testing.T, serv *httptest.Server, params map[string]interface{}) *transport {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	params["url"] = serv.URL + "/hook"
+	tr, err := ctor(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tr.(*transport)
+	w.retryInterval = 10 * time.Millisecond
+	return w
+}
+
+func TestSend(t *testing.T) {
+	t.Run("Deliver", testSendDeliver)
+	t.Run("All", testSendAll)
+	t.Run("Partial", testSendPartial)
+	t.Run("Retry", testSendRetry)
+	t.Run("Rate", testSendRate)
+	t.Run("GiveUp", testSendGiveUp)
+	t.Run("Bad", testSendBad)
+	t.Run("Cancel", testSendCancel)
+	t.Run("TLS", testSendTLS)
+}
+
+func testSendDeliver(t *testing.T) {
+	t.Parallel()
+
+	h := new(testHandler)
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, map[string]interface{}{
+		"headers": map[string]interface{}{
+			"Authorization": "Bearer xxx",
+		},
+		"secret": "himitsu",
+	})
+
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1"), newTestAlert("alert2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, headers := h.received()
+	if len(bodies) != 2 {
+		t.Fatal(`len(bodies) != 2`, len(bodies))
+	}
+
+	var a kkok.Alert
+	err = json.Unmarshal(bodies[1], &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "alert2" {
+		t.Error(`a.Title != "alert2"`)
+	}
+
+	hd := headers[0]
+	if hd.Get("Content-Type") != defaultContentType {
+		t.Error(`hd.Get("Content-Type") != defaultContentType`)
+	}
+	if hd.Get("Authorization") != "Bearer xxx" {
+		t.Error(`hd.Get("Authorization") != "Bearer xxx"`)
+	}
+
+	sig := hd.Get(defaultSignatureHeader)
+	if !strings.HasPrefix(sig, "sha256=") {
+		t.Fatal(`!strings.HasPrefix(sig, "sha256=")`, sig)
+	}
+	mac := hmac.New(sha256.New, []byte("himitsu"))
+	mac.Write(bodies[0])
+	if sig[7:] != hex.EncodeToString(mac.Sum(nil)) {
+		t.Error(`signature mismatch`)
+	}
+}
+
+func testSendAll(t *testing.T) {
+	t.Parallel()
+
+	h := new(testHandler)
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, map[string]interface{}{
+		"all":          true,
+		"method":       "PUT",
+		"content_type": "text/plain",
+		"body":         `alerts.map(function(a) { return a.Title }).join("\n")`,
+	})
+
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1"), newTestAlert("alert2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, headers := h.received()
+	if len(bodies) != 1 {
+		t.Fatal(`len(bodies) != 1`, len(bodies))
+	}
+	if string(bodies[0]) != "alert1\nalert2" {
+		t.Error(`string(bodies[0]) != "alert1\nalert2"`, string(bodies[0]))
+	}
+	if headers[0].Get("Content-Type") != "text/plain" {
+		t.Error(`headers[0].Get("Content-Type") != "text/plain"`)
+	}
+}
+
+func testSendPartial(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{maxBodies: 1}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, nil)
+	alerts := []*kkok.Alert{
+		newTestAlert("alert1"),
+		newTestAlert("alert2"),
+		newTestAlert("alert3"),
+	}
+	err := tr.Deliver(alerts)
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+	pe, ok := err.(*kkok.PartialError)
+	if !ok {
+		t.Fatal(`!ok`, err)
+	}
+	if len(pe.Undelivered) != 2 || pe.Undelivered[0] != alerts[1] {
+		t.Error(`len(pe.Undelivered) != 2 || pe.Undelivered[0] != alerts[1]`)
+	}
+
+	// the first alert fails.
+	err = tr.Deliver(alerts[1:])
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+	if _, ok := err.(*kkok.PartialError); ok {
+		t.Error(`err is *kkok.PartialError`)
+	}
+}
+
+func testSendRetry(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{errors: 2}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	// not retried by default.
+	tr := newTestTransport(t, serv, nil)
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err == nil {
+		t.Fatal(`err == nil`)
+	}
+
+	tr = newTestTransport(t, serv, map[string]interface{}{
+		"max_retry": 1,
+	})
+	err = tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, _ := h.received()
+	if len(bodies) != 1 {
+		t.Error(`len(bodies) != 1`, len(bodies))
+	}
+}
+
+func testSendRate(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{rateLimit: true}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, map[string]interface{}{
+		"max_retry": 1,
+	})
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, _ := h.received()
+	if len(bodies) != 1 {
+		t.Error(`len(bodies) != 1`, len(bodies))
+	}
+}
+
+func testSendGiveUp(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{errors: 3}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, map[string]interface{}{
+		"max_retry": 2,
+	})
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSendBad(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{status: http.StatusBadRequest}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, nil)
+	tr.retryInterval = time.Hour
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSendCancel(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{errors: 1}
+	serv := httptest.NewServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, map[string]interface{}{
+		"max_retry": 1,
+	})
+	tr.retryInterval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := tr.DeliverContext(ctx, []*kkok.Alert{newTestAlert("alert1")})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func testSendTLS(t *testing.T) {
+	t.Parallel()
+
+	h := new(testHandler)
+	serv := httptest.NewTLSServer(h)
+	defer serv.Close()
+
+	tr := newTestTransport(t, serv, nil)
+	err := tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	tr = newTestTransport(t, serv, map[string]interface{}{
+		"insecure_skip_verify": true,
+	})
+	err = tr.Deliver([]*kkok.Alert{newTestAlert("alert1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"text/template"
+)
+
+// parseTemplate parses a template file.
+// The template provides "json" function to encode values into JSON.
+func parseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(map[string]interface{}{
+		"json": toJSON,
+	}).ParseFiles(path)
+}
+
+// toJSON encodes v into JSON.  Strings are quoted and escaped.
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
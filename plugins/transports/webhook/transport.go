@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	transportType = "webhook"
+
+	defaultMethod          = "POST"
+	defaultContentType     = "application/json"
+	defaultSignatureHeader = "X-Kkok-Signature"
+	defaultTimeout         = 5 * time.Second
+	defaultRetry           = 0
+	defaultRetryInterval   = 1 * time.Second
+)
+
+type transport struct {
+	label           string
+	url             *url.URL
+	method          string
+	headers         map[string]string
+	contentType     string
+	all             bool
+	tmplPath        string
+	tmpl            *template.Template
+	origBody        string
+	body            *otto.Script
+	secret          string
+	signatureHeader string
+	tls             tlsParams
+	timeout         time.Duration
+	maxRetry        int
+	retryInterval   time.Duration
+	client          *well.HTTPClient
+}
+
+// tlsParams keeps TLS construction parameters to implement Params().
+type tlsParams struct {
+	insecureSkipVerify bool
+	caFile             string
+	certFile           string
+	keyFile            string
+}
+
+func (t *transport) String() string {
+	if len(t.label) > 0 {
+		return t.label
+	}
+
+	return transportType
+}
+
+func (t *transport) Params() kkok.PluginParams {
+	m := map[string]interface{}{
+		"url":          t.url.String(),
+		"method":       t.method,
+		"content_type": t.contentType,
+		"timeout":      int(t.timeout.Seconds()),
+		"max_retry":    t.maxRetry,
+	}
+
+	if len(t.label) > 0 {
+		m["label"] = t.label
+	}
+	if len(t.headers) > 0 {
+		h := make(map[string]interface{}, len(t.headers))
+		for k, v := range t.headers {
+			h[k] = v
+		}
+		m["headers"] = h
+	}
+	if t.all {
+		m["all"] = t.all
+	}
+	if len(t.tmplPath) > 0 {
+		m["template"] = t.tmplPath
+	}
+	if len(t.origBody) > 0 {
+		m["body"] = t.origBody
+	}
+	if len(t.secret) > 0 {
+		m["secret"] = t.secret
+		m["signature_header"] = t.signatureHeader
+	}
+	if t.tls.insecureSkipVerify {
+		m["insecure_skip_verify"] = true
+	}
+	if len(t.tls.caFile) > 0 {
+		m["ca_file"] = t.tls.caFile
+	}
+	if len(t.tls.certFile) > 0 {
+		m["cert_file"] = t.tls.certFile
+		m["key_file"] = t.tls.keyFile
+	}
+
+	return kkok.PluginParams{
+		Type:   transportType,
+		Params: m,
+	}
+}
+
+// render generates a request body from data.
+// data is either *kkok.Alert or []*kkok.Alert.
+func (t *transport) render(data interface{}) ([]byte, error) {
+	switch {
+	case t.tmpl != nil:
+		buf := new(bytes.Buffer)
+		err := t.tmpl.Execute(buf, data)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case t.body != nil:
+		var v otto.Value
+		var err error
+		switch d := data.(type) {
+		case *kkok.Alert:
+			v, err = kkok.NewVM().EvalAlert(d, t.body)
+		case []*kkok.Alert:
+			v, err = kkok.NewVM().EvalAlerts(d, t.body)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v.IsString() {
+			return []byte(v.String()), nil
+		}
+		e, err := v.Export()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(e)
+	}
+
+	return json.Marshal(data)
+}
+
+// sign returns the value of the signature header for body.
+func (t *transport) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(t.secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (t *transport) Deliver(alerts []*kkok.Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+func (t *transport) DeliverContext(ctx context.Context, alerts []*kkok.Alert) error {
+	if t.all {
+		return t.deliver(ctx, alerts)
+	}
+
+	for i, a := range alerts {
+		err := t.deliver(ctx, a)
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			return &kkok.PartialError{Err: err, Undelivered: alerts[i:]}
+		}
+	}
+	return nil
+}
+
+func (t *transport) deliver(ctx context.Context, data interface{}) error {
+	body, err := t.render(data)
+	if err != nil {
+		return errors.Wrap(err, t.String())
+	}
+	err = t.send(ctx, body)
+	if err != nil {
+		return errors.Wrap(err, t.String())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/kkok"
+)
+
+func TestTransport(t *testing.T) {
+	t.Run("String", testString)
+	t.Run("Render", testRender)
+	t.Run("Sign", testSign)
+}
+
+func testString(t *testing.T) {
+	t.Parallel()
+
+	tr := &transport{}
+	if tr.String() != transportType {
+		t.Error(`tr.String() != transportType`)
+	}
+
+	tr = &transport{
+		label: "label",
+	}
+	if tr.String() != "label" {
+		t.Error(`tr.String() != "label"`)
+	}
+}
+
+func newTestAlert(title string) *kkok.Alert {
+	return &kkok.Alert{
+		From:  "test",
+		Date:  time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC),
+		Host:  "host1",
+		Title: title,
+	}
+}
+
+func testRender(t *testing.T) {
+	t.Parallel()
+
+	a := newTestAlert(`say "hello"`)
+	b := newTestAlert("world")
+
+	tr := &transport{}
+	data, err := tr.render(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"From":"test","Date":"2017-03-04T05:06:07Z","Host":"host1","Title":"say \"hello\"","Routes":null}` {
+		t.Error(`unexpected JSON`, string(data))
+	}
+
+	tr.tmpl, err = parseTemplate("testdata/body.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = tr.render(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"text": "say \"hello\"", "host": "host1"}`+"\n" {
+		t.Error(`unexpected template output`, string(data))
+	}
+
+	tr.tmpl = nil
+	tr.body, err = kkok.CompileJS(`({text: alert.Title, n: 1})`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = tr.render(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"n":1,"text":"say \"hello\""}` {
+		t.Error(`unexpected JS object output`, string(data))
+	}
+
+	tr.body, err = kkok.CompileJS(`alerts.map(function(a) { return a.Title }).join(",")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = tr.render([]*kkok.Alert{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `say "hello",world` {
+		t.Error(`unexpected JS string output`, string(data))
+	}
+}
+
+func testSign(t *testing.T) {
+	t.Parallel()
+
+	// echo -n 'hello' | openssl dgst -sha256 -hmac secret
+	tr := &transport{secret: "secret"}
+	sig := tr.sign([]byte("hello"))
+	if sig != "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b" {
+		t.Error(`unexpected signature`, sig)
+	}
+}
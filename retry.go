@@ -0,0 +1,141 @@
+package kkok
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
+)
+
+// PartialError is an error that transports may return when some of
+// alerts have been delivered before a failure.  Retries and fallbacks
+// deliver only Undelivered alerts to avoid duplicates.
+type PartialError struct {
+	Err         error
+	Undelivered []*Alert
+}
+
+func (e *PartialError) Error() string {
+	return e.Err.Error()
+}
+
+// undelivered returns alerts that have not been delivered due to err.
+func undelivered(alerts []*Alert, err error) []*Alert {
+	if pe, ok := errors.Cause(err).(*PartialError); ok {
+		return pe.Undelivered
+	}
+	return alerts
+}
+
+// RetryPolicy specifies how to retry failed deliveries of alerts.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of delivery attempts
+	// including the first one.  Values less than 2 disable retries.
+	MaxAttempts int
+
+	// Backoff is the duration to wait before the first retry.
+	// The duration doubles for each retry.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum duration between retries.
+	// Zero means no limit.
+	MaxBackoff time.Duration
+
+	// Deadline is the duration since the first failure after which
+	// no more retries are attempted.  Zero means no deadline.
+	Deadline time.Duration
+}
+
+// Enabled returns true if the policy allows retries.
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Retry retries to deliver alerts through t after the first attempt
+// has failed with err.  It returns nil when a retry succeeds, or the
+// last error when the policy gives up or ctx is canceled.
+func (p RetryPolicy) Retry(ctx context.Context, t Transport, alerts []*Alert, err error) error {
+	var deadline time.Time
+	if p.Deadline > 0 {
+		deadline = time.Now().Add(p.Deadline)
+	}
+
+	backoff := p.Backoff
+	for attempt := 2; attempt <= p.MaxAttempts; attempt++ {
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		alerts = undelivered(alerts, err)
+		err = DeliverContext(ctx, t, alerts)
+		if err == nil {
+			return nil
+		}
+
+		log.Warn("[kkok] failed to retry sending alerts", map[string]interface{}{
+			log.FnError: err.Error(),
+			"transport": t.String(),
+			"attempt":   attempt,
+		})
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+
+	return err
+}
+
+// goRetry starts a goroutine to retry delivery through t of the route.
+// Alerts are kept as dead letters if retries are given up.
+func (k *Kkok) goRetry(route string, t Transport, alerts []*Alert, err error) {
+	well.Go(func(ctx context.Context) error {
+		err = k.retry.Retry(ctx, t, alerts, err)
+		k.history.delivered(route, t, alerts, err)
+		if err == nil {
+			k.incidents.delivered(alerts)
+			metricDeliveries.WithLabelValues(route, t.String()).Inc()
+			log.Info("[kkok] sent alerts after retries", map[string]interface{}{
+				"route":     route,
+				"transport": t.String(),
+				"nalerts":   len(alerts),
+			})
+			return nil
+		}
+
+		rest := undelivered(alerts, err)
+		if ctx.Err() != nil {
+			// dead letters do not survive the shutdown.
+			for _, a := range rest {
+				log.Error("[kkok] dropped alert at shutdown", map[string]interface{}{
+					log.FnError: err.Error(),
+					"route":     route,
+					"transport": t.String(),
+					"id":        a.ID,
+					"from":      a.From,
+					"host":      a.Host,
+					"title":     a.Title,
+				})
+			}
+			return nil
+		}
+
+		log.Error("[kkok] gave up sending alerts", map[string]interface{}{
+			log.FnError: err.Error(),
+			"route":     route,
+			"transport": t.String(),
+			"nalerts":   len(alerts),
+		})
+		k.deadLetters.add(route, t, rest, err)
+		return nil
+	})
+}
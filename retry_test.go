@@ -0,0 +1,181 @@
+package kkok
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failTransport struct {
+	nfail    int
+	attempts int
+}
+
+func (t *failTransport) Params() PluginParams {
+	return PluginParams{
+		Type:   "fail",
+		Params: make(map[string]interface{}),
+	}
+}
+
+func (t *failTransport) String() string {
+	return "fail"
+}
+
+func (t *failTransport) Deliver(alerts []*Alert) error {
+	t.attempts++
+	if t.attempts <= t.nfail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+// partialTransport delivers only the first alert at each attempt.
+type partialTransport struct {
+	delivered []*Alert
+}
+
+func (t *partialTransport) Params() PluginParams {
+	return PluginParams{
+		Type:   "partial",
+		Params: make(map[string]interface{}),
+	}
+}
+
+func (t *partialTransport) String() string {
+	return "partial"
+}
+
+func (t *partialTransport) Deliver(alerts []*Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	t.delivered = append(t.delivered, alerts[0])
+	if len(alerts) == 1 {
+		return nil
+	}
+	return &PartialError{
+		Err:         errors.New("partially failed"),
+		Undelivered: alerts[1:],
+	}
+}
+
+func testRetrySuccess(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}
+
+	tr := &failTransport{nfail: 2}
+	err := tr.Deliver(nil)
+	err = p.Retry(context.Background(), tr, nil, err)
+	if err != nil {
+		t.Error(err)
+	}
+	if tr.attempts != 3 {
+		t.Error(`tr.attempts != 3`)
+	}
+}
+
+func testRetryGiveUp(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}
+
+	tr := &failTransport{nfail: 3}
+	err := tr.Deliver(nil)
+	err = p.Retry(context.Background(), tr, nil, err)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+	if tr.attempts != 3 {
+		t.Error(`tr.attempts != 3`)
+	}
+}
+
+func testRetryDeadline(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     10 * time.Millisecond,
+		MaxBackoff:  40 * time.Millisecond,
+		Deadline:    100 * time.Millisecond,
+	}
+
+	// retries at 10, 30, 70 milliseconds, then 110 exceeds the deadline.
+	tr := &failTransport{nfail: 100}
+	err := tr.Deliver(nil)
+	err = p.Retry(context.Background(), tr, nil, err)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+	if tr.attempts != 4 {
+		t.Error(`tr.attempts != 4`, tr.attempts)
+	}
+}
+
+func testRetryCancel(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tr := &failTransport{nfail: 1}
+	err := tr.Deliver(nil)
+	err = p.Retry(ctx, tr, nil, err)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+	if tr.attempts != 1 {
+		t.Error(`tr.attempts != 1`)
+	}
+}
+
+func testRetryPartial(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}
+
+	alerts := []*Alert{{Title: "a1"}, {Title: "a2"}, {Title: "a3"}}
+	tr := &partialTransport{}
+	err := tr.Deliver(alerts)
+	err = p.Retry(context.Background(), tr, alerts, err)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(tr.delivered) != 3 {
+		t.Fatal(`len(tr.delivered) != 3`, len(tr.delivered))
+	}
+	for i, a := range alerts {
+		if tr.delivered[i] != a {
+			t.Error(`tr.delivered[i] != a`, i)
+		}
+	}
+
+	if len(undelivered(alerts, errors.New("failed"))) != 3 {
+		t.Error(`len(undelivered(alerts, errors.New("failed"))) != 3`)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("Success", testRetrySuccess)
+	t.Run("GiveUp", testRetryGiveUp)
+	t.Run("Deadline", testRetryDeadline)
+	t.Run("Cancel", testRetryCancel)
+	t.Run("Partial", testRetryPartial)
+}
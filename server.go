@@ -1,10 +1,14 @@
 package kkok
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -119,7 +123,15 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// End-points other than /version require authentication.
+	// Webhooks verify requests by themselves.
+	if strings.HasPrefix(p, "/webhooks/") {
+		if wh := a.k.webhooks.get(p[10:]); wh != nil {
+			a.postWebhook(w, r, wh)
+			return
+		}
+	}
+
+	// End-points other than /version and webhooks require authentication.
 	if !a.authenticate(w, r) {
 		return
 	}
@@ -129,6 +141,32 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasPrefix(p, "/alerts/") {
+		id, action := getID(p[8:])
+		a.handleAlertAction(w, r, id, action)
+		return
+	}
+
+	if p == "/webhooks/alertmanager" {
+		a.postAlertmanager(w, r)
+		return
+	}
+
+	if p == "/incidents" {
+		a.getIncidents(w, r)
+		return
+	}
+
+	if p == "/escalations" {
+		a.getEscalations(w, r)
+		return
+	}
+
+	if p == "/metrics" {
+		a.handleMetrics(w, r)
+		return
+	}
+
 	if p == "/filters" {
 		a.getFilters(w, r)
 		return
@@ -162,6 +200,22 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if p == "/history" {
+		a.getHistory(w, r)
+		return
+	}
+
+	if p == "/deadletters" {
+		a.handleDeadLetters(w, r)
+		return
+	}
+
+	if strings.HasPrefix(p, "/deadletters/") {
+		id, action := getID(p[13:])
+		a.handleDeadLetterAction(w, r, id, action)
+		return
+	}
+
 	http.Error(w, "not found", http.StatusNotFound)
 }
 
@@ -177,22 +231,95 @@ func (a *apiHandler) handleAlerts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *apiHandler) getAlerts(w http.ResponseWriter, r *http.Request) {
-	alerts := a.d.peek()
+	alerts := a.d.Peek()
 	sendJSON(w, r, alerts)
 }
 
+// postResult is the result of posting an alert.
+type postResult struct {
+	ID          string `json:"id,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// decodeAlerts decodes a JSON object, a JSON array of objects, or
+// a stream of newline-delimited JSON objects into alerts.
+// batch is false only if data is a single object and
+// the content type is not NDJSON.
+func decodeAlerts(data []byte, ndjson bool) (alerts []*Alert, batch bool, err error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &alerts)
+		return alerts, true, err
+	}
+
+	if !ndjson {
+		alert := new(Alert)
+		err = json.Unmarshal(data, alert)
+		return []*Alert{alert}, false, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		alert := new(Alert)
+		err = dec.Decode(alert)
+		if err == io.EOF {
+			return alerts, true, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		alerts = append(alerts, alert)
+	}
+}
+
 func (a *apiHandler) postAlert(w http.ResponseWriter, r *http.Request) {
-	alert := new(Alert)
-	if !recvJSON(w, r, alert) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONLength))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err := alert.Validate()
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	alerts, batch, err := decodeAlerts(data, mt == "application/x-ndjson")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		et := err.Error()
+		fields := well.FieldsFromContext(r.Context())
+		fields[log.FnError] = et
+		log.Error("json.Unmarshal", fields)
+		http.Error(w, et, http.StatusBadRequest)
 		return
 	}
 
+	if !batch {
+		res := a.postOneAlert(r, alerts[0], "api")
+		if len(res.Error) > 0 {
+			http.Error(w, res.Error, http.StatusBadRequest)
+			return
+		}
+		sendJSON(w, r, res)
+		return
+	}
+
+	results := make([]postResult, 0, len(alerts))
+	for _, alert := range alerts {
+		results = append(results, a.postOneAlert(r, alert, "api"))
+	}
+	sendJSON(w, r, results)
+}
+
+// postOneAlert validates and posts an alert sent via r.
+// source is used to count posted alerts for each source.
+func (a *apiHandler) postOneAlert(r *http.Request, alert *Alert, source string) postResult {
+	if alert == nil {
+		return postResult{Error: "null alert"}
+	}
+
+	err := alert.Validate()
+	if err != nil {
+		return postResult{Error: err.Error()}
+	}
+
 	if alert.Date.IsZero() {
 		alert.Date = time.Now().UTC()
 	}
@@ -205,15 +332,69 @@ func (a *apiHandler) postAlert(w http.ResponseWriter, r *http.Request) {
 		alert.Host = h
 	}
 
+	alert.ID = newAlertID()
+	alert.Fingerprint = ""
 	alert.Routes = nil
 	alert.Sub = nil
+	alert.Escalation = ""
+	alert.Incident = nil
 
-	a.d.put(alert)
+	a.d.PostFrom(source, alert)
 
 	fields := well.FieldsFromContext(r.Context())
+	fields["alert_id"] = alert.ID
 	fields["from"] = alert.From
 	fields["title"] = alert.Title
 	log.Info("new alert", fields)
+
+	return postResult{
+		ID:          alert.ID,
+		Fingerprint: alert.Fingerprint,
+	}
+}
+
+func (a *apiHandler) handleAlertAction(w http.ResponseWriter, r *http.Request, id, action string) {
+	if action != "ack" {
+		http.Error(w, "no such alert action: "+action, http.StatusBadRequest)
+		return
+	}
+
+	if getMethod(r) != "POST" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := a.k.AckAlert(id)
+	if err != nil {
+		http.NotFound(w, r)
+	}
+}
+
+func (a *apiHandler) getEscalations(w http.ResponseWriter, r *http.Request) {
+	if getMethod(r) != "GET" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendJSON(w, r, a.k.Escalations())
+}
+
+func (a *apiHandler) getIncidents(w http.ResponseWriter, r *http.Request) {
+	if getMethod(r) != "GET" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendJSON(w, r, a.k.Incidents())
+}
+
+func (a *apiHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if getMethod(r) != "GET" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	metricsHandler.ServeHTTP(w, r)
 }
 
 func (a *apiHandler) getFilters(w http.ResponseWriter, r *http.Request) {
@@ -278,7 +459,18 @@ func (a *apiHandler) putFilter(w http.ResponseWriter, r *http.Request, id string
 		return
 	}
 
-	a.k.PutFilter(f)
+	err = a.k.PutFilter(f)
+	if err != nil {
+		a.stateError(w, r, err)
+	}
+}
+
+func (a *apiHandler) stateError(w http.ResponseWriter, r *http.Request, err error) {
+	et := err.Error()
+	fields := well.FieldsFromContext(r.Context())
+	fields[log.FnError] = et
+	log.Error("failed to save the state", fields)
+	http.Error(w, et, http.StatusInternalServerError)
 }
 
 func (a *apiHandler) deleteFilter(w http.ResponseWriter, r *http.Request, id string) {
@@ -307,21 +499,33 @@ func (a *apiHandler) handleFilterAction(w http.ResponseWriter, r *http.Request,
 	case "disable":
 		f.Enable(false)
 	case "inactivate":
-		a.inactivateFilter(w, r, f)
+		if !a.inactivateFilter(w, r, f) {
+			return
+		}
 	default:
 		http.Error(w, "no such filter action: "+action, http.StatusBadRequest)
+		return
+	}
+
+	if !f.Dynamic() {
+		return
+	}
+	err := a.k.saveState()
+	if err != nil {
+		a.stateError(w, r, err)
 	}
 }
 
-func (a *apiHandler) inactivateFilter(w http.ResponseWriter, r *http.Request, f Filter) {
+func (a *apiHandler) inactivateFilter(w http.ResponseWriter, r *http.Request, f Filter) bool {
 	var payload struct {
 		Until time.Time `json:"until"`
 	}
 	if !recvJSON(w, r, &payload) {
-		return
+		return false
 	}
 
 	f.Inactivate(payload.Until)
+	return true
 }
 
 func (a *apiHandler) getRoutes(w http.ResponseWriter, r *http.Request) {
@@ -339,6 +543,8 @@ func (a *apiHandler) handleRoute(w http.ResponseWriter, r *http.Request, id stri
 		a.getRoute(w, r, id)
 	case "PUT":
 		a.putRoute(w, r, id)
+	case "DELETE":
+		a.deleteRoute(w, r, id)
 	default:
 		http.Error(w, "bad method", http.StatusMethodNotAllowed)
 	}
@@ -385,7 +591,99 @@ func (a *apiHandler) putRoute(w http.ResponseWriter, r *http.Request, id string)
 		route[i] = tr
 	}
 
-	a.k.AddRoute(id, route)
+	_, err := chainTransports(route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = a.k.PutRoute(id, route)
+	if err != nil {
+		a.stateError(w, r, err)
+	}
+}
+
+func (a *apiHandler) deleteRoute(w http.ResponseWriter, r *http.Request, id string) {
+	err := a.k.removeRoute(id)
+	if err == nil {
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (a *apiHandler) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	switch getMethod(r) {
+	case "GET":
+		sendJSON(w, r, a.k.DeadLetters())
+	case "DELETE":
+		a.k.PurgeDeadLetters()
+	default:
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *apiHandler) handleDeadLetterAction(w http.ResponseWriter, r *http.Request, sid, action string) {
+	id, err := strconv.ParseUint(sid, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid dead letter id: "+sid, http.StatusBadRequest)
+		return
+	}
+
+	if action != "retry" {
+		http.Error(w, "no such dead letter action: "+action, http.StatusBadRequest)
+		return
+	}
+
+	if getMethod(r) != "POST" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err = a.k.RetryDeadLetter(r.Context(), id)
+	switch {
+	case err == nil:
+	case err == errDeadLetterNotFound:
+		http.NotFound(w, r)
+	default:
+		et := err.Error()
+		fields := well.FieldsFromContext(r.Context())
+		fields["dead_letter"] = id
+		fields[log.FnError] = et
+		log.Error("failed to retry a dead letter", fields)
+		http.Error(w, et, http.StatusInternalServerError)
+	}
+}
+
+func (a *apiHandler) getHistory(w http.ResponseWriter, r *http.Request) {
+	if getMethod(r) != "GET" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	v := r.URL.Query()
+	q := &HistoryQuery{
+		From:  v.Get("from"),
+		Host:  v.Get("host"),
+		Route: v.Get("route"),
+	}
+
+	var err error
+	if s := v.Get("since"); len(s) > 0 {
+		q.Since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, "invalid since: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+	if s := v.Get("until"); len(s) > 0 {
+		q.Until, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, "invalid until: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+
+	sendJSON(w, r, a.k.SearchHistory(q))
 }
 
 // NewHTTPServer returns *well.HTTPServer for REST API.
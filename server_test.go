@@ -2,6 +2,7 @@ package kkok
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"mime"
@@ -16,7 +17,7 @@ type testAlertHandler struct {
 	alerts []*Alert
 }
 
-func (h *testAlertHandler) Handle(alerts []*Alert) {
+func (h *testAlertHandler) Handle(ctx context.Context, alerts []*Alert) {
 	h.alerts = alerts
 }
 
@@ -144,7 +145,7 @@ func testServerAlertsGet(t *testing.T) {
 
 	r := httptest.NewRequest("GET", "http://localhost/alerts", nil)
 	d := NewDispatcher(0, 0, new(testAlertHandler))
-	d.put(&Alert{
+	d.Post(&Alert{
 		From:  "hoge",
 		Host:  "localhost",
 		Title: "aaa",
@@ -190,8 +191,10 @@ func testServerAlertsPost(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatal(`w.Code != http.StatusOK`)
 	}
+	var res map[string]string
+	testRecvJSON(t, w, &res)
 
-	alerts := d.take()
+	alerts := d.pool.Take()
 	if len(alerts) != 1 {
 		t.Fatal(`len(alerts) != 1`)
 	}
@@ -219,6 +222,12 @@ func testServerAlertsPost(t *testing.T) {
 	if a.Stats != nil {
 		t.Error(`a.Stats != nil`)
 	}
+	if a.ID != res["id"] {
+		t.Error(`a.ID != res["id"]`)
+	}
+	if len(a.Fingerprint) == 0 || a.Fingerprint != res["fingerprint"] {
+		t.Error(`len(a.Fingerprint) == 0 || a.Fingerprint != res["fingerprint"]`)
+	}
 
 	j2 := `{
     "From": "fuga",
@@ -231,7 +240,7 @@ func testServerAlertsPost(t *testing.T) {
 		t.Fatal(`w.Code != http.StatusOK`)
 	}
 
-	alerts = d.take()
+	alerts = d.pool.Take()
 	if len(alerts) != 1 {
 		t.Fatal(`len(alerts) != 1`)
 	}
@@ -244,6 +253,68 @@ func testServerAlertsPost(t *testing.T) {
 	}
 }
 
+func testServerAlertsBatch(t *testing.T) {
+	t.Parallel()
+
+	j := `[
+    {"From": "from1", "Title": "title1"},
+    {"From": "from2"},
+    {"From": "from3", "Title": "title3", "Host": "host3"}
+]`
+	r := jsonRequest("POST", "/alerts", j)
+	d := NewDispatcher(0, 0, new(testAlertHandler))
+	w := recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var res []postResult
+	testRecvJSON(t, w, &res)
+	if len(res) != 3 {
+		t.Fatal(`len(res) != 3`)
+	}
+	if len(res[0].ID) == 0 || len(res[0].Error) != 0 {
+		t.Error(`len(res[0].ID) == 0 || len(res[0].Error) != 0`)
+	}
+	if len(res[1].ID) != 0 || len(res[1].Error) == 0 {
+		t.Error(`len(res[1].ID) != 0 || len(res[1].Error) == 0`)
+	}
+
+	alerts := d.pool.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+	if alerts[0].ID != res[0].ID {
+		t.Error(`alerts[0].ID != res[0].ID`)
+	}
+	if alerts[1].ID != res[2].ID {
+		t.Error(`alerts[1].ID != res[2].ID`)
+	}
+
+	nd := `{"From": "from1", "Title": "title1"}
+{"From": "from2", "Title": "title2"}
+`
+	r = jsonRequest("POST", "/alerts", nd)
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	res = nil
+	testRecvJSON(t, w, &res)
+	if len(res) != 2 {
+		t.Fatal(`len(res) != 2`)
+	}
+	if d.pool.Len() != 2 {
+		t.Error(`d.pool.Len() != 2`)
+	}
+
+	r = jsonRequest("POST", "/alerts", `[{"From": "from1", "Title": "title1"},`)
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(`w.Code != http.StatusBadRequest`)
+	}
+}
+
 func testServerAlertsBad(t *testing.T) {
 	t.Parallel()
 
@@ -263,6 +334,58 @@ func hasString(s string, ss []string) bool {
 	return false
 }
 
+func testServerAlertsAck(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddEscalationPolicy("p1", []EscalationStep{{Route: "r1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Handle(context.Background(), []*Alert{{ID: "a1", Escalation: "p1"}})
+
+	r := httptest.NewRequest("GET", "http://localhost/escalations", nil)
+	w := recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var escalations []*Escalation
+	testRecvJSON(t, w, &escalations)
+	if len(escalations) != 1 {
+		t.Fatal(`len(escalations) != 1`)
+	}
+	if escalations[0].Alert.ID != "a1" {
+		t.Error(`escalations[0].Alert.ID != "a1"`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/alerts/a1/ack", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/alerts/a1/foo", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(`w.Code != http.StatusBadRequest`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/alerts/a1/ack", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Error(`w.Code != http.StatusOK`)
+	}
+	if len(k.Escalations()) != 0 {
+		t.Error(`len(k.Escalations()) != 0`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/alerts/a1/ack", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusNotFound {
+		t.Error(`w.Code != http.StatusNotFound`)
+	}
+}
+
 func testServerFiltersGet(t *testing.T) {
 	t.Parallel()
 
@@ -673,13 +796,395 @@ func testServerRoutesIDPut(t *testing.T) {
 	}
 }
 
+func testServerRoutesIDDelete(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddRoute("r1", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.PutRoute("r2", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("DELETE", "http://localhost/routes/nosuchroute", nil)
+	w := recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Error(`w.Code != http.StatusOK`)
+	}
+
+	if len(k.RouteIDs()) != 2 {
+		t.Error(`len(k.RouteIDs()) != 2`)
+	}
+
+	// static route r1 cannot be removed
+	r = httptest.NewRequest("DELETE", "http://localhost/routes/r1", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Error(`w.Code != http.StatusInternalServerError`)
+	}
+
+	if len(k.RouteIDs()) != 2 {
+		t.Error(`len(k.RouteIDs()) != 2`)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://localhost/routes/r2", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Error(`w.Code != http.StatusOK`)
+	}
+
+	ids := k.RouteIDs()
+	if len(ids) != 1 {
+		t.Fatal(`len(ids) != 1`)
+	}
+	if ids[0] != "r1" {
+		t.Error(`ids[0] != "r1"`)
+	}
+}
+
+func testServerHistory(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	err := k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.AddRoute("r1", []Transport{&testTransport{}})
+
+	k.Handle(context.Background(), []*Alert{
+		{From: "from1", Host: "host1", Title: "title1"},
+		{From: "from2", Host: "host2", Title: "title2"},
+	})
+
+	r := httptest.NewRequest("GET", "http://localhost/history?from=from2&route=r1", nil)
+	w := recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var entries []*HistoryEntry
+	testRecvJSON(t, w, &entries)
+	if len(entries) != 1 {
+		t.Fatal(`len(entries) != 1`)
+	}
+	e := entries[0]
+	if e.Alert.Title != "title2" {
+		t.Error(`e.Alert.Title != "title2"`)
+	}
+	if len(e.Filters) != 1 || e.Filters[0] != "f" {
+		t.Error(`len(e.Filters) != 1 || e.Filters[0] != "f"`)
+	}
+	if len(e.Deliveries) != 1 || e.Deliveries[0].Route != "r1" {
+		t.Error(`len(e.Deliveries) != 1 || e.Deliveries[0].Route != "r1"`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/history?since=2000-01-01T00:00:00Z&until=2001-01-01T00:00:00Z", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	testRecvJSON(t, w, &entries)
+	if len(entries) != 0 {
+		t.Error(`len(entries) != 0`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/history?since=yesterday", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(`w.Code != http.StatusBadRequest`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/history", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+}
+
+func testServerDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	err := k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &failTransport{nfail: 2}
+	k.AddRoute("r1", []Transport{tr})
+
+	k.Handle(context.Background(), []*Alert{{From: "from1", Title: "title1"}})
+
+	r := httptest.NewRequest("GET", "http://localhost/deadletters", nil)
+	w := recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var letters []*DeadLetter
+	testRecvJSON(t, w, &letters)
+	if len(letters) != 1 {
+		t.Fatal(`len(letters) != 1`)
+	}
+	d := letters[0]
+	if d.Route != "r1" {
+		t.Error(`d.Route != "r1"`)
+	}
+	if d.Transport != "fail" {
+		t.Error(`d.Transport != "fail"`)
+	}
+	if d.Alert.Title != "title1" {
+		t.Error(`d.Alert.Title != "title1"`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/deadletters/abc/retry", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(`w.Code != http.StatusBadRequest`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/deadletters/100/retry", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusNotFound {
+		t.Error(`w.Code != http.StatusNotFound`)
+	}
+
+	// the second attempt fails
+	r = httptest.NewRequest("POST", "http://localhost/deadletters/1/retry", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Error(`w.Code != http.StatusInternalServerError`)
+	}
+	if len(k.DeadLetters()) != 1 {
+		t.Error(`len(k.DeadLetters()) != 1`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/deadletters/1/retry", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Error(`w.Code != http.StatusOK`)
+	}
+	if len(k.DeadLetters()) != 0 {
+		t.Error(`len(k.DeadLetters()) != 0`)
+	}
+
+	tr.attempts = 0
+	k.Handle(context.Background(), []*Alert{{From: "from2", Title: "title2"}})
+	if len(k.DeadLetters()) != 1 {
+		t.Error(`len(k.DeadLetters()) != 1`)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://localhost/deadletters", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Error(`w.Code != http.StatusOK`)
+	}
+	if len(k.DeadLetters()) != 0 {
+		t.Error(`len(k.DeadLetters()) != 0`)
+	}
+}
+
+func testServerAlertmanager(t *testing.T) {
+	t.Parallel()
+
+	j := `{
+    "version": "4",
+    "receiver": "kkok",
+    "status": "firing",
+    "alerts": [
+        {
+            "status": "firing",
+            "labels": {"alertname": "HighLoad", "job": "node", "instance": "host1:9100", "severity": "critical"},
+            "annotations": {"summary": "high load on host1", "description": "load is 100"},
+            "startsAt": "2011-02-03T04:05:06Z",
+            "endsAt": "0001-01-01T00:00:00Z",
+            "generatorURL": "http://prometheus/graph"
+        },
+        {
+            "status": "resolved",
+            "labels": {"alertname": "DiskFull"},
+            "annotations": {},
+            "startsAt": "2011-02-03T04:05:06Z",
+            "endsAt": "2011-02-03T05:05:06Z"
+        }
+    ]
+}`
+	r := jsonRequest("POST", "/webhooks/alertmanager", j)
+	d := NewDispatcher(0, 0, new(testAlertHandler))
+	w := recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var res []map[string]string
+	testRecvJSON(t, w, &res)
+	if len(res) != 2 {
+		t.Fatal(`len(res) != 2`)
+	}
+
+	alerts := d.pool.Take()
+	if len(alerts) != 2 {
+		t.Fatal(`len(alerts) != 2`)
+	}
+
+	a := alerts[0]
+	if a.ID != res[0]["id"] {
+		t.Error(`a.ID != res[0]["id"]`)
+	}
+	if a.From != "node" {
+		t.Error(`a.From != "node"`)
+	}
+	if a.Host != "host1" {
+		t.Error(`a.Host != "host1"`)
+	}
+	if a.Title != "high load on host1" {
+		t.Error(`a.Title != "high load on host1"`)
+	}
+	if a.Message != "load is 100" {
+		t.Error(`a.Message != "load is 100"`)
+	}
+	if a.Status != StatusFiring {
+		t.Error(`a.Status != StatusFiring`)
+	}
+	if !a.Date.Equal(time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC)) {
+		t.Error(`wrong date for firing alert`)
+	}
+	labels, ok := a.Info["labels"].(map[string]interface{})
+	if !ok {
+		t.Fatal(`labels, ok := a.Info["labels"].(map[string]interface{}); !ok`)
+	}
+	if labels["severity"] != "critical" {
+		t.Error(`labels["severity"] != "critical"`)
+	}
+	if a.Info["generator_url"] != "http://prometheus/graph" {
+		t.Error(`a.Info["generator_url"] != "http://prometheus/graph"`)
+	}
+
+	a = alerts[1]
+	if a.From != "alertmanager" {
+		t.Error(`a.From != "alertmanager"`)
+	}
+	if a.Title != "DiskFull" {
+		t.Error(`a.Title != "DiskFull"`)
+	}
+	if a.Host != "192.0.2.1" {
+		t.Error(`a.Host != "192.0.2.1"`)
+	}
+	if !a.Resolved() {
+		t.Error(`!a.Resolved()`)
+	}
+	if !a.Date.Equal(time.Date(2011, 2, 3, 5, 5, 6, 0, time.UTC)) {
+		t.Error(`wrong date for resolved alert`)
+	}
+
+	// an alert without title does not reject the others.
+	j = `{"alerts": [{"status": "firing", "labels": {"job": "node"}},
+                     {"status": "firing", "labels": {"alertname": "HighLoad"}}]}`
+	r = jsonRequest("POST", "/webhooks/alertmanager", j)
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	res = nil
+	testRecvJSON(t, w, &res)
+	if len(res) != 2 {
+		t.Fatal(`len(res) != 2`)
+	}
+	if len(res[0]["error"]) == 0 || len(res[0]["id"]) != 0 {
+		t.Error(`invalid alert is accepted`, res[0])
+	}
+	if len(res[1]["id"]) == 0 {
+		t.Error(`valid alert is rejected`, res[1])
+	}
+	if d.pool.Len() != 1 {
+		t.Error(`d.pool.Len() != 1`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/webhooks/alertmanager", nil)
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+}
+
+func testServerIncidents(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	k.Handle(context.Background(), []*Alert{
+		{ID: "a1", From: "from1", Title: "title1", Fingerprint: "fp1"},
+		{ID: "a2", From: "from2", Title: "title2", Fingerprint: "fp2"},
+		{ID: "a3", From: "from2", Title: "title2", Fingerprint: "fp2", Status: StatusResolved},
+	})
+
+	r := httptest.NewRequest("GET", "http://localhost/incidents", nil)
+	w := recordWithKkok(k, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var incidents []*Incident
+	testRecvJSON(t, w, &incidents)
+	if len(incidents) != 1 {
+		t.Fatal(`len(incidents) != 1`)
+	}
+	if incidents[0].AlertID != "a1" {
+		t.Error(`incidents[0].AlertID != "a1"`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/incidents", nil)
+	w = recordWithKkok(k, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+}
+
+func testServerMetrics(t *testing.T) {
+	t.Parallel()
+
+	d := NewDispatcher(0, 0, new(testAlertHandler))
+	r := jsonRequest("POST", "/alerts", `{"From": "from1", "Title": "metrics"}`)
+	w := recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/metrics", nil)
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `kkok_alerts_posted_total{source="api"}`) {
+		t.Error(`no kkok_alerts_posted_total for api`)
+	}
+	if !strings.Contains(body, `kkok_pool_alerts`) {
+		t.Error(`no kkok_pool_alerts`)
+	}
+
+	r = httptest.NewRequest("POST", "http://localhost/metrics", nil)
+	w = recordWithDispatcher(d, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+}
+
 func TestServer(t *testing.T) {
 	t.Run("Version/Get", testServerVersionGet)
 	t.Run("Version/OverrideGet", testServerVersionOverrideGet)
 	t.Run("AuthToken", testServerAuthToken)
 	t.Run("Alerts/Get", testServerAlertsGet)
 	t.Run("Alerts/Post", testServerAlertsPost)
+	t.Run("Alerts/Batch", testServerAlertsBatch)
 	t.Run("Alerts/Bad", testServerAlertsBad)
+	t.Run("Alerts/Ack", testServerAlertsAck)
+	t.Run("Webhooks/Alertmanager", testServerAlertmanager)
 	t.Run("Filters/Get", testServerFiltersGet)
 	t.Run("Filters/ID/Get", testServerFiltersIDGet)
 	t.Run("Filters/ID/Put", testServerFiltersIDPut)
@@ -688,4 +1193,9 @@ func TestServer(t *testing.T) {
 	t.Run("Routes/Get", testServerRoutesGet)
 	t.Run("Routes/ID/Get", testServerRoutesIDGet)
 	t.Run("Routes/ID/Put", testServerRoutesIDPut)
+	t.Run("Routes/ID/Delete", testServerRoutesIDDelete)
+	t.Run("History", testServerHistory)
+	t.Run("DeadLetters", testServerDeadLetters)
+	t.Run("Incidents", testServerIncidents)
+	t.Run("Metrics", testServerMetrics)
 }
@@ -0,0 +1,235 @@
+package kkok
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/pkg/errors"
+)
+
+// state is the content of the state file.
+type state struct {
+	// Filters are parameters of dynamic filters including "id".
+	Filters []PluginParams `json:"filters"`
+
+	// Routes maps IDs of dynamic routes to transport parameters.
+	Routes map[string][]PluginParams `json:"routes"`
+}
+
+// SetStateFile sets the filename to save dynamically added filters
+// and routes, then restores them from the file, if any.
+//
+// Dynamic filters that have expired or whose IDs conflict with
+// static filters are not restored.  Likewise, dynamic routes whose
+// IDs conflict with static routes are not restored.  This should be
+// called after static filters and routes are added.
+func (k *Kkok) SetStateFile(filename string) error {
+	k.lks.Lock()
+	defer k.lks.Unlock()
+
+	k.stateFile = filename
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "state")
+	}
+
+	var st state
+	err = json.Unmarshal(data, &st)
+	if err != nil {
+		return errors.Wrap(err, "state: "+filename)
+	}
+
+	for _, pp := range st.Filters {
+		f, err := restoreFilter(pp)
+		if err != nil {
+			log.Error("[kkok] failed to restore a filter", map[string]interface{}{
+				log.FnError: err.Error(),
+				"filter":    pp.Params["id"],
+			})
+			continue
+		}
+		if f == nil {
+			continue
+		}
+
+		k.lkf.Lock()
+		dup := false
+		for _, f2 := range k.filters {
+			if f2.ID() == f.ID() {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			k.filters = append(k.filters, f)
+		}
+		k.lkf.Unlock()
+
+		if dup {
+			log.Warn("[kkok] ignored a saved filter with duplicate id", map[string]interface{}{
+				"filter": f.ID(),
+			})
+		}
+	}
+
+	for id, pl := range st.Routes {
+		transports, err := restoreRoute(pl)
+		if err != nil {
+			log.Error("[kkok] failed to restore a route", map[string]interface{}{
+				log.FnError: err.Error(),
+				"route":     id,
+			})
+			continue
+		}
+
+		r, err := newRoute(transports, true)
+		if err != nil {
+			log.Error("[kkok] failed to restore a route", map[string]interface{}{
+				log.FnError: err.Error(),
+				"route":     id,
+			})
+			continue
+		}
+
+		k.lkr.Lock()
+		_, dup := k.routes[id]
+		if !dup {
+			k.routes[id] = r
+		}
+		k.lkr.Unlock()
+
+		if dup {
+			log.Warn("[kkok] ignored a saved route with duplicate id", map[string]interface{}{
+				"route": id,
+			})
+		}
+	}
+
+	return nil
+}
+
+func restoreRoute(pl []PluginParams) ([]Transport, error) {
+	transports := make([]Transport, len(pl))
+	for i, pp := range pl {
+		t, err := NewTransport(pp.Type, pp.Params)
+		if err != nil {
+			return nil, err
+		}
+		transports[i] = t
+	}
+	return transports, nil
+}
+
+// restoreFilter constructs a dynamic filter from saved parameters.
+// This returns nil if the filter has already expired.
+func restoreFilter(pp PluginParams) (Filter, error) {
+	if pp.Params == nil {
+		return nil, errors.New("no filter id")
+	}
+
+	var inactiveUntil time.Time
+	if i, ok := pp.Params["inactive"]; ok {
+		s, ok := i.(string)
+		if !ok {
+			return nil, errors.New("inactive must be a string for RFC3339 time format")
+		}
+		err := inactiveUntil.UnmarshalText([]byte(s))
+		if err != nil {
+			return nil, errors.Wrap(err, "inactive")
+		}
+		delete(pp.Params, "inactive")
+	}
+
+	f, err := NewFilter(pp.Type, pp.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	f.SetDynamic()
+	if f.Expired() {
+		return nil, nil
+	}
+	if !inactiveUntil.IsZero() {
+		f.Inactivate(inactiveUntil)
+	}
+	return f, nil
+}
+
+// saveState saves dynamic filters and routes into the state file.
+// This does nothing if the state file is not set.
+func (k *Kkok) saveState() error {
+	k.lks.Lock()
+	defer k.lks.Unlock()
+
+	if len(k.stateFile) == 0 {
+		return nil
+	}
+
+	var st state
+	st.Filters = []PluginParams{}
+	for _, f := range k.Filters() {
+		if !f.Dynamic() {
+			continue
+		}
+		pp := f.Params()
+		m := make(map[string]interface{}, len(pp.Params)+1)
+		for key, v := range pp.Params {
+			m[key] = v
+		}
+		m["id"] = f.ID()
+		st.Filters = append(st.Filters, PluginParams{pp.Type, m})
+	}
+
+	st.Routes = make(map[string][]PluginParams)
+	k.lkr.Lock()
+	for id, r := range k.routes {
+		if !r.dynamic {
+			continue
+		}
+		pl := make([]PluginParams, len(r.transports))
+		for i, t := range r.transports {
+			pl[i] = t.Params()
+		}
+		st.Routes[id] = pl
+	}
+	k.lkr.Unlock()
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err, "state")
+	}
+
+	err = writeFileAtomic(k.stateFile, data)
+	if err != nil {
+		return errors.Wrap(err, "state")
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file with data atomically.
+func writeFileAtomic(filename string, data []byte) error {
+	tmpname := filename + ".tmp"
+	f, err := os.OpenFile(tmpname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(tmpname)
+		return err
+	}
+
+	return os.Rename(tmpname, filename)
+}
@@ -0,0 +1,240 @@
+package kkok
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newDupFilter(t *testing.T, id string, params map[string]interface{}) Filter {
+	f := &dupFilter{}
+	err := f.Init(id, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testStateRestore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+
+	k := NewKkok()
+	err = k.AddStaticFilter(newDupFilter(t, "static1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.SetStateFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = k.PutFilter(newDupFilter(t, "f1", map[string]interface{}{
+		"label": "label1",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f2 := newDupFilter(t, "f2", nil)
+	err = k.PutFilter(f2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(time.Hour).UTC()
+	f2.Inactivate(until)
+
+	err = k.PutFilter(newDupFilter(t, "f3", map[string]interface{}{
+		"disabled": true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f4 := newDupFilter(t, "f4", map[string]interface{}{
+		"expire": time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano),
+	})
+	err = k.PutFilter(f4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = k.removeFilter("f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.saveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	k2 := NewKkok()
+	err = k2.AddStaticFilter(newDupFilter(t, "f3", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k2.SetStateFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := k2.Filters()
+	if len(filters) != 2 {
+		t.Fatal(`len(filters) != 2`, filters)
+	}
+
+	// f3 is a static filter in k2
+	if filters[0].ID() != "f3" {
+		t.Error(`filters[0].ID() != "f3"`)
+	}
+	if filters[0].Dynamic() {
+		t.Error(`filters[0].Dynamic()`)
+	}
+
+	f := filters[1]
+	if f.ID() != "f2" {
+		t.Fatal(`f.ID() != "f2"`)
+	}
+	if !f.Dynamic() {
+		t.Error(`!f.Dynamic()`)
+	}
+	if !f.Disabled() {
+		t.Error(`!f.Disabled()`)
+	}
+	inactive, ok := f.Params().Params["inactive"].(time.Time)
+	if !ok {
+		t.Fatal(`inactive is not time.Time`)
+	}
+	if !inactive.Equal(until) {
+		t.Error(`!inactive.Equal(until)`)
+	}
+}
+
+func testStateNoFile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	k := NewKkok()
+	err = k.SetStateFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Filters()) != 0 {
+		t.Error(`len(k.Filters()) != 0`)
+	}
+
+	k = NewKkok()
+	err = k.SetStateFile(filepath.Join(dir, "nosuchdir", "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.PutFilter(newDupFilter(t, "f1", nil))
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+	if len(k.Filters()) != 1 {
+		t.Error(`len(k.Filters()) != 1`)
+	}
+}
+
+func testStateRoutes(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+
+	k := NewKkok()
+	err = k.AddRoute("static1", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.SetStateFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = k.PutRoute("r1", []Transport{&testTransport{}, &testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.PutRoute("r2", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.PutRoute("r3", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.removeRoute("r2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k2 := NewKkok()
+	err = k2.AddRoute("r3", []Transport{&testTransport{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k2.SetStateFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := k2.RouteIDs()
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"r1", "r3"}) {
+		t.Error(`ids != ["r1", "r3"]`, ids)
+	}
+	if len(k2.getRoute("r1")) != 2 {
+		t.Error(`len(k2.getRoute("r1")) != 2`)
+	}
+
+	// r3 is a static route in k2
+	err = k2.removeRoute("r3")
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+	err = k2.removeRoute("r1")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestState(t *testing.T) {
+	RegisterTransport("test", func(params map[string]interface{}) (Transport, error) {
+		return &testTransport{}, nil
+	})
+	RegisterFilter("dup", func(id string, params map[string]interface{}) (Filter, error) {
+		f := &dupFilter{}
+		err := f.Init(id, params)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	})
+
+	t.Run("Restore", testStateRestore)
+	t.Run("NoFile", testStateNoFile)
+	t.Run("Routes", testStateRoutes)
+}
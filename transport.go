@@ -1,6 +1,12 @@
 package kkok
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cybozu-go/kkok/util"
+)
 
 // Transport is the interface that transport plugins must implement.
 type Transport interface {
@@ -19,6 +25,107 @@ type Transport interface {
 	Deliver(alerts []*Alert) error
 }
 
+// ContextTransport is an optional interface for transports
+// that can abort delivery when a context is done.
+//
+// Transports that do not implement this interface cannot be stopped
+// when delivery times out.  kkok abandons such a Deliver call and
+// proceeds to retries and fallbacks, so the alerts may be delivered
+// twice if the abandoned call eventually succeeds.
+type ContextTransport interface {
+	Transport
+
+	// DeliverContext delivers alerts via the transport.
+	// It should return soon after ctx is done.
+	DeliverContext(ctx context.Context, alerts []*Alert) error
+}
+
+// RateLimitedTransport is an optional interface for transports
+// that wait between messages to comply with rate limits.
+type RateLimitedTransport interface {
+	Transport
+
+	// DeliveryWait returns the estimated duration to wait for
+	// rate limits while delivering alerts.  kkok extends the
+	// delivery timeout by this duration.
+	DeliveryWait(alerts []*Alert) time.Duration
+}
+
+// deliveryWait returns the duration that t waits for rate limits
+// to deliver alerts.
+func deliveryWait(t Transport, alerts []*Alert) time.Duration {
+	if f, ok := t.(fallbackTransport); ok {
+		t = f.Transport
+	}
+	if rt, ok := t.(RateLimitedTransport); ok {
+		return rt.DeliveryWait(alerts)
+	}
+	return 0
+}
+
+// DeliverContext delivers alerts through t until ctx is done.
+//
+// If t does not implement ContextTransport, t.Deliver is called in
+// another goroutine and this returns ctx.Err() when ctx is done before
+// t.Deliver returns.  In that case, t.Deliver continues to run and
+// may deliver alerts after this returns.
+func DeliverContext(ctx context.Context, t Transport, alerts []*Alert) error {
+	if ct, ok := t.(ContextTransport); ok {
+		return ct.DeliverContext(ctx, alerts)
+	}
+
+	if ctx.Done() == nil {
+		return t.Deliver(alerts)
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- t.Deliver(alerts)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timeoutTransport limits the time to deliver alerts through a transport.
+type timeoutTransport struct {
+	Transport
+	timeout time.Duration
+}
+
+func (t timeoutTransport) Deliver(alerts []*Alert) error {
+	return t.DeliverContext(context.Background(), alerts)
+}
+
+func (t timeoutTransport) DeliverContext(ctx context.Context, alerts []*Alert) error {
+	timeout := t.timeout + deliveryWait(t.Transport, alerts)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return DeliverContext(ctx, t.Transport, alerts)
+}
+
+// withTimeout returns a transport that gives up delivery after timeout.
+// If t is a transportChain, timeout applies to each transport in it.
+// Time to wait for rate limits is not counted in timeout.
+func withTimeout(t Transport, timeout time.Duration) Transport {
+	if timeout <= 0 {
+		return t
+	}
+
+	if c, ok := t.(transportChain); ok {
+		c2 := make(transportChain, len(c))
+		for i, t := range c {
+			c2[i] = timeoutTransport{t, timeout}
+		}
+		return c2
+	}
+	return timeoutTransport{t, timeout}
+}
+
 // TransportConstructor is a function signature for transport construction.
 type TransportConstructor func(params map[string]interface{}) (Transport, error)
 
@@ -30,10 +137,32 @@ func RegisterTransport(typ string, ctor TransportConstructor) {
 }
 
 // NewTransport constructs a Transport.
+//
+// If params has "fallback" as true, the transport is used only when
+// the preceding transport in the route fails to deliver alerts.
+// "fallback" is removed from params before it is passed to the
+// constructor.
 func NewTransport(typ string, params map[string]interface{}) (Transport, error) {
 	ctor, ok := transportTypes[typ]
 	if !ok {
 		return nil, errors.New("no such transport type: " + typ)
 	}
-	return ctor(params)
+
+	fallback, err := util.GetBool("fallback", params)
+	switch {
+	case err == nil:
+		delete(params, "fallback")
+	case util.IsNotFound(err):
+	default:
+		return nil, err
+	}
+
+	t, err := ctor(params)
+	if err != nil {
+		return nil, err
+	}
+	if fallback {
+		return fallbackTransport{t}, nil
+	}
+	return t, nil
 }
@@ -0,0 +1,171 @@
+package kkok
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type slowTransport struct {
+	mu     sync.Mutex
+	wait   time.Duration
+	alerts []*Alert
+}
+
+func (t *slowTransport) Params() PluginParams {
+	return PluginParams{
+		Type:   "slow",
+		Params: make(map[string]interface{}),
+	}
+}
+
+func (t *slowTransport) String() string {
+	return "slow"
+}
+
+func (t *slowTransport) Deliver(alerts []*Alert) error {
+	time.Sleep(t.wait)
+	t.mu.Lock()
+	t.alerts = alerts
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *slowTransport) delivered() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.alerts)
+}
+
+func testDeliverContext(t *testing.T) {
+	t.Parallel()
+
+	tr := &slowTransport{wait: 200 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := DeliverContext(ctx, tr, []*Alert{{}})
+	if err != context.DeadlineExceeded {
+		t.Error(`err != context.DeadlineExceeded`)
+	}
+
+	err = DeliverContext(context.Background(), tr, []*Alert{{}})
+	if err != nil {
+		t.Error(err)
+	}
+	if tr.delivered() != 1 {
+		t.Error(`tr.delivered() != 1`)
+	}
+}
+
+func testDeliverConcurrently(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1", "r2"}
+	err := k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr1 := &slowTransport{wait: 200 * time.Millisecond}
+	tr2 := &slowTransport{wait: 200 * time.Millisecond}
+	tr3 := &slowTransport{wait: 200 * time.Millisecond}
+	k.AddRoute("r1", []Transport{tr1, tr2})
+	k.AddRoute("r2", []Transport{tr3})
+
+	done := make(chan struct{})
+	go func() {
+		k.Handle(context.Background(), []*Alert{{}})
+		close(done)
+	}()
+
+	// routes can be updated during delivery.
+	time.Sleep(50 * time.Millisecond)
+	err = k.AddRoute("r3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+		t.Error(`Handle returned too early`)
+	default:
+	}
+
+	select {
+	case <-done:
+	case <-time.After(300 * time.Millisecond):
+		t.Fatal(`transports are not called concurrently`)
+	}
+	if tr1.delivered() != 1 || tr2.delivered() != 1 || tr3.delivered() != 1 {
+		t.Error(`alerts are not delivered`)
+	}
+}
+
+func testDeliverTimeout(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	k.SetDeliveryTimeout(50 * time.Millisecond)
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	err := k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr1 := &slowTransport{wait: 200 * time.Millisecond}
+	tr2 := &testTransport{}
+	k.AddRoute("r1", []Transport{tr1, fallbackTransport{tr2}})
+
+	k.Handle(context.Background(), []*Alert{{}})
+	if len(tr2.alerts) != 1 {
+		t.Error(`len(tr2.alerts) != 1`)
+	}
+}
+
+type rateLimitedTransport struct {
+	slowTransport
+}
+
+func (t *rateLimitedTransport) DeliveryWait(alerts []*Alert) time.Duration {
+	return time.Duration(len(alerts)) * t.wait
+}
+
+func testDeliverRateLimited(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	k.SetDeliveryTimeout(50 * time.Millisecond)
+	f := &routeFilter{}
+	f.Init("f", nil)
+	f.routes = []string{"r1"}
+	err := k.AddStaticFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for rate limits does not count for the timeout.
+	tr1 := &rateLimitedTransport{slowTransport{wait: 100 * time.Millisecond}}
+	tr2 := &testTransport{}
+	k.AddRoute("r1", []Transport{tr1, fallbackTransport{tr2}})
+
+	k.Handle(context.Background(), []*Alert{{}})
+	if tr1.delivered() != 1 {
+		t.Error(`tr1.delivered() != 1`)
+	}
+	if len(tr2.alerts) != 0 {
+		t.Error(`len(tr2.alerts) != 0`)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("DeliverContext", testDeliverContext)
+	t.Run("Concurrently", testDeliverConcurrently)
+	t.Run("Timeout", testDeliverTimeout)
+	t.Run("RateLimited", testDeliverRateLimited)
+}
@@ -0,0 +1,120 @@
+package kkok
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/well"
+	"github.com/pkg/errors"
+)
+
+var (
+	reWebhookPath = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	// reservedWebhooks are paths of built-in webhook end points.
+	reservedWebhooks = map[string]bool{
+		"alertmanager": true,
+	}
+)
+
+// Webhook is an optional interface for sources that receive alerts
+// through HTTP POST requests to /webhooks/PATH of the API server.
+//
+// Requests to webhooks are not authenticated by the API token.
+// Webhooks should verify requests by themselves.
+type Webhook interface {
+	// Path returns PATH of the end point.
+	Path() string
+
+	// Verify verifies a request.  body is the request body.
+	// If this returns non-nil error, the request is rejected.
+	Verify(r *http.Request, body []byte) error
+
+	// Alerts converts a request body into alerts.
+	Alerts(r *http.Request, body []byte) ([]*Alert, error)
+}
+
+// webhookTable maps paths to webhooks.
+type webhookTable struct {
+	mu       sync.Mutex
+	webhooks map[string]Webhook
+}
+
+func newWebhookTable() *webhookTable {
+	return &webhookTable{
+		webhooks: make(map[string]Webhook),
+	}
+}
+
+func (t *webhookTable) add(w Webhook) error {
+	p := w.Path()
+	if !reWebhookPath.MatchString(p) {
+		return errors.New("invalid webhook path: " + p)
+	}
+	if reservedWebhooks[p] {
+		return errors.New("reserved webhook path: " + p)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.webhooks[p]; ok {
+		return errors.New("duplicate webhook path: " + p)
+	}
+	t.webhooks[p] = w
+	return nil
+}
+
+func (t *webhookTable) get(p string) Webhook {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.webhooks[p]
+}
+
+// AddWebhook registers a webhook source.
+func (k *Kkok) AddWebhook(w Webhook) error {
+	return k.webhooks.add(w)
+}
+
+func (a *apiHandler) postWebhook(w http.ResponseWriter, r *http.Request, wh Webhook) {
+	if getMethod(r) != "POST" {
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONLength))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = wh.Verify(r, body)
+	if err != nil {
+		fields := well.FieldsFromContext(r.Context())
+		fields[log.FnError] = err.Error()
+		fields["webhook"] = wh.Path()
+		log.Warn("webhook verification failed", fields)
+		http.Error(w, "verification failed", http.StatusForbidden)
+		return
+	}
+
+	alerts, err := wh.Alerts(r, body)
+	if err != nil {
+		fields := well.FieldsFromContext(r.Context())
+		fields[log.FnError] = err.Error()
+		fields["webhook"] = wh.Path()
+		log.Error("webhook conversion failed", fields)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]postResult, 0, len(alerts))
+	for _, alert := range alerts {
+		results = append(results, a.postOneAlert(r, alert, "webhook"))
+	}
+	sendJSON(w, r, results)
+}
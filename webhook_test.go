@@ -0,0 +1,129 @@
+package kkok
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testWebhook struct {
+	path string
+}
+
+func (w *testWebhook) Path() string {
+	return w.path
+}
+
+func (w *testWebhook) Verify(r *http.Request, body []byte) error {
+	if r.Header.Get("X-Test-Secret") != "secret" {
+		return errors.New("bad secret")
+	}
+	return nil
+}
+
+func (w *testWebhook) Alerts(r *http.Request, body []byte) ([]*Alert, error) {
+	if string(body) == "bad" {
+		return nil, errors.New("bad body")
+	}
+	return []*Alert{
+		{From: "hook", Title: string(body)},
+		{From: "hook"},
+	}, nil
+}
+
+func testWebhookAdd(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddWebhook(&testWebhook{"hook1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.AddWebhook(&testWebhook{"hook1"})
+	if err == nil {
+		t.Error(`duplicate path is accepted`)
+	}
+	err = k.AddWebhook(&testWebhook{"alertmanager"})
+	if err == nil {
+		t.Error(`reserved path is accepted`)
+	}
+	err = k.AddWebhook(&testWebhook{"a/b"})
+	if err == nil {
+		t.Error(`invalid path is accepted`)
+	}
+}
+
+func testWebhookPost(t *testing.T) {
+	t.Parallel()
+
+	k := NewKkok()
+	err := k.AddWebhook(&testWebhook{"hook1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(0, 0, new(testAlertHandler))
+	h := &apiHandler{"token", k, d}
+
+	r := jsonRequest("POST", "/webhooks/hook1", "title1")
+	r.Header.Set("X-Test-Secret", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(`w.Code != http.StatusOK`)
+	}
+	var res []postResult
+	testRecvJSON(t, w, &res)
+	if len(res) != 2 {
+		t.Fatal(`len(res) != 2`)
+	}
+	if len(res[0].ID) == 0 {
+		t.Error(`len(res[0].ID) == 0`)
+	}
+	if len(res[1].Error) == 0 {
+		t.Error(`len(res[1].Error) == 0`)
+	}
+
+	alerts := d.pool.Take()
+	if len(alerts) != 1 {
+		t.Fatal(`len(alerts) != 1`)
+	}
+	if alerts[0].Title != "title1" {
+		t.Error(`alerts[0].Title != "title1"`)
+	}
+
+	r = jsonRequest("POST", "/webhooks/hook1", "title1")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Error(`w.Code != http.StatusForbidden`)
+	}
+
+	r = jsonRequest("POST", "/webhooks/hook1", "bad")
+	r.Header.Set("X-Test-Secret", "secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(`w.Code != http.StatusBadRequest`)
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost/webhooks/hook1", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(`w.Code != http.StatusMethodNotAllowed`)
+	}
+
+	// unknown webhooks require the API token.
+	r = jsonRequest("POST", "/webhooks/hook2", "title1")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Error(`w.Code != http.StatusForbidden`)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Run("Add", testWebhookAdd)
+	t.Run("Post", testWebhookPost)
+}